@@ -0,0 +1,109 @@
+// Package clusterjobs รัน clustering (ดึง event -> ส่งให้ engine -> บันทึก cluster)
+// ทั้งแบบ synchronous ใน request และแบบงานในคิวที่ worker ทำเบื้องหลังและบันทึกสถานะลงฐานข้อมูล
+package clusterjobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"globe/internal/db/models"
+	"globe/internal/db/store"
+	"globe/internal/history/service"
+	"globe/internal/metrics"
+	"globe/internal/pyservice"
+	"globe/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// LifecycleJob คือชื่องานใน lifecycle.Jobs ของการ clustering ทุกแบบ (ตอนปิด server จะรองานเหล่านี้)
+const LifecycleJob = "clustering"
+
+// ขั้นตอนที่ล้มเหลว (ใช้กับ errors.Is) ส่วน error ของ engine คืนตามเดิม เช่น *pyservice.Error
+var (
+	ErrFetchEvents    = errors.New("failed to fetch events")
+	ErrInsertClusters = errors.New("failed to insert clusters")
+)
+
+// Engine คำนวณ cluster แบบ hierarchy จาก event
+type Engine func(ctx context.Context, events []models.EventLatLonDate) ([]models.Cluster, error)
+
+// PythonEngine ส่ง event ไป POST /process ของ Python service
+func PythonEngine(py *pyservice.Client) Engine {
+	return func(ctx context.Context, events []models.EventLatLonDate) ([]models.Cluster, error) {
+		var resp struct {
+			Status string `json:"status"`
+			Data   struct {
+				Clusters []models.Cluster `json:"clusters"`
+			} `json:"data"`
+		}
+		if err := py.Process(ctx, events, &resp); err != nil {
+			return nil, err
+		}
+		return resp.Data.Clusters, nil
+	}
+}
+
+// Outcome คือผลของการ clustering หนึ่งครั้ง
+type Outcome struct {
+	Clusters []models.Cluster
+	Excluded []int // event ที่ติด flag คุณภาพข้อมูลจึงไม่ถูกส่งไป
+	Result   models.ClusterJobResult
+}
+
+// Run ดึง event ส่งให้ engine แล้วบันทึก cluster ใน transaction เดียว
+// report (ไม่บังคับ) ถูกเรียกเมื่อเริ่มแต่ละขั้นตอนพร้อมความคืบหน้าโดยประมาณ (0-1)
+func Run(ctx context.Context, events store.EventStore, clusters store.ClusterStore, engine Engine, params models.ClusterJobParams, report func(stage string, progress float64)) (out Outcome, err error) {
+	if report == nil {
+		report = func(string, float64) {}
+	}
+	start := time.Now()
+	defer func() {
+		outcome := metrics.OutcomeSuccess
+		if err != nil {
+			outcome = metrics.OutcomeError
+			if ctx.Err() != nil {
+				outcome = metrics.OutcomeAborted
+			}
+		}
+		metrics.ObserveClustering(outcome, start)
+	}()
+
+	// event ที่ติด flag คุณภาพข้อมูลจะไม่ถูกส่งไป clustering (ยกเว้น include_flagged)
+	report(models.ClusterStageFetching, 0.05)
+	kept, excluded, err := service.GetClusteringEvents(ctx, events, params.IncludeFlagged)
+	if err != nil {
+		return out, fmt.Errorf("%w: %w", ErrFetchEvents, err)
+	}
+
+	report(models.ClusterStageComputing, 0.2)
+	result, err := engine(ctx, kept)
+	if err != nil {
+		return out, err
+	}
+
+	report(models.ClusterStageInserting, 0.9)
+	insertCtx, span := tracing.Start(ctx, "clusters.insert", attribute.Int("clusters.count", len(result)))
+	err = clusters.InsertClustersAndMappings(insertCtx, result)
+	tracing.End(span, err)
+	if err != nil {
+		return out, fmt.Errorf("%w: %w", ErrInsertClusters, err)
+	}
+
+	levels := make(map[int]struct{})
+	for _, c := range result {
+		levels[c.Level] = struct{}{}
+	}
+	return Outcome{
+		Clusters: result,
+		Excluded: excluded,
+		Result: models.ClusterJobResult{
+			Events:   len(kept),
+			Excluded: len(excluded),
+			Clusters: len(result),
+			Levels:   len(levels),
+		},
+	}, nil
+}
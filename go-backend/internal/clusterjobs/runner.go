@@ -0,0 +1,371 @@
+package clusterjobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"globe/internal/config"
+	"globe/internal/db/models"
+	"globe/internal/db/store"
+	"globe/internal/lifecycle"
+	"globe/internal/pyservice"
+	"globe/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	ErrUnknownEngine = errors.New("unknown clustering engine")
+	ErrQueueFull     = errors.New("cluster job queue is full")
+	ErrJobFinished   = errors.New("cluster job already finished")
+	// ErrJobRunningElsewhere คืองานที่ worker ของ instance อื่นหยิบไปแล้ว ยกเลิกได้จาก instance นั้นเท่านั้น
+	ErrJobRunningElsewhere = errors.New("cluster job is running on another instance")
+
+	// errCanceled คือสาเหตุของ context เมื่อผู้ใช้สั่งยกเลิก (แยกจากการยกเลิกตอนปิด server)
+	errCanceled = errors.New("canceled by request")
+	// errLeaseLost คือสาเหตุของ context เมื่อต่อ lease ไม่ได้เพราะงานถูกนำกลับเข้าคิวไปแล้ว
+	errLeaseLost = errors.New("cluster job lease lost")
+)
+
+// maxAttempts คือจำนวนครั้งสูงสุดที่เริ่มงานเดิมใหม่หลังถูกขัดจังหวะ (server ปิดหรือ crash ระหว่างทำ)
+// กันไม่ให้งานที่ทำให้ process ล้มวนทำซ้ำไม่สิ้นสุด
+const maxAttempts = 3
+
+// retryDelay คือเวลารอก่อนอ่านคิวใหม่เมื่อฐานข้อมูลมีปัญหา
+const retryDelay = 5 * time.Second
+
+// saveTimeout คือเวลาสูงสุดของการบันทึกสถานะสุดท้ายของงาน (ใช้ได้แม้ context ของงานถูกยกเลิกแล้ว)
+const saveTimeout = 5 * time.Second
+
+// Runner รับงาน clustering เข้าคิว (ตาราง cluster_job) และให้ worker ทำทีละงานตามลำดับ
+// worker ที่หยิบงานถือ lease ของงานและต่อ lease ระหว่างทำ งานที่ถูกขัดจังหวะตอนปิด server จะกลับเข้าคิวทันที
+// ส่วนงานของ instance ที่ crash จะกลับเข้าคิวเมื่อ lease หมด การบันทึก cluster อยู่ใน transaction
+// และข้าม cluster ที่มีอยู่แล้ว จึงทำซ้ำได้อย่างปลอดภัย
+type Runner struct {
+	db        store.Store
+	engines   map[string]Engine
+	jobs      *lifecycle.Jobs
+	workers   int
+	queueSize int
+	lease     time.Duration
+	instance  string // ส่วนหน้าของ owner ของงานที่ instance นี้หยิบ
+	wake      chan struct{}
+
+	mu      sync.Mutex // กันไม่ให้ worker หยิบงานที่กำลังถูกยกเลิก
+	running map[int]context.CancelCauseFunc
+	claims  int // ลำดับการหยิบงาน ทำให้ owner ของแต่ละครั้งไม่ซ้ำกัน
+}
+
+// NewRunner สร้าง Runner ตาม CLUSTER_JOB_* (เรียก Start เพื่อเริ่ม worker)
+func NewRunner(db store.Store, py *pyservice.Client, jobs *lifecycle.Jobs, cfg config.ClusterJobs) *Runner {
+	return &Runner{
+		db:        db,
+		engines:   map[string]Engine{models.ClusterEnginePython: PythonEngine(py)},
+		jobs:      jobs,
+		workers:   cfg.Workers,
+		queueSize: cfg.QueueSize,
+		lease:     cfg.Lease,
+		instance:  instanceID(),
+		wake:      make(chan struct{}, cfg.Workers),
+		running:   make(map[int]context.CancelCauseFunc),
+	}
+}
+
+// Start นำงาน running ที่ lease หมดแล้วกลับเข้าคิว แล้วเริ่ม worker และตัวตรวจ lease ที่ทำแบบเดียวกันทุกช่วง lease
+// งานของ instance อื่นที่ยังต่อ lease อยู่จะไม่ถูกแตะ
+// worker หยุดรับงานใหม่เมื่อ ctx ถูกยกเลิก ส่วนงานที่กำลังทำจะถูกรอหรือยกเลิกผ่าน lifecycle.Jobs
+func (r *Runner) Start(ctx context.Context) error {
+	if err := r.requeueExpired(ctx); err != nil {
+		return err
+	}
+
+	for i := 0; i < r.workers; i++ {
+		go r.work(ctx)
+	}
+	go r.watchLeases(ctx)
+	r.notify()
+	return nil
+}
+
+// Submit บันทึกงานใหม่ในสถานะ queued (ErrQueueFull ถ้ามีงานรออยู่ครบ CLUSTER_JOB_QUEUE_SIZE แล้ว)
+func (r *Runner) Submit(ctx context.Context, engine string, params models.ClusterJobParams) (models.ClusterJob, error) {
+	if engine == "" {
+		engine = models.ClusterEnginePython
+	}
+	if _, ok := r.engines[engine]; !ok {
+		return models.ClusterJob{}, fmt.Errorf("%w %q", ErrUnknownEngine, engine)
+	}
+	if r.jobs.Closed() {
+		return models.ClusterJob{}, lifecycle.ErrShuttingDown
+	}
+	// นับคิวและเพิ่มงานในคำสั่งเดียว หลาย request หรือหลาย instance จึงไม่ทำให้คิวเกิน queueSize
+	job, ok, err := r.db.CreateClusterJob(ctx, models.ClusterJob{Status: models.ClusterJobQueued, Engine: engine, Params: params}, r.queueSize)
+	if err != nil {
+		return job, err
+	}
+	if !ok {
+		return models.ClusterJob{}, ErrQueueFull
+	}
+	slog.InfoContext(ctx, "Cluster job queued", "job_id", job.JobID, "engine", engine)
+	r.notify()
+	return job, nil
+}
+
+// Get คืนงานตาม id
+func (r *Runner) Get(ctx context.Context, id int) (models.ClusterJob, error) {
+	return r.db.GetClusterJob(ctx, id)
+}
+
+// List คืนประวัติงาน
+func (r *Runner) List(ctx context.Context, query models.ClusterJobQuery) ([]models.ClusterJob, error) {
+	return r.db.ListClusterJobs(ctx, query)
+}
+
+// Cancel ยกเลิกงานที่ยังไม่จบ งานในคิวเปลี่ยนเป็น canceled ทันที (เฉพาะเมื่อยังไม่มี worker หยิบไป)
+// ส่วนงานที่กำลังทำใน process นี้จะถูกยกเลิก context แล้ว worker บันทึกสถานะ canceled เมื่องานหยุด
+func (r *Runner) Cancel(ctx context.Context, id int) (models.ClusterJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cancel, ok := r.running[id]; ok {
+		job, err := r.db.GetClusterJob(ctx, id)
+		if err != nil {
+			return job, err
+		}
+		cancel(errCanceled)
+		slog.InfoContext(ctx, "Cluster job cancellation requested", "job_id", id)
+		return job, nil
+	}
+
+	job, ok, err := r.db.CancelQueuedClusterJob(ctx, id)
+	if err != nil {
+		return job, err
+	}
+	if ok {
+		slog.InfoContext(ctx, "Cluster job canceled", "job_id", id)
+		return job, nil
+	}
+
+	// ไม่ได้อยู่ในคิวแล้ว: ไม่มีงานนี้ จบไปแล้ว หรือ instance อื่นกำลังทำอยู่
+	job, err = r.db.GetClusterJob(ctx, id)
+	if err != nil {
+		return job, err
+	}
+	if job.Finished() {
+		return job, ErrJobFinished
+	}
+	return job, ErrJobRunningElsewhere
+}
+
+func (r *Runner) notify() {
+	for i := 0; i < r.workers; i++ {
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *Runner) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		a, ok, err := r.claim(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Claiming cluster job failed", "err", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+		if !ok {
+			select {
+			case <-ctx.Done():
+			case <-r.wake:
+			}
+			continue
+		}
+		r.run(a)
+	}
+}
+
+// active คืองานที่ worker หยิบมาแล้ว
+type active struct {
+	job    models.ClusterJob
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	done   func() // จบงานใน lifecycle.Jobs
+}
+
+// claim หยิบงานที่เก่าที่สุดในคิวและเปลี่ยนเป็น running ด้วย ClaimClusterJob ซึ่ง atomic ในฐานข้อมูล
+// หลาย worker หรือหลาย instance จึงไม่หยิบงานเดียวกัน ส่วน r.mu กันไม่ให้ Cancel เห็นงานก่อนลงทะเบียนใน running
+func (r *Runner) claim(ctx context.Context) (active, bool, error) {
+	// หลังเริ่มปิด server จะไม่เริ่มงานใหม่ งานยังอยู่ในคิวสำหรับครั้งถัดไป
+	if r.jobs.Closed() {
+		return active{}, false, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.claims++
+	job, ok, err := r.db.ClaimClusterJob(ctx, fmt.Sprintf("%s/%d", r.instance, r.claims), r.lease)
+	if err != nil || !ok {
+		return active{}, false, err
+	}
+	jobCtx, done, err := r.jobs.Start(context.Background(), LifecycleJob)
+	if err != nil {
+		// server เริ่มปิดระหว่างหยิบงาน คืนงานเข้าคิวโดยไม่นับครั้งนี้
+		job.Status, job.Attempts, job.StartedAt = models.ClusterJobQueued, job.Attempts-1, nil
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), saveTimeout)
+		defer cancel()
+		return active{}, false, r.db.UpdateClusterJob(saveCtx, job)
+	}
+	runCtx, cancel := context.WithCancelCause(jobCtx)
+	r.running[job.JobID] = cancel
+	return active{job: job, ctx: runCtx, cancel: cancel, done: done}, true, nil
+}
+
+func (r *Runner) run(a active) {
+	defer a.done()
+	job := a.job
+	ctx, span := tracing.Start(a.ctx, "cluster_job.run",
+		attribute.Int("cluster_job.id", job.JobID), attribute.String("cluster_job.engine", job.Engine))
+	slog.InfoContext(ctx, "Cluster job started", "job_id", job.JobID, "engine", job.Engine, "attempt", job.Attempts)
+
+	report := func(stage string, progress float64) {
+		job.Stage, job.Progress = stage, progress
+		if err := r.db.UpdateClusterJob(ctx, job); err != nil {
+			slog.WarnContext(ctx, "Saving cluster job progress failed", "job_id", job.JobID, "err", err)
+		}
+	}
+	stopHeartbeat := r.heartbeat(a)
+	out, err := Run(ctx, r.db, r.db, r.engines[job.Engine], job.Params, report)
+	stopHeartbeat()
+	tracing.End(span, err)
+
+	// บันทึกสถานะสุดท้ายภายใต้ lock เพื่อไม่ให้ Cancel เห็นงานที่จบแล้วเป็นงานในคิว
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, job.JobID)
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		job.Status, job.Stage, job.Progress, job.Result, job.FinishedAt = models.ClusterJobSucceeded, "", 1, &out.Result, &now
+		slog.InfoContext(ctx, "Cluster job succeeded", "job_id", job.JobID, "clusters", out.Result.Clusters)
+	case errors.Is(context.Cause(a.ctx), errCanceled):
+		job.Status, job.FinishedAt = models.ClusterJobCanceled, &now
+		slog.InfoContext(ctx, "Cluster job canceled", "job_id", job.JobID)
+	case errors.Is(context.Cause(a.ctx), errLeaseLost):
+		// งานกลับเข้าคิวไปแล้ว สถานะเป็นของ worker ที่หยิบงานไปใหม่
+		slog.WarnContext(ctx, "Cluster job lease lost, stopped", "job_id", job.JobID, "owner", job.Owner)
+		return
+	case a.ctx.Err() != nil:
+		// ถูกยกเลิกตอนปิด server: cluster ที่ insert ไม่เสร็จถูก rollback จึงกลับเข้าคิวให้ทำใหม่ครั้งหน้า
+		r.interrupted(ctx, &job)
+	default:
+		job.Status, job.Error, job.FinishedAt = models.ClusterJobFailed, err.Error(), &now
+		slog.ErrorContext(ctx, "Cluster job failed", "job_id", job.JobID, "err", err)
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), saveTimeout)
+	defer cancel()
+	if err := r.db.UpdateClusterJob(saveCtx, job); err != nil {
+		slog.ErrorContext(ctx, "Saving cluster job failed", "job_id", job.JobID, "status", job.Status, "err", err)
+	}
+}
+
+// heartbeat ต่อ lease ของงานทุก 1/3 ของ lease จนกว่าจะเรียก stop
+// ถ้างานไม่ได้เป็นของ worker นี้แล้ว (lease หมดระหว่างที่ต่อไม่ได้และถูกนำกลับเข้าคิว) จะยกเลิกงานด้วย errLeaseLost
+func (r *Runner) heartbeat(a active) (stop func()) {
+	quit, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(r.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+			ok, err := r.db.RenewClusterJobLease(a.ctx, a.job.JobID, a.job.Owner, r.lease)
+			switch {
+			case a.ctx.Err() != nil:
+				return
+			case err != nil:
+				slog.WarnContext(a.ctx, "Renewing cluster job lease failed", "job_id", a.job.JobID, "err", err)
+			case !ok:
+				a.cancel(errLeaseLost)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		<-stopped
+	}
+}
+
+// watchLeases นำงานที่ lease หมดกลับเข้าคิวทุกช่วง lease จนกว่า ctx จะถูกยกเลิก
+func (r *Runner) watchLeases(ctx context.Context) {
+	ticker := time.NewTicker(r.lease)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.requeueExpired(ctx); err != nil {
+			slog.ErrorContext(ctx, "Requeueing expired cluster jobs failed", "err", err)
+		}
+	}
+}
+
+// requeueExpired นำงานที่ lease หมด (instance ที่ทำอยู่ crash หรือขาดการติดต่อฐานข้อมูล) กลับเข้าคิว
+// หรือให้ล้มเหลวถ้าเริ่มมาแล้ว maxAttempts ครั้ง
+func (r *Runner) requeueExpired(ctx context.Context) error {
+	expired, err := r.db.RequeueExpiredClusterJobs(ctx, maxAttempts)
+	if err != nil {
+		return err
+	}
+	for _, job := range expired {
+		if job.Status == models.ClusterJobFailed {
+			slog.WarnContext(ctx, "Cluster job interrupted too many times", "job_id", job.JobID, "attempts", job.Attempts)
+			continue
+		}
+		slog.WarnContext(ctx, "Cluster job lease expired, requeued", "job_id", job.JobID, "owner", job.Owner, "attempts", job.Attempts)
+	}
+	if len(expired) > 0 {
+		r.notify()
+	}
+	return nil
+}
+
+// instanceID คือชื่อของ process นี้ใน owner ของงาน (host, pid และค่าสุ่มกันชื่อซ้ำเมื่อ pid ถูกใช้ซ้ำ)
+func instanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// interrupted คืนงานที่ถูกขัดจังหวะกลับเข้าคิว หรือให้ล้มเหลวถ้าเริ่มมาแล้ว maxAttempts ครั้ง
+func (r *Runner) interrupted(ctx context.Context, job *models.ClusterJob) {
+	if job.Attempts >= maxAttempts {
+		now := time.Now()
+		job.Status, job.FinishedAt = models.ClusterJobFailed, &now
+		job.Error = fmt.Sprintf("interrupted %d times", job.Attempts)
+		slog.WarnContext(ctx, "Cluster job interrupted too many times", "job_id", job.JobID, "attempts", job.Attempts)
+		return
+	}
+	job.Status, job.Stage, job.Progress, job.StartedAt = models.ClusterJobQueued, "", 0, nil
+	slog.WarnContext(ctx, "Cluster job interrupted, requeued", "job_id", job.JobID, "attempts", job.Attempts)
+}
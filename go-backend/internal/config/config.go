@@ -0,0 +1,356 @@
+// Package config รวมการตั้งค่าทั้งหมดของ backend ไว้ที่เดียว
+// ลำดับความสำคัญ: flag > environment variable > ไฟล์ .env (ไม่มีก็ได้) > ค่าเริ่มต้น
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"globe/internal/db/models"
+	"globe/internal/logging"
+	"globe/internal/tracing"
+
+	"github.com/joho/godotenv"
+)
+
+// DefaultEnvFile คือไฟล์ .env ที่ root ของ repo (go-backend รันจากโฟลเดอร์ของตัวเอง)
+const DefaultEnvFile = "../.env"
+
+type Config struct {
+	DatabaseURL string
+	Port        int
+	CORSOrigins string
+	Server      Server
+	PyService   PyService
+	PostGIS     bool
+	ClusterHull models.HullOptions
+	Log         Log
+	Tracing     Tracing
+	ClusterJobs ClusterJobs
+
+	envFile string
+	values  []value // ค่าที่ใช้จริงของแต่ละ setting สำหรับ Dump
+}
+
+// Server คือ timeout ของ HTTP server (fiber.Config)
+type Server struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout คือเวลาที่รอ request และงานที่ค้างอยู่ตอนได้ SIGINT/SIGTERM ก่อนยกเลิก
+	ShutdownTimeout time.Duration
+}
+
+// PyService คือที่อยู่ของ Python clustering service และนโยบาย timeout/retry/circuit breaker ของ client
+type PyService struct {
+	URL              string
+	Timeout          time.Duration // ต่อการเรียกหนึ่งครั้ง
+	ConnectTimeout   time.Duration
+	Retries          int           // จำนวน retry ของ endpoint ที่เรียกซ้ำได้
+	RetryBackoff     time.Duration // เวลารอก่อน retry ครั้งแรก (เพิ่มเป็นสองเท่าทุกครั้ง)
+	BreakerThreshold int           // ล้มเหลวติดกันกี่ครั้งจึงเปิด circuit (0 = ไม่ใช้)
+	BreakerCooldown  time.Duration // เวลาที่งดเรียกก่อนลองใหม่
+}
+
+// Log คือระดับและรูปแบบของ log (log/slog)
+type Log struct {
+	Level  slog.Level
+	Format string // json | text
+}
+
+// ClusterJobs คือ worker pool ของงาน clustering แบบ asynchronous (POST /api/cluster-jobs)
+type ClusterJobs struct {
+	Workers   int           // จำนวนงานที่ทำพร้อมกัน
+	QueueSize int           // จำนวนงานที่รอในคิวได้สูงสุด
+	Lease     time.Duration // งาน running ที่ไม่ได้ต่อ lease นานเท่านี้ถือว่า instance ที่ทำอยู่หายไปแล้ว
+}
+
+// Tracing คือการส่ง span ของ OpenTelemetry (endpoint ของ otlp ตั้งผ่าน OTEL_EXPORTER_OTLP_*)
+type Tracing struct {
+	Exporter    string  // none | stdout | otlp
+	SampleRatio float64 // สัดส่วน trace ที่บันทึก (trace ที่มี parent ใช้การตัดสินของ parent)
+}
+
+type value struct {
+	env, value, source string
+	secret             bool
+}
+
+// setting คือค่าหนึ่งค่า: ชื่อ env, ค่าเริ่มต้น และวิธีใส่ลง Config (flag ใช้ชื่อ env ตัวเล็กคั่นด้วย -)
+type setting struct {
+	env    string
+	def    func(lookup func(string) string) string
+	usage  string
+	secret bool
+	apply  func(c *Config, v string) error
+}
+
+func constant(v string) func(func(string) string) string {
+	return func(func(string) string) string { return v }
+}
+
+var settings = []setting{
+	{"DATABASE_URL", constant(""), "postgres://... (Supabase) or sqlite://<file> (offline mode)", true,
+		func(c *Config, v string) error {
+			if v == "" {
+				return errors.New("is required")
+			}
+			c.DatabaseURL = v
+			return nil
+		}},
+	{"GO_PORT", constant("5000"), "HTTP port of the Go backend", false,
+		func(c *Config, v string) (err error) {
+			c.Port, err = strconv.Atoi(v)
+			if err != nil || c.Port < 1 || c.Port > 65535 {
+				return errors.New("must be a port number (1-65535)")
+			}
+			return nil
+		}},
+	{"CORS_ORIGINS", constant("http://localhost:5173"), "comma-separated origins allowed by CORS", false,
+		func(c *Config, v string) error {
+			for _, origin := range strings.Split(v, ",") {
+				if !validHTTPURL(strings.TrimSpace(origin)) {
+					return fmt.Errorf("invalid origin %q", origin)
+				}
+			}
+			c.CORSOrigins = v
+			return nil
+		}},
+	{"READ_TIMEOUT", constant("10s"), "HTTP server read timeout", false, duration(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"WRITE_TIMEOUT", constant("10s"), "HTTP server write timeout", false, duration(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"IDLE_TIMEOUT", constant("5s"), "HTTP server idle timeout", false, duration(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"SHUTDOWN_TIMEOUT", constant("30s"), "time to drain requests and jobs on shutdown", false, duration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	// ค่าเดิมใช้ PY_PORT อย่างเดียว จึงยังใช้เป็นค่าเริ่มต้นของ PY_SERVICE_URL
+	{"PY_SERVICE_URL", func(lookup func(string) string) string {
+		port := lookup("PY_PORT")
+		if port == "" {
+			port = "8000"
+		}
+		return "http://localhost:" + port
+	}, "base URL of the Python clustering service", false,
+		func(c *Config, v string) error {
+			if !validHTTPURL(v) {
+				return errors.New("must be an http(s) URL")
+			}
+			c.PyService.URL = strings.TrimRight(v, "/")
+			return nil
+		}},
+	{"PY_SERVICE_TIMEOUT", constant("10s"), "timeout of each request to the Python service", false, duration(func(c *Config) *time.Duration { return &c.PyService.Timeout })},
+	{"PY_SERVICE_CONNECT_TIMEOUT", constant("2s"), "timeout of connecting to the Python service", false, duration(func(c *Config) *time.Duration { return &c.PyService.ConnectTimeout })},
+	{"PY_SERVICE_RETRIES", constant("2"), "retries when the Python service is unreachable, times out or returns 502/503/504", false,
+		nonNegativeInt(func(c *Config) *int { return &c.PyService.Retries })},
+	{"PY_SERVICE_RETRY_BACKOFF", constant("250ms"), "wait before the first retry, doubled for each further retry", false, duration(func(c *Config) *time.Duration { return &c.PyService.RetryBackoff })},
+	{"PY_SERVICE_BREAKER_THRESHOLD", constant("5"), "consecutive failed calls that open the circuit breaker (0 disables it)", false,
+		nonNegativeInt(func(c *Config) *int { return &c.PyService.BreakerThreshold })},
+	{"PY_SERVICE_BREAKER_COOLDOWN", constant("30s"), "how long the open circuit rejects calls before trying again", false, duration(func(c *Config) *time.Duration { return &c.PyService.BreakerCooldown })},
+	{"CLUSTER_JOB_WORKERS", constant("1"), "cluster jobs run at the same time", false,
+		func(c *Config, v string) (err error) {
+			c.ClusterJobs.Workers, err = strconv.Atoi(v)
+			if err != nil || c.ClusterJobs.Workers < 1 {
+				return errors.New("must be a whole number (1 or more)")
+			}
+			return nil
+		}},
+	{"CLUSTER_JOB_QUEUE_SIZE", constant("100"), "queued cluster jobs allowed before new ones are rejected", false,
+		func(c *Config, v string) (err error) {
+			c.ClusterJobs.QueueSize, err = strconv.Atoi(v)
+			if err != nil || c.ClusterJobs.QueueSize < 1 {
+				return errors.New("must be a whole number (1 or more)")
+			}
+			return nil
+		}},
+	{"CLUSTER_JOB_LEASE", constant("1m"), "how long a running cluster job stays claimed without a heartbeat before another instance queues it again", false,
+		duration(func(c *Config) *time.Duration { return &c.ClusterJobs.Lease })},
+	{"POSTGIS", constant("false"), "use PostGIS columns for spatial filters and cluster hulls", false,
+		func(c *Config, v string) (err error) {
+			c.PostGIS, err = strconv.ParseBool(v)
+			if err != nil {
+				return errors.New("must be true or false")
+			}
+			return nil
+		}},
+	{"CLUSTER_HULL", constant(models.HullConvex), "cluster hull in PostGIS mode (convex, concave)", false,
+		func(c *Config, v string) error {
+			if v != models.HullConvex && v != models.HullConcave {
+				return errors.New("must be convex or concave")
+			}
+			c.ClusterHull.Mode = v
+			return nil
+		}},
+	{"CLUSTER_HULL_RATIO", constant("0"), "concave hull ratio, 0 to 1 (0 = default)", false,
+		func(c *Config, v string) (err error) {
+			c.ClusterHull.Ratio, err = strconv.ParseFloat(v, 64)
+			if err != nil || c.ClusterHull.Ratio < 0 || c.ClusterHull.Ratio > 1 {
+				return errors.New("must be a number between 0 and 1")
+			}
+			return nil
+		}},
+	{"LOG_LEVEL", constant("info"), "minimum log level (debug, info, warn, error)", false,
+		func(c *Config, v string) (err error) {
+			c.Log.Level, err = logging.ParseLevel(v)
+			return err
+		}},
+	{"LOG_FORMAT", constant(logging.FormatJSON), "log format (json, text)", false,
+		func(c *Config, v string) error {
+			if v != logging.FormatJSON && v != logging.FormatText {
+				return errors.New("must be json or text")
+			}
+			c.Log.Format = v
+			return nil
+		}},
+	{"TRACING_EXPORTER", constant(tracing.ExporterNone), "OpenTelemetry span exporter (none, stdout, otlp)", false,
+		func(c *Config, v string) error {
+			if v != tracing.ExporterNone && v != tracing.ExporterStdout && v != tracing.ExporterOTLP {
+				return errors.New("must be none, stdout or otlp")
+			}
+			c.Tracing.Exporter = v
+			return nil
+		}},
+	{"TRACING_SAMPLE_RATIO", constant("1"), "fraction of new traces to record, 0 to 1", false,
+		func(c *Config, v string) (err error) {
+			c.Tracing.SampleRatio, err = strconv.ParseFloat(v, 64)
+			if err != nil || c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+				return errors.New("must be a number between 0 and 1")
+			}
+			return nil
+		}},
+}
+
+// Load อ่านการตั้งค่าจาก args (flag ที่อยู่ก่อน subcommand), environment และไฟล์ .env
+// คืน args ที่เหลือหลัง flag (เช่น ["migrate", "up"]) และรวม error ของทุก setting ที่ไม่ถูกต้อง
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("globe", flag.ContinueOnError)
+	envFile := fs.String("env-file", DefaultEnvFile, "optional .env file")
+	flags := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flags[s.env] = fs.String(flagName(s.env), "", s.usage+" (env "+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	fileValues, err := godotenv.Read(*envFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) || explicit["env-file"] {
+			return nil, nil, fmt.Errorf("read %s: %w", *envFile, err)
+		}
+		fileValues = map[string]string{}
+	}
+
+	lookup := func(env string) (string, string) {
+		if f, ok := flags[env]; ok && explicit[flagName(env)] {
+			return *f, "flag"
+		}
+		if v, ok := os.LookupEnv(env); ok {
+			return v, "env"
+		}
+		if v, ok := fileValues[env]; ok {
+			return v, *envFile
+		}
+		return "", ""
+	}
+	lookupValue := func(env string) string {
+		v, _ := lookup(env)
+		return v
+	}
+
+	cfg := &Config{envFile: *envFile}
+	var errs []error
+	for _, s := range settings {
+		v, source := lookup(s.env)
+		if source == "" {
+			v, source = s.def(lookupValue), "default"
+		}
+		if err := s.apply(cfg, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+		cfg.values = append(cfg.values, value{env: s.env, value: v, source: source, secret: s.secret})
+	}
+	if err := cfg.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return cfg, fs.Args(), nil
+}
+
+// validate ตรวจเงื่อนไขที่เกี่ยวกับหลาย setting
+func (c *Config) validate() error {
+	if c.PostGIS && strings.HasPrefix(c.DatabaseURL, "sqlite:") {
+		return errors.New("POSTGIS: PostGIS mode needs a Postgres DATABASE_URL")
+	}
+	return nil
+}
+
+// Dump คืนการตั้งค่าที่ใช้จริงพร้อมที่มาของค่า (flag, env, ไฟล์ หรือ default) สำหรับเขียนลง log
+// โดยซ่อนรหัสผ่านใน DATABASE_URL
+func (c *Config) Dump() []slog.Attr {
+	attrs := []slog.Attr{slog.String("env_file", c.envFile)}
+	for _, v := range c.values {
+		shown := v.value
+		if v.secret {
+			shown = redact(v.value)
+		}
+		attrs = append(attrs, slog.Group(v.env, slog.String("value", shown), slog.String("source", v.source)))
+	}
+	return attrs
+}
+
+// Addr คือ address ที่ HTTP server listen
+func (c *Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+func duration(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return errors.New("must be a positive duration such as 10s")
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// nonNegativeInt คือ apply ของ setting ที่เป็นจำนวนเต็มตั้งแต่ 0 ขึ้นไป
+func nonNegativeInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return errors.New("must be a whole number (0 or more)")
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func flagName(env string) string {
+	return strings.ToLower(strings.ReplaceAll(env, "_", "-"))
+}
+
+func validHTTPURL(raw string) bool {
+	u, err := url.ParseRequestURI(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// redact ซ่อนรหัสผ่านของ URL (ถ้า parse ไม่ได้ก็ซ่อนทั้งค่า)
+func redact(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return "****"
+	}
+	return u.Redacted()
+}
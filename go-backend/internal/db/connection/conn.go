@@ -3,31 +3,30 @@ package connection
 import (
 	"context"
 	"errors"
-	"os"
-	"path/filepath"
 
+	"globe/internal/metrics"
+	"globe/internal/tracing"
+
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
 )
 
 var DB *pgxpool.Pool
 
 // ConnectDB ทำหน้าที่เชื่อมต่อกับฐานข้อมูล Supabase
-func ConnectDB() error {
-	// โหลด .env ก่อนใช้งาน
-	envPath := filepath.Join("..", ".env")
-	if err := godotenv.Load(envPath); err != nil {
-		return err
-	}
-
-	// ดึงค่า DATABASE_URL จาก environment variable
-	dbURL := os.Getenv("DATABASE_URL")
+func ConnectDB(dbURL string) error {
 	if dbURL == "" {
 		return ErrDatabaseURLNotSet
 	}
 
-	var err error
-	DB, err = pgxpool.New(context.Background(), dbURL)
+	cfg, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		return err
+	}
+	// วัด latency ของทุก query (GET /metrics) และสร้าง span ของ query เมื่อเปิด tracing
+	cfg.ConnConfig.Tracer = multitracer.New(metrics.QueryTracer{}, tracing.QueryTracer{})
+
+	DB, err = pgxpool.NewWithConfig(context.Background(), cfg)
 	if err != nil {
 		return err
 	}
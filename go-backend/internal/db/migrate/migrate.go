@@ -0,0 +1,321 @@
+// Package migrate จัดการ schema ของฐานข้อมูลด้วยไฟล์ SQL ที่ฝังอยู่ใน binary
+// ไฟล์ใน migrations/ ตั้งชื่อแบบ NNNN_name.up.sql / NNNN_name.down.sql
+// และ version ที่ apply แล้วเก็บในตาราง schema_migrations
+package migrate
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// optional คือ schema เสริมที่เลือกเปิดได้ (เช่น postgis) ไม่อยู่ในลำดับ version
+// และไม่บันทึกใน schema_migrations จึงเขียนให้ apply ซ้ำได้
+//
+//go:embed optional/*.sql
+var optional embed.FS
+
+// lockID คือ advisory lock ที่กันไม่ให้ migrate พร้อมกันหลาย process
+const lockID = 7201604
+
+var (
+	ErrSchemaOutdated = errors.New("database schema is missing migrations, run `go run . migrate up`")
+	ErrSchemaAhead    = errors.New("database schema is newer than this binary")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Unknown   bool       `json:"unknown,omitempty"` // apply แล้วในฐานข้อมูลแต่ไม่มีใน binary นี้
+}
+
+// Migrations คืน migration ทั้งหมดที่ฝังไว้ เรียงตาม version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := fileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := files.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" || strings.TrimSpace(mig.Down) == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up apply migration ที่ยังไม่ได้ apply ทั้งหมดตามลำดับ แต่ละตัวอยู่ใน transaction ของตัวเอง
+func Up(ctx context.Context, db *pgxpool.Pool) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, mig := range migrations {
+		done, err := inTx(ctx, db, func(tx pgx.Tx, versions map[int]time.Time) (bool, error) {
+			if _, ok := versions[mig.Version]; ok {
+				return false, nil
+			}
+			if _, err := tx.Exec(ctx, mig.Up); err != nil {
+				return false, fmt.Errorf("migration %04d_%s up: %w", mig.Version, mig.Name, err)
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+			return true, err
+		})
+		if err != nil {
+			return applied, err
+		}
+		if done {
+			applied = append(applied, mig)
+		}
+	}
+	return applied, nil
+}
+
+// Down ย้อน migration ล่าสุด steps ตัว
+func Down(ctx context.Context, db *pgxpool.Pool, steps int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		mig := migrations[i]
+		done, err := inTx(ctx, db, func(tx pgx.Tx, versions map[int]time.Time) (bool, error) {
+			if _, ok := versions[mig.Version]; !ok {
+				return false, nil
+			}
+			if _, err := tx.Exec(ctx, mig.Down); err != nil {
+				return false, fmt.Errorf("migration %04d_%s down: %w", mig.Version, mig.Name, err)
+			}
+			_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			return true, err
+		})
+		if err != nil {
+			return reverted, err
+		}
+		if done {
+			reverted = append(reverted, mig)
+		}
+	}
+	return reverted, nil
+}
+
+// GetStatus คืนสถานะของทุก migration (รวม version ที่อยู่ในฐานข้อมูลแต่ไม่มีใน binary)
+func GetStatus(ctx context.Context, db *pgxpool.Pool) ([]Status, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, mig := range migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := versions[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &at
+			delete(versions, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for version, at := range versions {
+		at := at
+		statuses = append(statuses, Status{Version: version, Applied: true, AppliedAt: &at, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Verify ตรวจว่า schema ตรงกับ binary นี้: ทุก migration ต้อง apply แล้ว และไม่มี version ที่ binary ไม่รู้จัก
+func Verify(ctx context.Context, db *pgxpool.Pool) error {
+	statuses, err := GetStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+	var pending, unknown []string
+	for _, s := range statuses {
+		switch {
+		case s.Unknown:
+			unknown = append(unknown, strconv.Itoa(s.Version))
+		case !s.Applied:
+			pending = append(pending, fmt.Sprintf("%04d_%s", s.Version, s.Name))
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w (unknown versions: %s)", ErrSchemaAhead, strings.Join(unknown, ", "))
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w (pending: %s)", ErrSchemaOutdated, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// SchemaVersion คืน version ล่าสุดที่ apply แล้ว (0 ถ้ายังไม่มี)
+func SchemaVersion(ctx context.Context, db *pgxpool.Pool) (int, error) {
+	statuses, err := GetStatus(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for _, s := range statuses {
+		if s.Applied && s.Version > version {
+			version = s.Version
+		}
+	}
+	return version, nil
+}
+
+// ApplyOptional apply (หรือ revert ถ้า down) schema เสริมชื่อ name ใน transaction เดียว
+func ApplyOptional(ctx context.Context, db *pgxpool.Pool, name string, down bool) error {
+	direction := "up"
+	if down {
+		direction = "down"
+	}
+	body, err := optional.ReadFile(path.Join("optional", name+"."+direction+".sql"))
+	if err != nil {
+		return fmt.Errorf("unknown optional schema %q", name)
+	}
+	_, err = inTx(ctx, db, func(tx pgx.Tx, _ map[int]time.Time) (bool, error) {
+		if _, err := tx.Exec(ctx, string(body)); err != nil {
+			return false, fmt.Errorf("optional schema %s %s: %w", name, direction, err)
+		}
+		return true, nil
+	})
+	return err
+}
+
+func ensureTable(ctx context.Context, db *pgxpool.Pool) error {
+	_, err := db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    int PRIMARY KEY,
+			name       text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)
+	`)
+	return err
+}
+
+// appliedVersions คืน version ที่ apply แล้ว (ตารางยังไม่มี = ยังไม่ได้ apply อะไรเลย)
+func appliedVersions(ctx context.Context, q interface {
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+}) (map[int]time.Time, error) {
+	var exists bool
+	rows, err := q.Query(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	if rows.Next() {
+		err = rows.Scan(&exists)
+	}
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[int]time.Time)
+	if !exists {
+		return versions, nil
+	}
+
+	rows, err = q.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		versions[version] = at
+	}
+	return versions, rows.Err()
+}
+
+// inTx รัน fn ใน transaction ที่ถือ advisory lock แล้ว พร้อม version ที่ apply แล้ว ณ ตอนนั้น
+func inTx(ctx context.Context, db *pgxpool.Pool, fn func(pgx.Tx, map[int]time.Time) (bool, error)) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+		return false, err
+	}
+	versions, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	done, err := fn(tx, versions)
+	if err != nil {
+		return false, err
+	}
+	return done, tx.Commit(ctx)
+}
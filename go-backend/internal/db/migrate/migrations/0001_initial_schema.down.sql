@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS eventclustermap_bbox ON eventclustermap;
+DROP FUNCTION IF EXISTS cluster_extend_bbox();
+DROP TABLE IF EXISTS eventclustermap;
+DROP TABLE IF EXISTS cluster;
+DROP TABLE IF EXISTS eventtag;
+DROP TABLE IF EXISTS tag;
+DROP TABLE IF EXISTS event;
@@ -0,0 +1,65 @@
+-- โครงสร้างเดิมที่เคยมีอยู่เฉพาะใน Supabase (IF NOT EXISTS เพื่อให้ฐานข้อมูลเดิมรับ migration นี้ได้)
+CREATE TABLE IF NOT EXISTS event (
+    event_id    serial PRIMARY KEY,
+    event_name  text             NOT NULL,
+    date        date             NOT NULL,
+    lat         double precision NOT NULL,
+    lon         double precision NOT NULL,
+    image       text             NOT NULL DEFAULT '',
+    video       text             NOT NULL DEFAULT '',
+    description text             NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS tag (
+    tag_id   serial PRIMARY KEY,
+    tag_name text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS eventtag (
+    event_id int NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    tag_id   int NOT NULL REFERENCES tag(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+-- cluster จาก divisive tree ฝั่ง Python (cluster_id กำหนดโดย Python)
+CREATE TABLE IF NOT EXISTS cluster (
+    cluster_id         int PRIMARY KEY,
+    parent_cluster_id  int,
+    centroid_lat       double precision NOT NULL,
+    centroid_lon       double precision NOT NULL,
+    centroid_time_days double precision NOT NULL,
+    level              int NOT NULL,
+    -- กรอบของ event ใน cluster (อัปเดตโดย trigger ด้านล่าง)
+    min_lat            double precision,
+    max_lat            double precision,
+    min_lon            double precision,
+    max_lon            double precision,
+    min_date           date,
+    max_date           date
+);
+
+CREATE TABLE IF NOT EXISTS eventclustermap (
+    event_id   int NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    cluster_id int NOT NULL REFERENCES cluster(cluster_id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, cluster_id)
+);
+
+CREATE OR REPLACE FUNCTION cluster_extend_bbox() RETURNS trigger AS $$
+BEGIN
+    UPDATE cluster c SET
+        min_lat  = LEAST(c.min_lat, e.lat),
+        max_lat  = GREATEST(c.max_lat, e.lat),
+        min_lon  = LEAST(c.min_lon, e.lon),
+        max_lon  = GREATEST(c.max_lon, e.lon),
+        min_date = LEAST(c.min_date, e.date),
+        max_date = GREATEST(c.max_date, e.date)
+    FROM event e
+    WHERE c.cluster_id = NEW.cluster_id AND e.event_id = NEW.event_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS eventclustermap_bbox ON eventclustermap;
+CREATE TRIGGER eventclustermap_bbox
+    AFTER INSERT ON eventclustermap
+    FOR EACH ROW EXECUTE FUNCTION cluster_extend_bbox();
@@ -0,0 +1,2 @@
+ALTER TABLE event DROP COLUMN IF EXISTS end_date;
+ALTER TABLE event DROP COLUMN IF EXISTS date_precision;
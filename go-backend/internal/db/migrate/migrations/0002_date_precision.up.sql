@@ -0,0 +1,3 @@
+-- date precision and ranged dates
+ALTER TABLE event ADD COLUMN IF NOT EXISTS date_precision text NOT NULL DEFAULT 'day'; -- day, month, season, year, decade, century
+ALTER TABLE event ADD COLUMN IF NOT EXISTS end_date date;
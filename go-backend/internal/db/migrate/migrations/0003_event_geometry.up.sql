@@ -0,0 +1,2 @@
+-- multi-location events: GeoJSON LineString (route) or Polygon (area), [lon, lat] order
+ALTER TABLE event ADD COLUMN IF NOT EXISTS geometry jsonb;
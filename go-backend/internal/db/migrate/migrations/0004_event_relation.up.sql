@@ -0,0 +1,9 @@
+-- event relationships graph
+CREATE TABLE IF NOT EXISTS event_relation (
+    relation_id     serial PRIMARY KEY,
+    source_event_id int  NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    target_event_id int  NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    relation_type   text NOT NULL,
+    note            text,
+    UNIQUE (source_event_id, target_event_id, relation_type)
+);
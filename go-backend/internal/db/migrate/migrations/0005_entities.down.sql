@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS event_entity;
+DROP TABLE IF EXISTS entity;
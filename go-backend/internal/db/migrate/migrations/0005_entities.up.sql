@@ -0,0 +1,13 @@
+-- people, organisations, units and countries linked to events
+CREATE TABLE IF NOT EXISTS entity (
+    entity_id   serial PRIMARY KEY,
+    name        text NOT NULL,
+    entity_type text NOT NULL, -- person, organisation, unit, country
+    description text
+);
+CREATE TABLE IF NOT EXISTS event_entity (
+    event_id  int  NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    entity_id int  NOT NULL REFERENCES entity(entity_id) ON DELETE CASCADE,
+    role      text NOT NULL, -- commander, participant, belligerent, other
+    PRIMARY KEY (event_id, entity_id, role)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tour_step;
+DROP TABLE IF EXISTS tour;
@@ -0,0 +1,23 @@
+-- narrative tours
+CREATE TABLE IF NOT EXISTS tour (
+    tour_id     serial PRIMARY KEY,
+    title       text NOT NULL,
+    description text
+);
+CREATE TABLE IF NOT EXISTS tour_step (
+    step_id          serial PRIMARY KEY,
+    tour_id          int NOT NULL REFERENCES tour(tour_id) ON DELETE CASCADE,
+    position         int NOT NULL,
+    event_id         int REFERENCES event(event_id) ON DELETE SET NULL,
+    cluster_id       int,
+    camera_lat       double precision NOT NULL DEFAULT 0,
+    camera_lon       double precision NOT NULL DEFAULT 0,
+    camera_altitude  double precision NOT NULL DEFAULT 0,
+    camera_heading   double precision NOT NULL DEFAULT 0,
+    camera_tilt      double precision NOT NULL DEFAULT 0,
+    window_start     date,
+    window_end       date,
+    narration        text,
+    duration_seconds int NOT NULL DEFAULT 0,
+    UNIQUE (tour_id, position)
+);
@@ -0,0 +1,8 @@
+-- hierarchical triangular mesh cell of each event at resolutions 0-12
+CREATE TABLE IF NOT EXISTS event_cell (
+    event_id int      NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    res      smallint NOT NULL,
+    cell_id  bigint   NOT NULL,
+    PRIMARY KEY (event_id, res)
+);
+CREATE INDEX IF NOT EXISTS event_cell_res_cell_idx ON event_cell (res, cell_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS event_date_idx;
+DROP INDEX IF EXISTS event_geohash_idx;
+ALTER TABLE event DROP COLUMN IF EXISTS geohash;
@@ -0,0 +1,4 @@
+-- geohash (9 characters) for nearby-event lookups
+ALTER TABLE event ADD COLUMN IF NOT EXISTS geohash text;
+CREATE INDEX IF NOT EXISTS event_geohash_idx ON event (geohash text_pattern_ops);
+CREATE INDEX IF NOT EXISTS event_date_idx ON event (date);
@@ -0,0 +1,16 @@
+-- asynchronous clustering jobs (POST /api/cluster-jobs)
+CREATE TABLE IF NOT EXISTS cluster_job (
+    job_id      serial PRIMARY KEY,
+    status      text NOT NULL DEFAULT 'queued',
+    engine      text NOT NULL,
+    params      jsonb NOT NULL DEFAULT '{}',
+    stage       text NOT NULL DEFAULT '',
+    progress    double precision NOT NULL DEFAULT 0,
+    result      jsonb,
+    error       text,
+    attempts    int NOT NULL DEFAULT 0,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    started_at  timestamptz,
+    finished_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS cluster_job_status_idx ON cluster_job (status, created_at);
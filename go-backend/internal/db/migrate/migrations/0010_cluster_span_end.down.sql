@@ -0,0 +1,16 @@
+CREATE OR REPLACE FUNCTION cluster_extend_bbox() RETURNS trigger AS $$
+BEGIN
+    UPDATE cluster c SET
+        min_lat  = LEAST(c.min_lat, e.lat),
+        max_lat  = GREATEST(c.max_lat, e.lat),
+        min_lon  = LEAST(c.min_lon, e.lon),
+        max_lon  = GREATEST(c.max_lon, e.lon),
+        min_date = LEAST(c.min_date, e.date),
+        max_date = GREATEST(c.max_date, e.date)
+    FROM event e
+    WHERE c.cluster_id = NEW.cluster_id AND e.event_id = NEW.event_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS event_span_end(date, text, date);
@@ -0,0 +1,35 @@
+-- cluster max_date covers the whole date span of its events (end_date or the end of the precision period)
+CREATE OR REPLACE FUNCTION event_span_end(d date, precision text, end_d date) RETURNS date AS $$
+    SELECT COALESCE(end_d, CASE precision
+        WHEN 'month'   THEN d + INTERVAL '1 month'   - INTERVAL '1 day'
+        WHEN 'season'  THEN d + INTERVAL '3 months'  - INTERVAL '1 day'
+        WHEN 'year'    THEN d + INTERVAL '1 year'    - INTERVAL '1 day'
+        WHEN 'decade'  THEN d + INTERVAL '10 years'  - INTERVAL '1 day'
+        WHEN 'century' THEN d + INTERVAL '100 years' - INTERVAL '1 day'
+        ELSE d
+    END)::date
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION cluster_extend_bbox() RETURNS trigger AS $$
+BEGIN
+    UPDATE cluster c SET
+        min_lat  = LEAST(c.min_lat, e.lat),
+        max_lat  = GREATEST(c.max_lat, e.lat),
+        min_lon  = LEAST(c.min_lon, e.lon),
+        max_lon  = GREATEST(c.max_lon, e.lon),
+        min_date = LEAST(c.min_date, e.date),
+        max_date = GREATEST(c.max_date, event_span_end(e.date, e.date_precision, e.end_date))
+    FROM event e
+    WHERE c.cluster_id = NEW.cluster_id AND e.event_id = NEW.event_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE cluster c SET max_date = s.max_date
+FROM (
+    SELECT ecm.cluster_id, MAX(event_span_end(e.date, e.date_precision, e.end_date)) AS max_date
+    FROM eventclustermap ecm
+    JOIN event e ON e.event_id = ecm.event_id
+    GROUP BY ecm.cluster_id
+) s
+WHERE c.cluster_id = s.cluster_id;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS event_cell_stale ON event;
+DROP FUNCTION IF EXISTS event_cell_stale();
@@ -0,0 +1,14 @@
+-- drop the HTM cells of an event when its coordinates change; /api/cells computes missing cells until the next reindex
+CREATE OR REPLACE FUNCTION event_cell_stale() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM event_cell WHERE event_id = NEW.event_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS event_cell_stale ON event;
+CREATE TRIGGER event_cell_stale
+    AFTER UPDATE OF lat, lon ON event
+    FOR EACH ROW
+    WHEN (OLD.lat IS DISTINCT FROM NEW.lat OR OLD.lon IS DISTINCT FROM NEW.lon)
+    EXECUTE FUNCTION event_cell_stale();
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS event_geohash ON event;
+DROP FUNCTION IF EXISTS event_set_geohash();
+DROP FUNCTION IF EXISTS geohash_encode(double precision, double precision, int);
@@ -0,0 +1,65 @@
+-- compute event.geohash in the database on insert and on coordinate change (same algorithm as geo.GeohashEncode)
+CREATE OR REPLACE FUNCTION geohash_encode(p_lat double precision, p_lon double precision, p_len int) RETURNS text AS $$
+DECLARE
+    base32 constant text := '0123456789bcdefghjkmnpqrstuvwxyz';
+    lat_lo double precision := -90;
+    lat_hi double precision := 90;
+    lon_lo double precision := -180;
+    lon_hi double precision := 180;
+    mid    double precision;
+    is_lon boolean := true;
+    nbits  int := 0;
+    ch     int := 0;
+    hash   text := '';
+BEGIN
+    -- NaN and Infinity fail BETWEEN, like quality.ValidCoordinates
+    IF p_lat IS NULL OR p_lon IS NULL OR NOT (p_lat BETWEEN -90 AND 90 AND p_lon BETWEEN -180 AND 180) THEN
+        RETURN NULL;
+    END IF;
+    WHILE length(hash) < p_len LOOP
+        IF is_lon THEN
+            mid := (lon_lo + lon_hi) / 2;
+            IF p_lon >= mid THEN
+                ch := ch * 2 + 1;
+                lon_lo := mid;
+            ELSE
+                ch := ch * 2;
+                lon_hi := mid;
+            END IF;
+        ELSE
+            mid := (lat_lo + lat_hi) / 2;
+            IF p_lat >= mid THEN
+                ch := ch * 2 + 1;
+                lat_lo := mid;
+            ELSE
+                ch := ch * 2;
+                lat_hi := mid;
+            END IF;
+        END IF;
+        is_lon := NOT is_lon;
+        nbits := nbits + 1;
+        IF nbits = 5 THEN
+            hash := hash || substr(base32, ch + 1, 1);
+            nbits := 0;
+            ch := 0;
+        END IF;
+    END LOOP;
+    RETURN hash;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- 9 characters = geo.GeohashPrecision
+CREATE OR REPLACE FUNCTION event_set_geohash() RETURNS trigger AS $$
+BEGIN
+    NEW.geohash := geohash_encode(NEW.lat, NEW.lon, 9);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS event_geohash ON event;
+CREATE TRIGGER event_geohash
+    BEFORE INSERT OR UPDATE OF lat, lon ON event
+    FOR EACH ROW EXECUTE FUNCTION event_set_geohash();
+
+UPDATE event SET geohash = geohash_encode(lat, lon, 9)
+WHERE geohash IS DISTINCT FROM geohash_encode(lat, lon, 9);
@@ -0,0 +1,2 @@
+ALTER TABLE cluster_job DROP COLUMN IF EXISTS lease_until;
+ALTER TABLE cluster_job DROP COLUMN IF EXISTS owner;
@@ -0,0 +1,3 @@
+-- the worker that holds a running cluster job renews lease_until while it runs; other instances requeue the job only after the lease expires
+ALTER TABLE cluster_job ADD COLUMN IF NOT EXISTS owner text;
+ALTER TABLE cluster_job ADD COLUMN IF NOT EXISTS lease_until timestamptz;
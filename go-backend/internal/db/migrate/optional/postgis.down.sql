@@ -0,0 +1,4 @@
+-- extension postgis ไม่ถูกลบเพราะอาจมีอย่างอื่นใช้อยู่
+ALTER TABLE cluster DROP COLUMN IF EXISTS hull;
+ALTER TABLE event DROP COLUMN IF EXISTS geog;
+DROP FUNCTION IF EXISTS event_geog(double precision, double precision, jsonb);
@@ -0,0 +1,19 @@
+-- โหมด PostGIS (ไม่บังคับ): geography ของ event และ hull ของ cluster พร้อม GiST index
+-- apply ด้วย `go run . migrate postgis` แล้วตั้ง POSTGIS=true (รันซ้ำได้)
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+-- geography จาก geometry (GeoJSON) ถ้ามี ไม่อย่างนั้นใช้จุด lat/lon (พิกัดใช้ไม่ได้ = NULL)
+CREATE OR REPLACE FUNCTION event_geog(double precision, double precision, jsonb) RETURNS geography AS $$
+    SELECT CASE
+        WHEN $3 IS NOT NULL THEN ST_SetSRID(ST_GeomFromGeoJSON($3::text), 4326)::geography
+        WHEN $1 BETWEEN -90 AND 90 AND $2 BETWEEN -180 AND 180 THEN ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography
+    END
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE event ADD COLUMN IF NOT EXISTS geog geography
+    GENERATED ALWAYS AS (event_geog(lat, lon, geometry)) STORED;
+CREATE INDEX IF NOT EXISTS event_geog_idx ON event USING gist (geog);
+
+-- เส้นรอบ event ใน subtree ของ cluster (convex หรือ concave) คำนวณโดย backend หลัง insert cluster
+ALTER TABLE cluster ADD COLUMN IF NOT EXISTS hull geography;
+CREATE INDEX IF NOT EXISTS cluster_hull_idx ON cluster USING gist (hull);
@@ -0,0 +1,24 @@
+package models
+
+// MaxCellResolution คือ resolution สูงสุดของ HTM cell ที่เก็บต่อ event (resolution 12 ≈ 2.4 กม.)
+const MaxCellResolution = 12
+
+// CellAggregate คือจำนวน event ใน HTM cell หนึ่ง
+type CellAggregate struct {
+	CellID    int64     `json:"cell_id"`
+	Name      string    `json:"name"` // เช่น "N0123"
+	Res       int       `json:"res"`
+	Count     int       `json:"count"`
+	CenterLat float64   `json:"center_lat"`
+	CenterLon float64   `json:"center_lon"`
+	Shape     *Geometry `json:"shape"` // สามเหลี่ยมของ cell (Polygon)
+	AreaKm2   float64   `json:"area_km2"`
+	Density   float64   `json:"density"` // จำนวน event ต่อตารางกิโลเมตร (เทียบกันได้แม้ cell พื้นที่ไม่เท่ากัน)
+	MinDate   Date      `json:"min_date"`
+	MaxDate   Date      `json:"max_date"`
+}
+
+type CellIndexResult struct {
+	Indexed int `json:"indexed"` // จำนวน event ที่คำนวณ cell ใหม่
+	Skipped int `json:"skipped"` // event ที่ lat/lon ใช้ไม่ได้
+}
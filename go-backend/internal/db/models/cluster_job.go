@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// สถานะของงาน clustering แบบ asynchronous (POST /api/cluster-jobs)
+const (
+	ClusterJobQueued    = "queued"
+	ClusterJobRunning   = "running"
+	ClusterJobSucceeded = "succeeded"
+	ClusterJobFailed    = "failed"
+	ClusterJobCanceled  = "canceled"
+)
+
+// ขั้นตอนของงานที่กำลังทำ (Python service ไม่รายงานความคืบหน้าระหว่างคำนวณ จึงรายงานเป็นขั้นตอน)
+const (
+	ClusterStageFetching  = "fetching_events"
+	ClusterStageComputing = "clustering"
+	ClusterStageInserting = "inserting_clusters"
+)
+
+// ClusterEnginePython คือ engine เดียวที่มีตอนนี้: POST /process ของ Python service
+const ClusterEnginePython = "python"
+
+// ClusterJobParams คือพารามิเตอร์ของงาน clustering
+type ClusterJobParams struct {
+	IncludeFlagged bool `json:"include_flagged"` // ส่ง event ที่ติด flag คุณภาพข้อมูลไปด้วย
+}
+
+// ClusterJobResult คือสรุปผลของงานที่สำเร็จ
+type ClusterJobResult struct {
+	Events   int `json:"events"`   // event ที่ส่งไป clustering
+	Excluded int `json:"excluded"` // event ที่ถูกตัดออกเพราะติด flag
+	Clusters int `json:"clusters"`
+	Levels   int `json:"levels"` // จำนวนชั้นของ hierarchy
+}
+
+type ClusterJob struct {
+	JobID      int               `json:"job_id"`
+	Status     string            `json:"status"`
+	Engine     string            `json:"engine"`
+	Params     ClusterJobParams  `json:"params"`
+	Stage      string            `json:"stage,omitempty"`
+	Progress   float64           `json:"progress"` // 0-1
+	Result     *ClusterJobResult `json:"result,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Attempts   int               `json:"attempts"` // จำนวนครั้งที่เริ่มทำ (งานที่ถูกขัดจังหวะตอนปิด server จะเริ่มใหม่)
+	CreatedAt  time.Time         `json:"created_at"`
+	StartedAt  *time.Time        `json:"started_at,omitempty"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	Owner      string            `json:"owner,omitempty"`       // worker ที่หยิบงานไปล่าสุด (instance/ลำดับการหยิบ)
+	LeaseUntil *time.Time        `json:"lease_until,omitempty"` // งาน running ที่เลยเวลานี้จะถูกนำกลับเข้าคิว
+}
+
+// Finished บอกว่างานจบแล้ว (สำเร็จ ล้มเหลว หรือถูกยกเลิก)
+func (j ClusterJob) Finished() bool {
+	return j.Status == ClusterJobSucceeded || j.Status == ClusterJobFailed || j.Status == ClusterJobCanceled
+}
+
+// ClusterJobQuery คือเงื่อนไขของรายการงาน
+type ClusterJobQuery struct {
+	Status      string // ว่าง = ทุกสถานะ
+	Limit       int    // 0 = ไม่จำกัด
+	OldestFirst bool   // ค่าเริ่มต้นเรียงงานล่าสุดก่อน
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ช่วงปีที่รองรับ (astronomical year numbering: ปี 0 = 1 BCE, ปี -499 = 500 BCE)
+// ขอบล่างตรงกับค่าต่ำสุดของชนิด date ใน PostgreSQL (4713 BC)
+const (
+	MinYear = -4712
+	MaxYear = 9999
+)
+
+// unixEpoch ใช้เป็นจุดอ้างอิงของแกนเวลาแบบนับวัน (ปฏิทิน Gregorian แบบ proleptic)
+var unixEpoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Date คือ time.Time ที่รองรับปีก่อนคริสตกาลตั้งแต่ต้นจนจบ
+// time.Time เก็บปีติดลบได้อยู่แล้ว แต่ MarshalJSON ของมันรับแค่ปี 0-9999
+// Date จึง encode เป็น ISO 8601 แบบ expanded year (เช่น "-0490-09-12T00:00:00Z")
+// และ scan จากคอลัมน์ date/timestamp ของ PostgreSQL ได้โดยตรง
+type Date struct {
+	time.Time
+}
+
+// NewDate สร้าง Date (UTC) จากปี เดือน วัน
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// TimePtr คืน *time.Time (nil ถ้า d เป็น nil) สำหรับส่งต่อให้ฟังก์ชันที่ใช้ time.Time
+func (d *Date) TimePtr() *time.Time {
+	if d == nil {
+		return nil
+	}
+	t := d.Time
+	return &t
+}
+
+// DayNumber คืนจำนวนวันนับจาก 1970-01-01 (ติดลบได้) ใช้เป็นแกนเวลาที่ต่อเนื่องสำหรับ clustering
+func (d Date) DayNumber() float64 {
+	return DayNumber(d.Time)
+}
+
+// DayNumber คืนจำนวนวันนับจาก 1970-01-01 ของ t
+// คำนวณผ่าน Unix seconds เพราะ time.Duration ล้นเมื่อห่างเกิน ~292 ปี
+func DayNumber(t time.Time) float64 {
+	return float64(t.Unix()-unixEpoch.Unix()) / 86400
+}
+
+// String คืนวันที่แบบ ISO 8601 (expanded year ถ้าจำเป็น)
+func (d Date) String() string {
+	return formatISODate(d.Time)
+}
+
+func formatISODate(t time.Time) string {
+	t = t.UTC()
+	year := t.Year()
+	if year >= 0 && year <= 9999 {
+		return t.Format(time.RFC3339Nano)
+	}
+	sign := "+"
+	if year < 0 {
+		sign = "-"
+		year = -year
+	}
+	return fmt.Sprintf("%s%04d%s", sign, year, t.Format("-01-02T15:04:05.999999999Z07:00"))
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(formatISODate(d.Time))
+}
+
+// expandedDate จับ [+-]YYYY[YY][-MM[-DD]][T...]
+var expandedDate = regexp.MustCompile(`^([+-]?)(\d{4,6})(?:-(\d{2})(?:-(\d{2}))?)?(T.*)?$`)
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("date must be a string: %w", err)
+	}
+	t, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	d.Time = t
+	return nil
+}
+
+// ParseDate รับ RFC3339, YYYY-MM-DD, YYYY-MM, YYYY และรูปแบบ expanded year
+// ที่มีเครื่องหมาย เช่น "-0043-03-15" (= 44 BCE)
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), nil
+	}
+
+	m := expandedDate.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected ISO 8601 (e.g. 1916-07-01 or -0043-03-15)", s)
+	}
+
+	year, _ := strconv.Atoi(m[2])
+	if m[1] == "-" {
+		year = -year
+	}
+	month, day := 1, 1
+	if m[3] != "" {
+		month, _ = strconv.Atoi(m[3])
+	}
+	if m[4] != "" {
+		day, _ = strconv.Atoi(m[4])
+	}
+
+	// ส่วนเวลา: parse โดยใช้ปี 2000 แทน แล้วแทนปีจริงกลับเข้าไป
+	clock := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if m[5] != "" {
+		c, err := time.Parse(time.RFC3339Nano, "2000-01-01"+m[5])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time part in %q: %w", s, err)
+		}
+		clock = c.UTC()
+	}
+
+	t := time.Date(year, time.Month(month), day, clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), time.UTC)
+	if t.Month() != time.Month(month) || t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %q: day out of range", s)
+	}
+	if year < MinYear || year > MaxYear {
+		return time.Time{}, fmt.Errorf("invalid date %q: year must be between %d and %d", s, MinYear, MaxYear)
+	}
+	return t, nil
+}
+
+// ScanDate ทำให้ pgx scan คอลัมน์ date ลง Date ได้ (รวมปี BC)
+func (d *Date) ScanDate(v pgtype.Date) error {
+	if !v.Valid {
+		return fmt.Errorf("cannot scan NULL into *models.Date")
+	}
+	if v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("cannot scan infinite date into *models.Date")
+	}
+	d.Time = v.Time
+	return nil
+}
+
+// ScanTimestamp ทำให้ pgx scan คอลัมน์ timestamp ลง Date ได้
+func (d *Date) ScanTimestamp(v pgtype.Timestamp) error {
+	if !v.Valid {
+		return fmt.Errorf("cannot scan NULL into *models.Date")
+	}
+	d.Time = v.Time
+	return nil
+}
+
+// ScanTimestamptz ทำให้ pgx scan คอลัมน์ timestamptz ลง Date ได้
+func (d *Date) ScanTimestamptz(v pgtype.Timestamptz) error {
+	if !v.Valid {
+		return fmt.Errorf("cannot scan NULL into *models.Date")
+	}
+	d.Time = v.Time.UTC()
+	return nil
+}
+
+// DateValue ทำให้ pgx encode Date เป็นคอลัมน์ date ได้
+func (d Date) DateValue() (pgtype.Date, error) {
+	return pgtype.Date{Time: d.Time, Valid: true}, nil
+}
+
+// TimestamptzValue ทำให้ pgx encode Date เป็นคอลัมน์ timestamptz ได้
+func (d Date) TimestamptzValue() (pgtype.Timestamptz, error) {
+	return pgtype.Timestamptz{Time: d.Time, Valid: true}, nil
+}
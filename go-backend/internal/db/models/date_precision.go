@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// ความละเอียดของวันที่ของ event (คอลัมน์ event.date_precision)
+// date เก็บวันแรกของช่วงเสมอ เช่น "spring 1916" = 1916-03-01 + season
+const (
+	PrecisionDay     = "day"
+	PrecisionMonth   = "month"
+	PrecisionSeason  = "season" // 3 เดือน
+	PrecisionYear    = "year"
+	PrecisionDecade  = "decade"
+	PrecisionCentury = "century"
+)
+
+// ValidDatePrecision ตรวจว่า precision เป็นค่าที่รองรับ ("" ถือว่าเป็น day)
+func ValidDatePrecision(p string) bool {
+	switch p {
+	case "", PrecisionDay, PrecisionMonth, PrecisionSeason, PrecisionYear, PrecisionDecade, PrecisionCentury:
+		return true
+	}
+	return false
+}
+
+// DateSpanEnd คืนวันสุดท้าย (รวม) ของช่วงเวลาของ event
+// ถ้ามี endDate จะใช้ endDate ก่อน ไม่อย่างนั้นคำนวณจาก precision
+func DateSpanEnd(date time.Time, precision string, endDate *time.Time) time.Time {
+	if endDate != nil {
+		return *endDate
+	}
+	var next time.Time
+	switch precision {
+	case PrecisionMonth:
+		next = date.AddDate(0, 1, 0)
+	case PrecisionSeason:
+		next = date.AddDate(0, 3, 0)
+	case PrecisionYear:
+		next = date.AddDate(1, 0, 0)
+	case PrecisionDecade:
+		next = date.AddDate(10, 0, 0)
+	case PrecisionCentury:
+		next = date.AddDate(100, 0, 0)
+	default:
+		return date
+	}
+	return next.AddDate(0, 0, -1)
+}
+
+// DateSpanMidpoint คืนจุดกึ่งกลางของช่วง และครึ่งความกว้างของช่วง (วัน) ใช้เป็นค่าความไม่แน่นอน
+func DateSpanMidpoint(date time.Time, precision string, endDate *time.Time) (time.Time, float64) {
+	// ใช้ Unix seconds แทน time.Duration ซึ่งล้นเมื่อช่วงยาวเกิน ~292 ปี
+	end := DateSpanEnd(date, precision, endDate)
+	halfSeconds := (end.Unix() - date.Unix()) / 2
+	return date.Add(time.Duration(halfSeconds%86400)*time.Second).AddDate(0, 0, int(halfSeconds/86400)),
+		float64(halfSeconds) / 86400
+}
+
+// DateSpanOverlaps ตรวจว่าช่วงของ event ทับกับ [start, end] หรือไม่ (nil = ไม่จำกัด)
+func DateSpanOverlaps(date time.Time, precision string, endDate *time.Time, start, end *time.Time) bool {
+	if end != nil && date.After(*end) {
+		return false
+	}
+	if start != nil && DateSpanEnd(date, precision, endDate).Before(*start) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package models
+
+type DedupQuery struct {
+	Filter        *EventFilter `json:"filter"`          // จำกัดขอบเขต event ที่จะตรวจ
+	NameThreshold float64      `json:"name_threshold"`  // ความคล้ายของชื่อขั้นต่ำ (0-1)
+	MaxDaysApart  int          `json:"max_days_apart"`  // ระยะห่างของวันที่สูงสุด (วัน)
+	MaxDistanceKm float64      `json:"max_distance_km"` // ระยะทางสูงสุด (กม.)
+	MinScore      float64      `json:"min_score"`       // คะแนนรวมขั้นต่ำ (0-1)
+	MaxCandidates int          `json:"max_candidates"`  // จำนวนคู่สูงสุดที่ส่งกลับ
+}
+
+type DuplicateCandidate struct {
+	EventA         EventResponse `json:"event_a"`
+	EventB         EventResponse `json:"event_b"`
+	NameSimilarity float64       `json:"name_similarity"`
+	DaysApart      float64       `json:"days_apart"`
+	DistanceKm     float64       `json:"distance_km"`
+	Score          float64       `json:"score"`
+}
+
+type MergeRequest struct {
+	SurvivorID   int   `json:"survivor_id"`   // event ที่จะเก็บไว้
+	DuplicateIDs []int `json:"duplicate_ids"` // event ที่จะรวมเข้าไปแล้วลบทิ้ง
+}
+
+type MergeResult struct {
+	SurvivorID      int   `json:"survivor_id"`
+	MergedIDs       []int `json:"merged_ids"`
+	TagsAdded       int64 `json:"tags_added"`
+	ClustersRemoved int64 `json:"clusters_removed"` // จำนวน cluster mapping ของ duplicates ที่ถูกลบ
+	MediaFilled     bool  `json:"media_filled"`
+}
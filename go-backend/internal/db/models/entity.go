@@ -0,0 +1,64 @@
+package models
+
+// ชนิดของ entity
+const (
+	EntityPerson       = "person"
+	EntityOrganisation = "organisation"
+	EntityUnit         = "unit" // หน่วยทหาร
+	EntityCountry      = "country"
+)
+
+// บทบาทของ entity ใน event
+const (
+	RoleCommander   = "commander"
+	RoleParticipant = "participant"
+	RoleBelligerent = "belligerent"
+	RoleOther       = "other"
+)
+
+func ValidEntityType(t string) bool {
+	switch t {
+	case EntityPerson, EntityOrganisation, EntityUnit, EntityCountry:
+		return true
+	}
+	return false
+}
+
+func ValidEntityRole(r string) bool {
+	switch r {
+	case RoleCommander, RoleParticipant, RoleBelligerent, RoleOther:
+		return true
+	}
+	return false
+}
+
+type Entity struct {
+	EntityID    int    `json:"entity_id"`
+	Name        string `json:"name"`
+	EntityType  string `json:"entity_type"` // person, organisation, unit, country
+	Description string `json:"description"`
+}
+
+type EventEntityLink struct {
+	EventID  int    `json:"event_id"`
+	EntityID int    `json:"entity_id"`
+	Role     string `json:"role"` // commander, participant, belligerent, other
+}
+
+type EntityFilter struct {
+	EntityIDs []int    `json:"entity_ids"`
+	Roles     []string `json:"roles"`    // ว่าง = ทุกบทบาท
+	Operator  string   `json:"operator"` // AND = ต้องมีทุก entity, OR (default) = มี entity ใดก็ได้
+}
+
+type EntityPathStop struct {
+	Event EventResponse `json:"event"`
+	Roles []string      `json:"roles"`
+}
+
+// EntityPath คือ event ของ entity เรียงตามเวลา พร้อมเส้นทาง [lon, lat] สำหรับวาดบนลูกโลก
+type EntityPath struct {
+	Entity Entity           `json:"entity"`
+	Stops  []EntityPathStop `json:"stops"`
+	Path   *Geometry        `json:"path"` // LineString ถ้ามีอย่างน้อย 2 จุด
+}
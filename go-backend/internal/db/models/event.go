@@ -1,56 +1,96 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"globe/internal/geo"
 )
 
 type Event struct {
-	EventID     int       `json:"event_id"`
-	EventName   string    `json:"event_name"`
-	Date        time.Time `json:"date"`
-	Lat         float64   `json:"lat"`
-	Lon         float64   `json:"lon"`
-	Video       string    `json:"video"`
-	Image       string    `json:"image"`
-	Description string    `json:"description"`
+	EventID       int       `json:"event_id"`
+	EventName     string    `json:"event_name"`
+	Date          Date      `json:"date"`
+	DatePrecision string    `json:"date_precision"` // day, month, season, year, decade, century
+	EndDate       *Date     `json:"end_date"`       // วันสิ้นสุด ถ้า event เป็นช่วงเวลา
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	Geometry      *Geometry `json:"geometry"` // เส้นทางหรือพื้นที่ของ event (ถ้ามี) lat/lon คือจุดตัวแทน
+	Video         string    `json:"video"`
+	Image         string    `json:"image"`
+	Description   string    `json:"description"`
 }
 
 type EventLatLonDate struct {
-	EventID int       `json:"EventID"`
-	Lat     float64   `json:"Lat"`
-	Lon     float64   `json:"Lon"`
-	Date    time.Time `json:"Date"`
+	EventID             int     `json:"EventID"`
+	Lat                 float64 `json:"Lat"`
+	Lon                 float64 `json:"Lon"`
+	Date                Date    `json:"Date"`                // จุดกึ่งกลางของช่วงวันที่
+	DayNumber           float64 `json:"DayNumber"`           // Date เป็นจำนวนวันนับจาก 1970-01-01 (ติดลบได้ สำหรับปี BCE)
+	DateUncertaintyDays float64 `json:"DateUncertaintyDays"` // ครึ่งความกว้างของช่วง (วัน)
+}
+
+// NewEventLatLonDate สร้างข้อมูลสำหรับ clustering: event ที่เป็นช่วงเวลาจะใช้จุดกึ่งกลางพร้อมค่าความไม่แน่นอน
+func NewEventLatLonDate(eventID int, lat, lon float64, date Date, precision string, endDate *Date) EventLatLonDate {
+	mid, uncertainty := DateSpanMidpoint(date.Time, precision, endDate.TimePtr())
+	return EventLatLonDate{
+		EventID:             eventID,
+		Lat:                 lat,
+		Lon:                 lon,
+		Date:                Date{Time: mid},
+		DayNumber:           DayNumber(mid),
+		DateUncertaintyDays: uncertainty,
+	}
 }
 
 type EventResponse struct {
-	EventID     int       `json:"event_id"`
-	EventName   string    `json:"event_name"`
-	Date        time.Time `json:"date"`
-	Lat         float64   `json:"lat"`
-	Lon         float64   `json:"lon"`
-	Video       string    `json:"video"`
-	Image       string    `json:"image"`
-	Description string    `json:"description"`
-	Tags        []string  `json:"tags"`
-	Clusters    []int     `json:"clusters"`
+	EventID       int       `json:"event_id"`
+	EventName     string    `json:"event_name"`
+	Date          Date      `json:"date"`
+	DatePrecision string    `json:"date_precision"`
+	EndDate       *Date     `json:"end_date"`
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	Geometry      *Geometry `json:"geometry"`
+	Video         string    `json:"video"`
+	Image         string    `json:"image"`
+	Description   string    `json:"description"`
+	Tags          []string  `json:"tags"`
+	Clusters      []int     `json:"clusters"`
+}
+
+// MarshalJSON ส่ง lat/lon เป็น null เมื่อพิกัดใช้ไม่ได้ (NaN, Infinity หรือนอกช่วง)
+// แทนที่จะแปลงเป็น 0 หรือทำให้ทั้ง response encode ไม่ได้
+func (e EventResponse) MarshalJSON() ([]byte, error) {
+	type event EventResponse
+	out := struct {
+		event
+		Lat *float64 `json:"lat"`
+		Lon *float64 `json:"lon"`
+	}{event: event(e)}
+	if geo.ValidCoordinates(e.Lat, e.Lon) {
+		out.Lat, out.Lon = &e.Lat, &e.Lon
+	}
+	return json.Marshal(out)
 }
 
 type Cluster struct {
-	ClusterID        int        `json:"cluster_id"`
-	ParentClusterID  *int       `json:"parent_cluster_id"`
-	CentroidLat      float64    `json:"centroid_lat"`
-	CentroidLon      float64    `json:"centroid_lon"`
-	CentroidTimeDays string     `json:"centroid_time_days"`
-	Level            int        `json:"level"`
-	EventIDs         []int      `json:"event_ids"`
-
-	Events           []EventResponse `json:"events"`
-	MinLat           *float64   `json:"min_lat"`
-	MaxLat           *float64   `json:"max_lat"`
-	MinLon           *float64   `json:"min_lon"`
-	MaxLon           *float64   `json:"max_lon"`
-	MinDate          *time.Time `json:"min_date"`
-	MaxDate          *time.Time `json:"max_date"`
+	ClusterID        int     `json:"cluster_id"`
+	ParentClusterID  *int    `json:"parent_cluster_id"`
+	CentroidLat      float64 `json:"centroid_lat"`
+	CentroidLon      float64 `json:"centroid_lon"`
+	CentroidTimeDays string  `json:"centroid_time_days"`
+	Level            int     `json:"level"`
+	EventIDs         []int   `json:"event_ids"`
+
+	Events  []EventResponse `json:"events"`
+	MinLat  *float64        `json:"min_lat"`
+	MaxLat  *float64        `json:"max_lat"`
+	MinLon  *float64        `json:"min_lon"`
+	MaxLon  *float64        `json:"max_lon"`
+	MinDate *Date           `json:"min_date"`
+	MaxDate *Date           `json:"max_date"`
+	Hull    *Geometry       `json:"hull,omitempty"` // เส้นรอบ cluster (เฉพาะโหมด PostGIS)
 }
 
 type Viewport struct {
@@ -60,10 +100,89 @@ type Viewport struct {
 	West  float64 `json:"west"`  // longitude ของขอบซ้าย
 }
 
+// Intersects ตรวจว่า event ทับกับ viewport: ใช้ geometry ถ้ามี ไม่อย่างนั้นใช้จุด lat/lon
+func (v Viewport) Intersects(lat, lon float64, g *Geometry) bool {
+	r := geo.Rect{South: v.South, North: v.North, West: v.West, East: v.East}
+	if g == nil {
+		return r.ContainsPoint(lat, lon)
+	}
+	switch g.Type {
+	case GeometryLineString:
+		return r.IntersectsPath(g.Coordinates())
+	case GeometryPolygon:
+		return r.IntersectsPolygon(g.Parts)
+	default:
+		for _, c := range g.Coordinates() {
+			if r.ContainsPoint(c[1], c[0]) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RadiusFilter เลือก event ที่อยู่ห่างจากจุด (lat, lon) ไม่เกิน Km กิโลเมตร
+type RadiusFilter struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	Km  float64 `json:"km"`
+}
+
+// Contains ตรวจว่า event อยู่ในรัศมี: geometry ใช้ระยะถึงเส้นหรือขอบ (0 ถ้าจุดศูนย์กลางอยู่ใน polygon)
+func (r RadiusFilter) Contains(lat, lon float64, g *Geometry) bool {
+	if g == nil {
+		return geo.HaversineKm(r.Lat, r.Lon, lat, lon) <= r.Km
+	}
+	if g.Type == GeometryPolygon && geo.PolygonContains(g.Parts, r.Lon, r.Lat) {
+		return true
+	}
+	for _, part := range g.Parts {
+		if geo.DistanceToPathKm(part, r.Lat, r.Lon) <= r.Km {
+			return true
+		}
+	}
+	return false
+}
+
+// AreaIntersects ตรวจว่า event ทับกับ polygon area (พิกัด [lon, lat])
+func AreaIntersects(area *Geometry, lat, lon float64, g *Geometry) bool {
+	if g == nil {
+		return geo.PolygonContains(area.Parts, lon, lat)
+	}
+	switch g.Type {
+	case GeometryLineString:
+		return geo.PathIntersectsPolygon(g.Coordinates(), area.Parts)
+	case GeometryPolygon:
+		return geo.PolygonsIntersect(g.Parts, area.Parts)
+	default:
+		for _, c := range g.Coordinates() {
+			if geo.PolygonContains(area.Parts, c[0], c[1]) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// DateFilter ใช้ overlap semantics: event ที่มีช่วงวันที่ (precision/end_date)
+// จะผ่าน filter ถ้าช่วงของ event ทับกับช่วงของ filter
 type DateFilter struct {
-	StartDate *time.Time `json:"start_date"` // วันที่เริ่มต้น
-	EndDate   *time.Time `json:"end_date"`   // วันที่สิ้นสุด
-	Year      *int       `json:"year"`       // ปีที่ต้องการ filter
+	StartDate *Date `json:"start_date"` // วันที่เริ่มต้น (รองรับปี BCE เช่น "-0490-09-12")
+	EndDate   *Date `json:"end_date"`   // วันที่สิ้นสุด
+	Year      *int  `json:"year"`       // ปีที่ต้องการ filter
+}
+
+// Bounds แปลง filter เป็นช่วง [start, end] (nil = ไม่จำกัด) โดย Year มีความสำคัญกว่า
+func (f *DateFilter) Bounds() (*time.Time, *time.Time) {
+	if f == nil {
+		return nil, nil
+	}
+	if f.Year != nil {
+		start := time.Date(*f.Year, 1, 1, 0, 0, 0, 0, time.UTC)
+		end := time.Date(*f.Year, 12, 31, 23, 59, 59, 0, time.UTC)
+		return &start, &end
+	}
+	return f.StartDate.TimePtr(), f.EndDate.TimePtr()
 }
 
 type ClusterQuery struct {
@@ -80,36 +199,61 @@ type TagFilter struct {
 }
 
 type EventFilter struct {
-	TagFilter  *TagFilter  `json:"tag_filter"`  // ตัวเลือกสำหรับ filter tags
-	DateFilter *DateFilter `json:"date_filter"` // ตัวเลือกสำหรับ filter วันที่
+	TagFilter    *TagFilter    `json:"tag_filter"`    // ตัวเลือกสำหรับ filter tags
+	DateFilter   *DateFilter   `json:"date_filter"`   // ตัวเลือกสำหรับ filter วันที่
+	Viewport     *Viewport     `json:"viewport"`      // เฉพาะ event ที่ทับกับ viewport (รวม geometry)
+	EntityFilter *EntityFilter `json:"entity_filter"` // เฉพาะ event ที่เกี่ยวข้องกับ entity
+	Radius       *RadiusFilter `json:"radius"`        // เฉพาะ event ในรัศมีรอบจุด
+	Area         *Geometry     `json:"area"`          // เฉพาะ event ที่ทับกับ polygon (GeoJSON)
 }
 
-type EventFull struct {
-	EventID     int      `json:"event_id"`
-	EventName   string   `json:"event_name"`
-	Description string   `json:"description"`
-	Date        string   `json:"date"`
-	Lat         float64  `json:"lat"`
-	Lon         float64  `json:"lon"`
-	Image       string   `json:"image"`
-	Video       string   `json:"video"`
-	Tags        []string `json:"tags"`
-	Clusters    []int    `json:"clusters"`
+// HasLocation บอกว่า filter มีเงื่อนไขเชิงพื้นที่ (viewport, radius หรือ area)
+func (f EventFilter) HasLocation() bool {
+	return f.Viewport != nil || f.Radius != nil || f.Area != nil
 }
 
+// MatchesLocation ตรวจ viewport, radius และ area ของ filter (ที่ไม่ได้กำหนดถือว่าผ่าน)
+func (f EventFilter) MatchesLocation(lat, lon float64, g *Geometry) bool {
+	if f.Viewport != nil && !f.Viewport.Intersects(lat, lon, g) {
+		return false
+	}
+	if f.Radius != nil && !f.Radius.Contains(lat, lon, g) {
+		return false
+	}
+	if f.Area != nil && !AreaIntersects(f.Area, lat, lon, g) {
+		return false
+	}
+	return true
+}
+
+type EventFull struct {
+	EventID       int       `json:"event_id"`
+	EventName     string    `json:"event_name"`
+	Description   string    `json:"description"`
+	Date          string    `json:"date"`
+	DatePrecision string    `json:"date_precision"`
+	EndDate       *string   `json:"end_date"`
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	Geometry      *Geometry `json:"geometry"`
+	Image         string    `json:"image"`
+	Video         string    `json:"video"`
+	Tags          []string  `json:"tags"`
+	Clusters      []int     `json:"clusters"`
+}
 
 type ClusterResponse struct {
-	ClusterID         int          `json:"cluster_id"`
-	ParentClusterID   *int         `json:"parent_cluster_id"`
-	CentroidLat       float64      `json:"centroid_lat"`
-	CentroidLon       float64      `json:"centroid_lon"`
-	CentroidTimeDays  float64      `json:"centroid_time_days"`
-	Level             int          `json:"level"`
-	MinDate           time.Time    `json:"min_date"`
-	MaxDate           time.Time    `json:"max_date"`
-	MinLat            float64      `json:"min_lat"`
-	MaxLat            float64      `json:"max_lat"`
-	MinLon            float64      `json:"min_lon"`
-	MaxLon            float64      `json:"max_lon"`
-	Events            []EventFull  `json:"events"` // สำคัญ!
-}
\ No newline at end of file
+	ClusterID        int         `json:"cluster_id"`
+	ParentClusterID  *int        `json:"parent_cluster_id"`
+	CentroidLat      float64     `json:"centroid_lat"`
+	CentroidLon      float64     `json:"centroid_lon"`
+	CentroidTimeDays float64     `json:"centroid_time_days"`
+	Level            int         `json:"level"`
+	MinDate          Date        `json:"min_date"`
+	MaxDate          Date        `json:"max_date"`
+	MinLat           float64     `json:"min_lat"`
+	MaxLat           float64     `json:"max_lat"`
+	MinLon           float64     `json:"min_lon"`
+	MaxLon           float64     `json:"max_lon"`
+	Events           []EventFull `json:"events"` // สำคัญ!
+}
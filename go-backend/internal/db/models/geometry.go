@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ชนิดของ geometry (ตาม GeoJSON)
+const (
+	GeometryPoint      = "Point"
+	GeometryLineString = "LineString"
+	GeometryPolygon    = "Polygon"
+)
+
+// Geometry คือรูปทรงของ event ที่ไม่ใช่จุดเดียว เช่น เส้นทางเดินทัพ (LineString)
+// หรือพื้นที่ที่ถูกล้อม (Polygon) เก็บในคอลัมน์ event.geometry (jsonb)
+// และ encode/decode เป็น GeoJSON geometry: พิกัดเรียงเป็น [lon, lat]
+type Geometry struct {
+	Type string
+	// Parts: Point = [[p]], LineString = [path], Polygon = rings (ring แรกคือขอบนอก)
+	Parts [][][2]float64
+}
+
+// Coordinates คืนทุกจุดของ geometry เรียงตามลำดับ
+func (g *Geometry) Coordinates() [][2]float64 {
+	var out [][2]float64
+	for _, part := range g.Parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	var coords interface{}
+	switch g.Type {
+	case GeometryPoint:
+		if len(g.Parts) == 0 || len(g.Parts[0]) == 0 {
+			return nil, errors.New("point geometry has no coordinates")
+		}
+		coords = g.Parts[0][0]
+	case GeometryLineString:
+		if len(g.Parts) == 0 {
+			return nil, errors.New("linestring geometry has no coordinates")
+		}
+		coords = g.Parts[0]
+	case GeometryPolygon:
+		coords = g.Parts
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q", g.Type)
+	}
+	return json.Marshal(struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}{g.Type, coords})
+}
+
+func (g *Geometry) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	switch raw.Type {
+	case GeometryPoint:
+		var p [2]float64
+		if err := json.Unmarshal(raw.Coordinates, &p); err != nil {
+			return fmt.Errorf("invalid point coordinates: %w", err)
+		}
+		g.Parts = [][][2]float64{{p}}
+	case GeometryLineString:
+		var path [][2]float64
+		if err := json.Unmarshal(raw.Coordinates, &path); err != nil {
+			return fmt.Errorf("invalid linestring coordinates: %w", err)
+		}
+		if len(path) < 2 {
+			return errors.New("linestring needs at least 2 positions")
+		}
+		g.Parts = [][][2]float64{path}
+	case GeometryPolygon:
+		var rings [][][2]float64
+		if err := json.Unmarshal(raw.Coordinates, &rings); err != nil {
+			return fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		if len(rings) == 0 {
+			return errors.New("polygon needs at least one ring")
+		}
+		for _, ring := range rings {
+			if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+				return errors.New("polygon rings need at least 4 positions and must be closed")
+			}
+		}
+		g.Parts = rings
+	default:
+		return fmt.Errorf("unsupported geometry type %q", raw.Type)
+	}
+	g.Type = raw.Type
+	return nil
+}
@@ -0,0 +1,41 @@
+package models
+
+const (
+	HeatmapGridLatLon    = "latlon"
+	HeatmapGridEqualArea = "equal_area"
+
+	DefaultHeatmapCellSize = 2.0 // องศา
+	MinHeatmapCellSize     = 0.1
+	MaxHeatmapCellSize     = 90.0
+)
+
+// HeatmapQuery คือ EventFilter (date_filter คือช่วงเวลาของ heatmap) พร้อมการตั้งค่า grid
+// ถ้า sigma_days > 0 แต่ละ event จะมีน้ำหนักแบบ Gaussian ตามระยะห่างจาก center
+// (default: จุดกึ่งกลางของ date_filter) แบบเดียวกับ normalize_date_with_gaussian ฝั่ง Python
+type HeatmapQuery struct {
+	EventFilter
+	Grid      string  `json:"grid"`       // latlon (default) หรือ equal_area
+	CellSize  float64 `json:"cell_size"`  // ขนาด cell (องศา)
+	Center    *Date   `json:"center"`     // จุดศูนย์กลางของน้ำหนักเวลา
+	SigmaDays float64 `json:"sigma_days"` // 0 = ไม่ถ่วงน้ำหนัก (ทุก event มีน้ำหนัก 1)
+}
+
+type HeatmapCell struct {
+	Row       int     `json:"row"`
+	Col       int     `json:"col"`
+	South     float64 `json:"south"`
+	North     float64 `json:"north"`
+	West      float64 `json:"west"`
+	East      float64 `json:"east"`
+	Count     int     `json:"count"`
+	Intensity float64 `json:"intensity"` // ผลรวมน้ำหนักของ event ใน cell
+}
+
+type Heatmap struct {
+	Grid         string        `json:"grid"`
+	CellSize     float64       `json:"cell_size"`
+	Center       *Date         `json:"center,omitempty"`
+	SigmaDays    float64       `json:"sigma_days"`
+	MaxIntensity float64       `json:"max_intensity"` // ใช้ normalize สีฝั่ง frontend
+	Cells        []HeatmapCell `json:"cells"`         // เฉพาะ cell ที่มี event
+}
@@ -0,0 +1,26 @@
+package models
+
+// รูปแบบ hull ของ cluster ในโหมด PostGIS
+const (
+	HullConvex  = "convex"
+	HullConcave = "concave"
+
+	// DefaultConcaveHullRatio ใช้กับ ST_ConcaveHull: 1 = convex, ค่าน้อยลง = เว้าตามรูปของ event มากขึ้น
+	DefaultConcaveHullRatio = 0.8
+)
+
+type HullOptions struct {
+	Mode  string  `json:"mode"`  // convex (ค่าเริ่มต้น) | concave
+	Ratio float64 `json:"ratio"` // เฉพาะ concave: มากกว่า 0 ถึง 1
+}
+
+// ValidHullOptions ตรวจ mode และ ratio (ratio 0 = ใช้ค่าเริ่มต้น)
+func ValidHullOptions(o HullOptions) bool {
+	switch o.Mode {
+	case "", HullConvex:
+		return true
+	case HullConcave:
+		return o.Ratio >= 0 && o.Ratio <= 1
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package models
+
+// metric ของการหา event ใกล้เคียง
+const (
+	NearbyMetricSpatial  = "spatial"  // ระยะทาง (กม.)
+	NearbyMetricTemporal = "temporal" // ระยะเวลา (วัน)
+	NearbyMetricWeighted = "weighted" // sqrt(ws*km² + wt*days²) แบบ find_weighted_dist ฝั่ง Python
+
+	DefaultNearbyK              = 10
+	MaxNearbyK                  = 100
+	DefaultNearbySpatialWeight  = 1.0
+	DefaultNearbyTemporalWeight = 3.0 // ตรงกับน้ำหนักแกนเวลาใน find_weighted_dist
+)
+
+func ValidNearbyMetric(metric string) bool {
+	switch metric {
+	case NearbyMetricSpatial, NearbyMetricTemporal, NearbyMetricWeighted:
+		return true
+	}
+	return false
+}
+
+// NearbyQuery คือจุดอ้างอิง (lat/lon และวันที่) สำหรับหา K event ที่ใกล้ที่สุด
+type NearbyQuery struct {
+	Lat            float64
+	Lon            float64
+	Date           *Date // จำเป็นสำหรับ metric temporal และ weighted
+	K              int
+	Metric         string
+	SpatialWeight  float64
+	TemporalWeight float64
+	ExcludeID      int // ไม่รวม event นี้ (ใช้กับ /events/:id/nearby)
+}
+
+type NearbyEvent struct {
+	Event        EventResponse `json:"event"`
+	DistanceKm   float64       `json:"distance_km"`
+	DistanceDays *float64      `json:"distance_days,omitempty"`
+	Distance     float64       `json:"distance"` // ค่าตาม metric ที่เลือก ใช้เรียงลำดับ
+}
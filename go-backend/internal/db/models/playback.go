@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// MaxPlaybackFrames จำกัดจำนวน frame ต่อ request
+const MaxPlaybackFrames = 2000
+
+// PlaybackQuery คือ EventFilter (tag, viewport, entity) พร้อมช่วงเวลาและขนาด step ของ animation
+// date_filter จะถูกแทนที่ด้วย start/end
+type PlaybackQuery struct {
+	EventFilter
+	Start    *Date  `json:"start"`
+	End      *Date  `json:"end"`
+	Step     string `json:"step"`      // day, week, month, year
+	StepSize int    `json:"step_size"` // จำนวน step ต่อ frame (default 1)
+}
+
+func ValidPlaybackStep(step string) bool {
+	switch step {
+	case HistogramBucketDay, HistogramBucketWeek, HistogramBucketMonth, HistogramBucketYear:
+		return true
+	}
+	return false
+}
+
+// AddPlaybackSteps เลื่อน t ไป n step (ใช้ AddDate จากจุดเริ่มเสมอเพื่อไม่ให้วันที่เลื่อนสะสมปลายเดือน)
+func AddPlaybackSteps(t time.Time, step string, n int) time.Time {
+	switch step {
+	case HistogramBucketWeek:
+		return t.AddDate(0, 0, 7*n)
+	case HistogramBucketMonth:
+		return t.AddDate(0, n, 0)
+	case HistogramBucketYear:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// PlaybackFrame เก็บเฉพาะความต่างจาก frame ก่อนหน้า (frame แรก: enter = event ที่เห็นทั้งหมด)
+type PlaybackFrame struct {
+	Index   int   `json:"index"`
+	Start   Date  `json:"start"`
+	End     Date  `json:"end"` // ไม่รวมวันนี้ (= start ของ frame ถัดไป)
+	Enter   []int `json:"enter"`
+	Leave   []int `json:"leave"`
+	Visible int   `json:"visible"` // จำนวน event ที่เห็นใน frame นี้
+}
+
+type PlaybackFrames struct {
+	Start    Date            `json:"start"`
+	End      Date            `json:"end"`
+	Step     string          `json:"step"`
+	StepSize int             `json:"step_size"`
+	Events   []EventResponse `json:"events"` // ข้อมูล event ทุกตัวที่ปรากฏ (ส่งครั้งเดียว frame อ้างด้วย event_id)
+	Frames   []PlaybackFrame `json:"frames"`
+}
@@ -0,0 +1,33 @@
+package models
+
+// EventRecord คือแถวของ event แบบดิบ (nullable) ใช้สำหรับตรวจคุณภาพข้อมูล
+type EventRecord struct {
+	EventID   int      `json:"event_id"`
+	EventName *string  `json:"event_name"`
+	Date      *Date    `json:"date"`
+	Precision *string  `json:"date_precision"`
+	EndDate   *Date    `json:"end_date"`
+	Lat       *float64 `json:"lat"`
+	Lon       *float64 `json:"lon"`
+	Image     *string  `json:"image"`
+	Video     *string  `json:"video"`
+}
+
+type QualityIssue struct {
+	Code    string `json:"code"`    // เช่น invalid_coordinates, empty_name
+	Field   string `json:"field"`   // field ที่มีปัญหา
+	Message string `json:"message"` // คำอธิบาย
+}
+
+type EventQualityReport struct {
+	EventID   int            `json:"event_id"`
+	EventName string         `json:"event_name"`
+	Issues    []QualityIssue `json:"issues"`
+}
+
+type QualityReport struct {
+	TotalEvents   int                  `json:"total_events"`
+	FlaggedEvents int                  `json:"flagged_events"`
+	IssueCounts   map[string]int       `json:"issue_counts"`
+	Events        []EventQualityReport `json:"events"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// ชนิดของความสัมพันธ์ระหว่าง event (ทิศทาง: source -> target)
+const (
+	RelationCauses       = "causes"        // source เป็นเหตุของ target
+	RelationPartOf       = "part_of"       // source เป็นส่วนหนึ่งของ target
+	RelationPrecededBy   = "preceded_by"   // source เกิดหลัง target โดยตรง
+	RelationSameCampaign = "same_campaign" // อยู่ในยุทธการเดียวกัน
+	RelationRelatedTo    = "related_to"    // เกี่ยวข้องกันแบบทั่วไป
+)
+
+// ValidRelationType ตรวจว่า relation_type เป็นค่าที่รองรับ
+func ValidRelationType(t string) bool {
+	switch t {
+	case RelationCauses, RelationPartOf, RelationPrecededBy, RelationSameCampaign, RelationRelatedTo:
+		return true
+	}
+	return false
+}
+
+type EventRelation struct {
+	RelationID    int    `json:"relation_id"`
+	SourceEventID int    `json:"source_event_id"`
+	TargetEventID int    `json:"target_event_id"`
+	RelationType  string `json:"relation_type"`
+	Note          string `json:"note"`
+}
+
+type EventGraph struct {
+	RootEventID int             `json:"root_event_id"`
+	Depth       int             `json:"depth"`
+	Nodes       []EventResponse `json:"nodes"`
+	Edges       []EventRelation `json:"edges"`
+}
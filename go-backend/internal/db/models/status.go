@@ -0,0 +1,8 @@
+package models
+
+// DataCounts คือจำนวนข้อมูลหลักในฐานข้อมูล สำหรับ /api/status
+type DataCounts struct {
+	Events          int `json:"events"`
+	ClusteredEvents int `json:"clustered_events"` // event ที่อยู่ใน cluster อย่างน้อยหนึ่ง cluster
+	Clusters        int `json:"clusters"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// ขนาด bucket ของ timeline histogram
+const (
+	HistogramBucketAuto  = "auto"
+	HistogramBucketDay   = "day"
+	HistogramBucketWeek  = "week"
+	HistogramBucketMonth = "month"
+	HistogramBucketYear  = "year"
+)
+
+// MaxHistogramBuckets จำกัดจำนวน bucket ต่อ request (เช่น bucket=day ช่วงหลายพันปี)
+const MaxHistogramBuckets = 10000
+
+func ValidHistogramBucket(bucket string) bool {
+	switch bucket {
+	case HistogramBucketAuto, HistogramBucketDay, HistogramBucketWeek, HistogramBucketMonth, HistogramBucketYear:
+		return true
+	}
+	return false
+}
+
+// HistogramQuery คือ EventFilter (รวม viewport) พร้อมขนาด bucket ("" = auto)
+type HistogramQuery struct {
+	EventFilter
+	Bucket string `json:"bucket"`
+}
+
+type HistogramBucket struct {
+	Start Date           `json:"start"` // วันแรกของ bucket
+	Count int            `json:"count"`
+	Tags  map[string]int `json:"tags"` // จำนวน event ต่อ tag ใน bucket นี้
+}
+
+type TimelineHistogram struct {
+	Bucket  string            `json:"bucket"` // ขนาด bucket ที่ใช้จริง (auto จะถูกแปลงแล้ว)
+	Start   *Date             `json:"start"`  // nil เมื่อไม่มี event
+	End     *Date             `json:"end"`
+	Total   int               `json:"total"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+// AutoHistogramBucket เลือกขนาด bucket ตามความยาวของช่วงเวลา ให้ได้ราว 50-500 bucket
+func AutoHistogramBucket(start, end time.Time) string {
+	days := (end.Unix() - start.Unix()) / 86400
+	switch {
+	case days <= 180:
+		return HistogramBucketDay
+	case days <= 3*365:
+		return HistogramBucketWeek
+	case days <= 40*365:
+		return HistogramBucketMonth
+	default:
+		return HistogramBucketYear
+	}
+}
+
+// HistogramBucketCount ประมาณจำนวน bucket ระหว่าง start ถึง end (รวมทั้งสองฝั่ง)
+func HistogramBucketCount(bucket string, start, end time.Time) int64 {
+	switch bucket {
+	case HistogramBucketDay:
+		return (end.Unix()-start.Unix())/86400 + 1
+	case HistogramBucketWeek:
+		return (end.Unix()-start.Unix())/(7*86400) + 2
+	case HistogramBucketMonth:
+		return int64(end.Year()-start.Year())*12 + int64(end.Month()-start.Month()) + 1
+	default:
+		return int64(end.Year()-start.Year()) + 1
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+// CameraPosition คือมุมกล้องบนลูกโลกสำหรับแต่ละ step
+type CameraPosition struct {
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	Altitude float64 `json:"altitude"` // ความสูงของกล้อง (กม.)
+	Heading  float64 `json:"heading"`  // องศา 0-360
+	Tilt     float64 `json:"tilt"`     // องศา 0-90
+}
+
+// TourStep อ้างถึง event หรือ cluster อย่างใดอย่างหนึ่ง
+type TourStep struct {
+	StepID          int            `json:"step_id"`
+	Position        int            `json:"position"` // ลำดับใน tour (เริ่มที่ 0)
+	EventID         *int           `json:"event_id"`
+	ClusterID       *int           `json:"cluster_id"`
+	Camera          CameraPosition `json:"camera"`
+	WindowStart     *Date          `json:"window_start"` // ช่วงเวลาที่ timeline จะแสดงใน step นี้
+	WindowEnd       *Date          `json:"window_end"`
+	Narration       string         `json:"narration"`
+	DurationSeconds int            `json:"duration_seconds"`
+}
+
+type Tour struct {
+	TourID      int        `json:"tour_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Steps       []TourStep `json:"steps"`
+}
+
+type TourPlaybackStep struct {
+	TourStep
+	Events  []EventResponse `json:"events"`  // event ของ step (cluster: event ใน cluster ที่อยู่ในช่วงเวลา)
+	Missing bool            `json:"missing"` // event/cluster ที่อ้างถึงถูกลบไปแล้ว
+}
+
+type TourPlayback struct {
+	TourID      int                `json:"tour_id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Steps       []TourPlaybackStep `json:"steps"`
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+	"globe/internal/geo"
+	"globe/internal/quality"
+)
+
+// ReindexEventCells คำนวณ HTM cell ทุก resolution (0..MaxCellResolution) ของ event แล้ว upsert ลง event_cell
+// full = false จะคำนวณเฉพาะ event ที่ยังไม่มี cell (trigger event_cell_stale ลบ cell ของ event ที่ lat/lon เปลี่ยน)
+// การ upsert ทำให้เรียกพร้อมกันหลายครั้งได้โดยไม่ชน primary key
+func ReindexEventCells(ctx context.Context, full bool) (models.CellIndexResult, error) {
+	var result models.CellIndexResult
+
+	query := `SELECT e.event_id, e.lat, e.lon FROM event e`
+	if !full {
+		query += ` WHERE NOT EXISTS (SELECT 1 FROM event_cell ec WHERE ec.event_id = e.event_id)`
+	}
+	rows, err := connection.DB.Query(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return result, err
+	}
+	var eventIDs, resolutions []int
+	var cellIDs []int64
+	var skipped []int
+	for rows.Next() {
+		var id int
+		var lat, lon float64
+		if err := rows.Scan(&id, &lat, &lon); err != nil {
+			rows.Close()
+			return result, err
+		}
+		if !quality.ValidCoordinates(lat, lon) {
+			skipped = append(skipped, id)
+			continue
+		}
+		// คำนวณที่ resolution สูงสุดครั้งเดียวแล้ว shift หา cell พ่อ
+		finest := geo.HTMCell(lat, lon, models.MaxCellResolution)
+		for res := 0; res <= models.MaxCellResolution; res++ {
+			eventIDs = append(eventIDs, id)
+			resolutions = append(resolutions, res)
+			cellIDs = append(cellIDs, geo.HTMParent(finest, res))
+		}
+		result.Indexed++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	result.Skipped = len(skipped)
+
+	tx, err := connection.DB.Begin(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO event_cell (event_id, res, cell_id)
+		SELECT * FROM unnest($1::int[], $2::smallint[], $3::bigint[])
+		ON CONFLICT (event_id, res) DO UPDATE SET cell_id = EXCLUDED.cell_id
+	`, eventIDs, resolutions, cellIDs); err != nil {
+		slog.ErrorContext(ctx, "Upsert event_cell failed", "err", err)
+		return result, err
+	}
+	// event ที่ lat/lon ใช้ไม่ได้แล้วต้องไม่มี cell ค้างอยู่
+	if len(skipped) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM event_cell WHERE event_id = ANY($1)`, skipped); err != nil {
+			return result, err
+		}
+	}
+	return result, tx.Commit(ctx)
+}
+
+// GetCellAggregates นับ event ที่ผ่าน filter ต่อ HTM cell ที่ resolution res โดยไม่เขียนฐานข้อมูล
+// event ที่มี cell ใน event_cell แล้วนับใน SQL ส่วน event ที่ยังไม่ถูก index (เพิ่มจากภายนอก API
+// หรือ lat/lon เพิ่งเปลี่ยน) คำนวณ cell ใน Go แล้วรวมเข้าด้วยกัน
+func GetCellAggregates(ctx context.Context, res int, filter models.EventFilter) ([]models.CellAggregate, error) {
+	conds, args, err := aggregateFilterSQL(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	conds += validCoordinatesSQL + ` AND e.date IS NOT NULL`
+
+	cells := make(map[int64]*models.CellAggregate)
+	add := func(id int64, count int, minDate, maxDate models.Date) {
+		cell, ok := cells[id]
+		if !ok {
+			cells[id] = &models.CellAggregate{CellID: id, Res: res, Count: count, MinDate: minDate, MaxDate: maxDate}
+			return
+		}
+		cell.Count += count
+		if minDate.Before(cell.MinDate.Time) {
+			cell.MinDate = minDate
+		}
+		if maxDate.After(cell.MaxDate.Time) {
+			cell.MaxDate = maxDate
+		}
+	}
+
+	rows, err := connection.DB.Query(ctx, fmt.Sprintf(`
+		SELECT ec.cell_id, COUNT(*), MIN(e.date), MAX(e.date)
+		FROM event_cell ec
+		JOIN event e ON e.event_id = ec.event_id
+		WHERE ec.res = $%d %s
+		GROUP BY ec.cell_id
+	`, len(args)+1, conds), append(args, res)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var count int
+		var minDate, maxDate models.Date
+		if err := rows.Scan(&id, &count, &minDate, &maxDate); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		add(id, count, minDate, maxDate)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = connection.DB.Query(ctx, `
+		SELECT e.lat, e.lon, e.date FROM event e
+		WHERE NOT EXISTS (SELECT 1 FROM event_cell ec WHERE ec.event_id = e.event_id)`+conds, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	unindexed := 0
+	for rows.Next() {
+		var lat, lon float64
+		var date models.Date
+		if err := rows.Scan(&lat, &lon, &date); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		add(geo.HTMCell(lat, lon, res), 1, date, date)
+		unindexed++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if unindexed > 0 {
+		slog.InfoContext(ctx, "Computed cells for unindexed events, run POST /api/cells/reindex to index them", "events", unindexed)
+	}
+
+	out := make([]models.CellAggregate, 0, len(cells))
+	for _, cell := range cells {
+		vertices, lat, lon := geo.HTMVertices(cell.CellID)
+		ring := [][2]float64{vertices[0], vertices[1], vertices[2], vertices[0]}
+		cell.Name = geo.HTMName(cell.CellID)
+		cell.CenterLat, cell.CenterLon = lat, lon
+		cell.Shape = &models.Geometry{Type: models.GeometryPolygon, Parts: [][][2]float64{ring}}
+		// สามเหลี่ยม HTM พื้นที่ไม่เท่ากัน (ต่างกันได้ราว 2 เท่า) จึงให้ความหนาแน่นต่อพื้นที่ไว้เทียบระหว่าง cell
+		cell.AreaKm2 = geo.HTMAreaKm2(cell.CellID)
+		cell.Density = float64(cell.Count) / cell.AreaKm2
+		out = append(out, *cell)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CellID < out[j].CellID })
+	return out, nil
+}
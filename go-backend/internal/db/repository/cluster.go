@@ -2,19 +2,25 @@ package repository
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"strings"
-	"time"
 
 	"globe/internal/db/connection"
 	"globe/internal/db/models"
 )
 
 // InsertClustersAndMappings inserts clusters and their event mappings into the database.
-func InsertClustersAndMappings(clusters []models.Cluster) error {
+// ทั้งหมด (รวม hull ในโหมด PostGIS) อยู่ใน transaction เดียว ถ้า ctx ถูกยกเลิกกลางทางจะไม่เหลือ cluster ครึ่ง ๆ กลาง ๆ
+func InsertClustersAndMappings(ctx context.Context, clusters []models.Cluster) error {
+	tx, err := connection.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	for _, cluster := range clusters {
 		// Insert cluster
-		_, err := connection.DB.Exec(context.Background(),
+		_, err := tx.Exec(ctx,
 			`INSERT INTO cluster (
 				cluster_id, parent_cluster_id, centroid_lat, centroid_lon,
 				centroid_time_days, level
@@ -29,29 +35,42 @@ func InsertClustersAndMappings(clusters []models.Cluster) error {
 			cluster.Level,
 		)
 		if err != nil {
-			log.Printf("Insert cluster error: %v", err)
+			slog.ErrorContext(ctx, "Insert cluster failed", "err", err)
 			return err
 		}
 
 		// Insert event-cluster mapping
 		for _, eventID := range cluster.EventIDs {
-			_, err := connection.DB.Exec(context.Background(),
+			_, err := tx.Exec(ctx,
 				`INSERT INTO eventclustermap (event_id, cluster_id)
 				VALUES ($1, $2)
 				ON CONFLICT (event_id, cluster_id) DO NOTHING
 				`, eventID, cluster.ClusterID)
 			if err != nil {
-				log.Printf("Insert eventclustermap error: %v", err)
+				slog.ErrorContext(ctx, "Insert eventclustermap failed", "err", err)
 				return err
 			}
 		}
 	}
-	return nil
+
+	// โหมด PostGIS: คำนวณ hull ใหม่หลังได้ mapping ครบ
+	if postGIS.enabled && len(clusters) > 0 {
+		if _, err := refreshClusterHulls(ctx, tx, postGIS.hull); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
 // GetHierarchicalClusters ดึง clusters แบบ hierarchical ตาม viewport และ filter
-func GetHierarchicalClusters(query models.ClusterQuery) ([]models.Cluster, error) {
-	log.Println("[DEBUG] Start querying hierarchical clusters (recursive BBOX & date)")
+func GetHierarchicalClusters(ctx context.Context, query models.ClusterQuery) ([]models.Cluster, error) {
+	slog.DebugContext(ctx, "Querying hierarchical clusters", "max_level", query.MaxLevel)
+
+	// hull มีเฉพาะโหมด PostGIS (cluster_id เป็น primary key จึงไม่ต้องใส่ใน GROUP BY)
+	hullSQL := "NULL::jsonb"
+	if postGIS.enabled {
+		hullSQL = "ST_AsGeoJSON(c.hull)::jsonb"
+	}
 
 	baseQuery := `
 		SELECT 
@@ -63,22 +82,21 @@ func GetHierarchicalClusters(query models.ClusterQuery) ([]models.Cluster, error
 			c.level,
 			ARRAY_AGG(DISTINCT ecm.event_id) as event_ids,
 			c.min_lat, c.max_lat, c.min_lon, c.max_lon,
-			c.min_date, c.max_date
+			c.min_date, c.max_date,
+			` + hullSQL + ` as hull
 		FROM cluster c
 		LEFT JOIN eventclustermap ecm ON c.cluster_id = ecm.cluster_id
 		WHERE c.level <= $1
 		GROUP BY c.cluster_id, c.parent_cluster_id, c.centroid_lat, c.centroid_lon, c.centroid_time_days, c.level, c.min_lat, c.max_lat, c.min_lon, c.max_lon, c.min_date, c.max_date
 	`
-	rows, err := connection.DB.Query(context.Background(), baseQuery, query.MaxLevel)
+	rows, err := connection.DB.Query(ctx, baseQuery, query.MaxLevel)
 	if err != nil {
-		log.Printf("[ERROR] Query failed: %v", err)
+		slog.ErrorContext(ctx, "Query failed", "err", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	var clusters []models.Cluster
-	parentMap := make(map[int][]models.Cluster)
-	clusterMap := make(map[int]models.Cluster)
 
 	for rows.Next() {
 		var cluster models.Cluster
@@ -92,22 +110,17 @@ func GetHierarchicalClusters(query models.ClusterQuery) ([]models.Cluster, error
 			&cluster.EventIDs,
 			&cluster.MinLat, &cluster.MaxLat, &cluster.MinLon, &cluster.MaxLon,
 			&cluster.MinDate, &cluster.MaxDate,
+			&cluster.Hull,
 		)
 		if err != nil {
-			log.Printf("[ERROR] Scanning row failed: %v", err)
+			slog.ErrorContext(ctx, "Scanning row failed", "err", err)
 			continue
 		}
 		clusters = append(clusters, cluster)
-		pid := 0
-		if cluster.ParentClusterID != nil {
-			pid = *cluster.ParentClusterID
-		}
-		parentMap[pid] = append(parentMap[pid], cluster)
-		clusterMap[cluster.ClusterID] = cluster
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("[ERROR] Rows error: %v", err)
+		slog.ErrorContext(ctx, "Rows error", "err", err)
 		return nil, err
 	}
 
@@ -118,17 +131,36 @@ func GetHierarchicalClusters(query models.ClusterQuery) ([]models.Cluster, error
 			allEventIDs[eid] = struct{}{}
 		}
 	}
-	eventDetails, err := loadEventDetails(allEventIDs)
+	eventDetails, err := loadEventDetails(ctx, allEventIDs)
 	if err != nil {
-		log.Printf("[ERROR] loading event details failed: %v", err)
+		slog.ErrorContext(ctx, "Loading event details failed", "err", err)
 		return nil, err
 	}
 
+	result := FilterClusterHierarchy(clusters, eventDetails, query)
+	slog.DebugContext(ctx, "Hierarchical clusters after filter", "clusters", len(result))
+	return result, nil
+}
+
+// FilterClusterHierarchy เดิน tree ของ cluster จาก root แล้วเก็บเฉพาะ cluster ที่ทับกับ viewport และช่วงวันที่
+// leaf cluster จะได้ event (จาก eventDetails) ที่ผ่าน filter โดย event หนึ่งอยู่ได้ใน cluster เดียว
+func FilterClusterHierarchy(clusters []models.Cluster, eventDetails map[int]models.EventResponse, query models.ClusterQuery) []models.Cluster {
+	parentMap := make(map[int][]models.Cluster)
+	for _, cluster := range clusters {
+		pid := 0
+		if cluster.ParentClusterID != nil {
+			pid = *cluster.ParentClusterID
+		}
+		parentMap[pid] = append(parentMap[pid], cluster)
+	}
+
 	isLeaf := func(clusterID int) bool {
 		children, ok := parentMap[clusterID]
 		return !ok || len(children) == 0
 	}
 
+	start, end := query.DateFilter.Bounds()
+
 	var result []models.Cluster
 	usedEventIDs := make(map[int]struct{}) // เก็บ event ที่ถูกใช้ไปแล้ว
 	var traverse func(parentID int)
@@ -148,14 +180,11 @@ func GetHierarchicalClusters(query models.ClusterQuery) ([]models.Cluster, error
 			}
 			if query.DateFilter != nil {
 				if query.DateFilter.Year != nil {
-					year := *query.DateFilter.Year
-					start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
-					end := time.Date(year, 12, 31, 23, 59, 59, 0, time.UTC)
-					if c.MaxDate.Before(start) || c.MinDate.After(end) {
+					if c.MaxDate.Before(*start) || c.MinDate.After(*end) {
 						continue
 					}
 				} else if query.DateFilter.StartDate != nil && query.DateFilter.EndDate != nil {
-					if c.MaxDate.Before(*query.DateFilter.StartDate) || c.MinDate.After(*query.DateFilter.EndDate) {
+					if c.MaxDate.Before(query.DateFilter.StartDate.Time) || c.MinDate.After(query.DateFilter.EndDate.Time) {
 						continue
 					}
 				}
@@ -169,7 +198,7 @@ func GetHierarchicalClusters(query models.ClusterQuery) ([]models.Cluster, error
 					}
 					if ev, ok := eventDetails[eid]; ok {
 						if query.DateFilter != nil && query.DateFilter.Year != nil {
-							if ev.Date.Year() != *query.DateFilter.Year {
+							if !models.DateSpanOverlaps(ev.Date.Time, ev.DatePrecision, ev.EndDate.TimePtr(), start, end) {
 								continue
 							}
 						}
@@ -207,13 +236,11 @@ func GetHierarchicalClusters(query models.ClusterQuery) ([]models.Cluster, error
 		}
 	}
 	traverse(0)
-
-	log.Printf("[DEBUG] Total clusters after filter: %d", len(result))
-	return result, nil
+	return result
 }
 
 // loadEventDetails คืน map[event_id]EventResponse
-func loadEventDetails(idSet map[int]struct{}) (map[int]models.EventResponse, error) {
+func loadEventDetails(ctx context.Context, idSet map[int]struct{}) (map[int]models.EventResponse, error) {
 	if len(idSet) == 0 {
 		return map[int]models.EventResponse{}, nil
 	}
@@ -227,8 +254,11 @@ func loadEventDetails(idSet map[int]struct{}) (map[int]models.EventResponse, err
 			e.event_id,
 			e.event_name,
 			e.date,
+			e.date_precision,
+			e.end_date,
 			e.lat,
 			e.lon,
+			e.geometry,
 			e.image,
 			e.video,
 			e.description,
@@ -242,7 +272,7 @@ func loadEventDetails(idSet map[int]struct{}) (map[int]models.EventResponse, err
 		GROUP BY e.event_id;
 	`
 
-	rows, err := connection.DB.Query(context.Background(), q, ids)
+	rows, err := connection.DB.Query(ctx, q, ids)
 	if err != nil {
 		return nil, err
 	}
@@ -252,7 +282,7 @@ func loadEventDetails(idSet map[int]struct{}) (map[int]models.EventResponse, err
 	for rows.Next() {
 		var ev models.EventResponse
 		if err := rows.Scan(
-			&ev.EventID, &ev.EventName, &ev.Date, &ev.Lat, &ev.Lon,
+			&ev.EventID, &ev.EventName, &ev.Date, &ev.DatePrecision, &ev.EndDate, &ev.Lat, &ev.Lon, &ev.Geometry,
 			&ev.Image, &ev.Video, &ev.Description, &ev.Tags, &ev.Clusters,
 		); err != nil {
 			return nil, err
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrClusterJobNotFound = errors.New("cluster job not found")
+	// ErrClusterJobLeaseLost คือการบันทึกงานที่ worker ไม่ได้ถืออยู่แล้ว (lease หมดและงานถูกนำกลับเข้าคิว)
+	ErrClusterJobLeaseLost = errors.New("cluster job lease lost")
+)
+
+const clusterJobColumns = `job_id, status, engine, params, stage, progress, result, COALESCE(error, ''),
+	attempts, created_at, started_at, finished_at, COALESCE(owner, ''), lease_until`
+
+// clusterJobQueueLockID คือ advisory lock ที่ทำให้การตรวจขนาดคิวกับการเพิ่มงานของแต่ละ instance ไม่ซ้อนกัน
+const clusterJobQueueLockID = 7201605
+
+// CreateClusterJob บันทึกงานใหม่และคืนงานพร้อม job_id และ created_at
+// เฉพาะเมื่อมีงานในคิวน้อยกว่า maxQueued (false ถ้าคิวเต็ม)
+func CreateClusterJob(ctx context.Context, job models.ClusterJob, maxQueued int) (models.ClusterJob, bool, error) {
+	tx, err := connection.DB.Begin(ctx)
+	if err != nil {
+		return job, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// ใน READ COMMITTED สอง request ที่นับพร้อมกันจะเห็นจำนวนเดียวกัน จึงต้องถือ lock ก่อนนับ
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, clusterJobQueueLockID); err != nil {
+		return job, false, err
+	}
+	job, err = scanClusterJob(tx.QueryRow(ctx, `
+		INSERT INTO cluster_job (status, engine, params)
+		SELECT $1, $2, $3
+		WHERE (SELECT COUNT(*) FROM cluster_job WHERE status = $4) < $5
+		RETURNING `+clusterJobColumns, job.Status, job.Engine, job.Params, models.ClusterJobQueued, maxQueued))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Insert cluster_job failed", "err", err)
+		return job, false, err
+	}
+	return job, true, tx.Commit(ctx)
+}
+
+// UpdateClusterJob บันทึกสถานะ ความคืบหน้า และผลของงาน เฉพาะเมื่องานยัง running และ job.Owner ถือ lease อยู่
+// (ErrClusterJobLeaseLost ถ้าไม่ใช่)
+func UpdateClusterJob(ctx context.Context, job models.ClusterJob) error {
+	tag, err := connection.DB.Exec(ctx, `
+		UPDATE cluster_job SET
+			status = $2, stage = $3, progress = $4, result = $5, error = NULLIF($6, ''),
+			attempts = $7, started_at = $8, finished_at = $9,
+			lease_until = CASE WHEN $2 = $11 THEN lease_until END
+		WHERE job_id = $1 AND status = $11 AND owner = $10`,
+		job.JobID, job.Status, job.Stage, job.Progress, job.Result, job.Error,
+		job.Attempts, job.StartedAt, job.FinishedAt, job.Owner, models.ClusterJobRunning)
+	if err != nil {
+		slog.ErrorContext(ctx, "Update cluster_job failed", "err", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrClusterJobLeaseLost
+	}
+	return nil
+}
+
+// ClaimClusterJob เปลี่ยนงานที่เก่าที่สุดในคิวเป็น running ของ owner พร้อม lease ในคำสั่งเดียว (false ถ้าคิวว่าง)
+// FOR UPDATE SKIP LOCKED ทำให้หลาย worker หรือหลาย instance ไม่หยิบงานเดียวกัน
+func ClaimClusterJob(ctx context.Context, owner string, lease time.Duration) (models.ClusterJob, bool, error) {
+	job, err := scanClusterJob(connection.DB.QueryRow(ctx, `
+		UPDATE cluster_job SET
+			status = $1, stage = '', progress = 0, error = NULL,
+			attempts = attempts + 1, started_at = now(),
+			owner = $3, lease_until = now() + make_interval(secs => $4)
+		WHERE job_id = (
+			SELECT job_id FROM cluster_job
+			WHERE status = $2
+			ORDER BY created_at, job_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+clusterJobColumns, models.ClusterJobRunning, models.ClusterJobQueued, owner, lease.Seconds()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Claim cluster_job failed", "err", err)
+		return job, false, err
+	}
+	return job, true, nil
+}
+
+// RenewClusterJobLease ต่อ lease ของงานที่ owner กำลังทำ (false ถ้างานไม่ได้เป็นของ owner แล้ว)
+func RenewClusterJobLease(ctx context.Context, id int, owner string, lease time.Duration) (bool, error) {
+	tag, err := connection.DB.Exec(ctx, `
+		UPDATE cluster_job SET lease_until = now() + make_interval(secs => $4)
+		WHERE job_id = $1 AND status = $2 AND owner = $3`,
+		id, models.ClusterJobRunning, owner, lease.Seconds())
+	if err != nil {
+		slog.ErrorContext(ctx, "Renew cluster_job lease failed", "err", err)
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RequeueExpiredClusterJobs นำงาน running ที่ lease หมดแล้ว (instance ที่ทำอยู่ crash หรือขาดการติดต่อ) กลับเข้าคิว
+// หรือให้ล้มเหลวถ้าเริ่มมาแล้ว maxAttempts ครั้ง ในคำสั่งเดียว คืนงานที่ถูกเปลี่ยน
+// งานที่ไม่มี lease (running มาจากก่อน migration 0013) ถือว่าหมดแล้ว
+func RequeueExpiredClusterJobs(ctx context.Context, maxAttempts int) ([]models.ClusterJob, error) {
+	rows, err := connection.DB.Query(ctx, `
+		UPDATE cluster_job SET
+			status      = CASE WHEN attempts >= $3 THEN $4 ELSE $2 END,
+			error       = CASE WHEN attempts >= $3 THEN 'interrupted ' || attempts || ' times' END,
+			finished_at = CASE WHEN attempts >= $3 THEN now() END,
+			stage       = CASE WHEN attempts >= $3 THEN stage ELSE '' END,
+			progress    = CASE WHEN attempts >= $3 THEN progress ELSE 0 END,
+			started_at  = CASE WHEN attempts >= $3 THEN started_at END,
+			lease_until = NULL
+		WHERE status = $1 AND (lease_until IS NULL OR lease_until < now())
+		RETURNING `+clusterJobColumns, models.ClusterJobRunning, models.ClusterJobQueued, maxAttempts, models.ClusterJobFailed)
+	if err != nil {
+		slog.ErrorContext(ctx, "Requeue cluster_job failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.ClusterJob{}
+	for rows.Next() {
+		job, err := scanClusterJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// CancelQueuedClusterJob เปลี่ยนงานเป็น canceled เฉพาะเมื่อยังอยู่ในคิว (false ถ้างานเริ่มไปแล้ว จบแล้ว หรือไม่มีงาน)
+func CancelQueuedClusterJob(ctx context.Context, id int) (models.ClusterJob, bool, error) {
+	job, err := scanClusterJob(connection.DB.QueryRow(ctx, `
+		UPDATE cluster_job SET status = $2, finished_at = now()
+		WHERE job_id = $1 AND status = $3
+		RETURNING `+clusterJobColumns, id, models.ClusterJobCanceled, models.ClusterJobQueued))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Cancel cluster_job failed", "err", err)
+		return job, false, err
+	}
+	return job, true, nil
+}
+
+func GetClusterJob(ctx context.Context, id int) (models.ClusterJob, error) {
+	job, err := scanClusterJob(connection.DB.QueryRow(ctx,
+		`SELECT `+clusterJobColumns+` FROM cluster_job WHERE job_id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, ErrClusterJobNotFound
+	}
+	return job, err
+}
+
+// ListClusterJobs คืนงานตามสถานะ (ถ้าระบุ) เรียงตามเวลาที่สร้าง
+func ListClusterJobs(ctx context.Context, query models.ClusterJobQuery) ([]models.ClusterJob, error) {
+	order := "DESC"
+	if query.OldestFirst {
+		order = "ASC"
+	}
+	rows, err := connection.DB.Query(ctx, fmt.Sprintf(`
+		SELECT `+clusterJobColumns+` FROM cluster_job
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at %[1]s, job_id %[1]s
+		LIMIT NULLIF($2, 0)`, order), query.Status, query.Limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.ClusterJob{}
+	for rows.Next() {
+		job, err := scanClusterJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func scanClusterJob(row pgx.Row) (models.ClusterJob, error) {
+	var job models.ClusterJob
+	err := row.Scan(&job.JobID, &job.Status, &job.Engine, &job.Params, &job.Stage, &job.Progress, &job.Result, &job.Error,
+		&job.Attempts, &job.CreatedAt, &job.StartedAt, &job.FinishedAt, &job.Owner, &job.LeaseUntil)
+	return job, err
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrEventNotFound  = errors.New("event not found")
+	ErrNothingToMerge = errors.New("no duplicate events given")
+)
+
+// MergeEvents รวม tags, media, relations และ entity links ของ duplicates เข้าไปใน survivor
+// แล้วลบ duplicates ทิ้งพร้อม cluster mappings ของมัน ทั้งหมดทำใน transaction เดียว
+func MergeEvents(ctx context.Context, req models.MergeRequest) (models.MergeResult, error) {
+	result := models.MergeResult{SurvivorID: req.SurvivorID}
+	req.DuplicateIDs = uniqueDuplicates(req.SurvivorID, req.DuplicateIDs)
+	if len(req.DuplicateIDs) == 0 {
+		return result, ErrNothingToMerge
+	}
+
+	tx, err := connection.DB.Begin(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback(ctx)
+
+	// ตรวจว่า event ทั้งหมดมีอยู่จริง
+	ids := append([]int{req.SurvivorID}, req.DuplicateIDs...)
+	var found int
+	if err := tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM event WHERE event_id = ANY($1)`, ids,
+	).Scan(&found); err != nil {
+		return result, err
+	}
+	if found != len(ids) {
+		return result, ErrEventNotFound
+	}
+
+	for _, dupID := range req.DuplicateIDs {
+		// 1. tags
+		ct, err := tx.Exec(ctx, `
+			INSERT INTO eventtag (event_id, tag_id)
+			SELECT $1, et.tag_id FROM eventtag et
+			WHERE et.event_id = $2
+			  AND NOT EXISTS (
+				SELECT 1 FROM eventtag x WHERE x.event_id = $1 AND x.tag_id = et.tag_id
+			  )
+		`, req.SurvivorID, dupID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Merge eventtag failed", "err", err)
+			return result, err
+		}
+		result.TagsAdded += ct.RowsAffected()
+
+		// 2. cluster mappings: ไม่คัดลอกไปให้ survivor เพราะ cluster ถูกคำนวณจากตำแหน่งและวันที่ของแต่ละ event
+		// survivor คงอยู่ใน cluster ของตัวเอง ส่วน mapping ของ duplicate ถูกลบทิ้ง (จนกว่าจะ clustering ใหม่)
+		ct, err = tx.Exec(ctx, `DELETE FROM eventclustermap WHERE event_id = $1`, dupID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Merge eventclustermap failed", "err", err)
+			return result, err
+		}
+		result.ClustersRemoved += ct.RowsAffected()
+
+		// 3. media และ description: เติมเฉพาะช่องที่ survivor ยังว่างอยู่
+		ct, err = tx.Exec(ctx, `
+			UPDATE event s SET
+				image       = COALESCE(NULLIF(s.image, ''), d.image),
+				video       = COALESCE(NULLIF(s.video, ''), d.video),
+				description = COALESCE(NULLIF(s.description, ''), d.description)
+			FROM event d
+			WHERE s.event_id = $1 AND d.event_id = $2
+			  AND (
+				(COALESCE(s.image, '') = '' AND COALESCE(d.image, '') <> '') OR
+				(COALESCE(s.video, '') = '' AND COALESCE(d.video, '') <> '') OR
+				(COALESCE(s.description, '') = '' AND COALESCE(d.description, '') <> '')
+			  )
+		`, req.SurvivorID, dupID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Merge media failed", "err", err)
+			return result, err
+		}
+		if ct.RowsAffected() > 0 {
+			result.MediaFilled = true
+		}
+
+		// 4. relations: ย้าย edge ของ duplicate มาที่ survivor (ข้าม edge ที่ survivor มีอยู่แล้ว)
+		for _, q := range []string{
+			`UPDATE event_relation r SET source_event_id = $1
+			 WHERE r.source_event_id = $2 AND NOT EXISTS (
+				SELECT 1 FROM event_relation x
+				WHERE x.source_event_id = $1 AND x.target_event_id = r.target_event_id AND x.relation_type = r.relation_type
+			 )`,
+			`UPDATE event_relation r SET target_event_id = $1
+			 WHERE r.target_event_id = $2 AND NOT EXISTS (
+				SELECT 1 FROM event_relation x
+				WHERE x.target_event_id = $1 AND x.source_event_id = r.source_event_id AND x.relation_type = r.relation_type
+			 )`,
+		} {
+			if _, err := tx.Exec(ctx, q, req.SurvivorID, dupID); err != nil {
+				slog.ErrorContext(ctx, "Merge event_relation failed", "err", err)
+				return result, err
+			}
+		}
+		// edge ที่เคยเชื่อม survivor กับ duplicate จะกลายเป็น self-loop
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM event_relation WHERE source_event_id = $1 AND target_event_id = $1`, req.SurvivorID,
+		); err != nil {
+			slog.ErrorContext(ctx, "Merge event_relation failed", "err", err)
+			return result, err
+		}
+
+		// 5. entity links
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO event_entity (event_id, entity_id, role)
+			SELECT $1, entity_id, role FROM event_entity WHERE event_id = $2
+			ON CONFLICT (event_id, entity_id, role) DO NOTHING
+		`, req.SurvivorID, dupID); err != nil {
+			slog.ErrorContext(ctx, "Merge event_entity failed", "err", err)
+			return result, err
+		}
+
+		// 6. tour steps ที่อ้างถึง duplicate
+		if _, err := tx.Exec(ctx,
+			`UPDATE tour_step SET event_id = $1 WHERE event_id = $2`, req.SurvivorID, dupID,
+		); err != nil {
+			slog.ErrorContext(ctx, "Merge tour_step failed", "err", err)
+			return result, err
+		}
+
+		// 7. ลบ duplicate
+		if err := deleteEventTx(ctx, tx, dupID); err != nil {
+			slog.ErrorContext(ctx, "Delete duplicate event failed", "event_id", dupID, "err", err)
+			return result, err
+		}
+		result.MergedIDs = append(result.MergedIDs, dupID)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// uniqueDuplicates ตัด id ที่ซ้ำและ survivor ออกจาก duplicate_ids (คงลำดับเดิม)
+// เพื่อให้จำนวน event ที่พบตรงกับจำนวน id ที่ตรวจ
+func uniqueDuplicates(survivorID int, ids []int) []int {
+	seen := map[int]bool{survivorID: true}
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// deleteEventTx ลบ event พร้อมแถวที่อ้างถึง event นั้น
+func deleteEventTx(ctx context.Context, tx pgx.Tx, eventID int) error {
+	for _, q := range []string{
+		`DELETE FROM eventtag WHERE event_id = $1`,
+		`DELETE FROM eventclustermap WHERE event_id = $1`,
+		`DELETE FROM event_relation WHERE source_event_id = $1 OR target_event_id = $1`,
+		`DELETE FROM event_entity WHERE event_id = $1`,
+		`DELETE FROM event_cell WHERE event_id = $1`,
+		`DELETE FROM event WHERE event_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, q, eventID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+	"globe/internal/quality"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrEntityNotFound = errors.New("entity not found")
+	ErrLinkNotFound   = errors.New("entity is not linked to event")
+)
+
+const entityColumns = `entity_id, name, entity_type, COALESCE(description, '')`
+
+func CreateEntity(ctx context.Context, ent models.Entity) (models.Entity, error) {
+	err := connection.DB.QueryRow(ctx, `
+		INSERT INTO entity (name, entity_type, description)
+		VALUES ($1, $2, $3)
+		RETURNING entity_id
+	`, ent.Name, ent.EntityType, ent.Description).Scan(&ent.EntityID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Insert entity failed", "err", err)
+		return ent, err
+	}
+	return ent, nil
+}
+
+func GetEntity(ctx context.Context, id int) (models.Entity, error) {
+	var ent models.Entity
+	err := connection.DB.QueryRow(ctx,
+		`SELECT `+entityColumns+` FROM entity WHERE entity_id = $1`, id,
+	).Scan(&ent.EntityID, &ent.Name, &ent.EntityType, &ent.Description)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ent, ErrEntityNotFound
+	}
+	return ent, err
+}
+
+// ListEntities คืน entity ทั้งหมด เรียงตามชื่อ (entityType ว่าง = ทุกชนิด)
+func ListEntities(ctx context.Context, entityType string) ([]models.Entity, error) {
+	query := `SELECT ` + entityColumns + ` FROM entity`
+	args := []interface{}{}
+	if entityType != "" {
+		query += ` WHERE entity_type = $1`
+		args = append(args, entityType)
+	}
+	query += ` ORDER BY name`
+
+	rows, err := connection.DB.Query(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entities := []models.Entity{}
+	for rows.Next() {
+		var ent models.Entity
+		if err := rows.Scan(&ent.EntityID, &ent.Name, &ent.EntityType, &ent.Description); err != nil {
+			return nil, err
+		}
+		entities = append(entities, ent)
+	}
+	return entities, rows.Err()
+}
+
+func UpdateEntity(ctx context.Context, ent models.Entity) (models.Entity, error) {
+	tag, err := connection.DB.Exec(ctx, `
+		UPDATE entity SET name = $2, entity_type = $3, description = $4
+		WHERE entity_id = $1
+	`, ent.EntityID, ent.Name, ent.EntityType, ent.Description)
+	if err != nil {
+		slog.ErrorContext(ctx, "Update entity failed", "err", err)
+		return ent, err
+	}
+	if tag.RowsAffected() == 0 {
+		return ent, ErrEntityNotFound
+	}
+	return ent, nil
+}
+
+// DeleteEntity ลบ entity พร้อม link ทั้งหมดของมัน
+func DeleteEntity(ctx context.Context, id int) error {
+	tx, err := connection.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM event_entity WHERE entity_id = $1`, id); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM entity WHERE entity_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEntityNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+// LinkEntityToEvent เชื่อม entity กับ event ด้วยบทบาท (ซ้ำได้โดยไม่ error)
+func LinkEntityToEvent(ctx context.Context, link models.EventEntityLink) error {
+	_, err := connection.DB.Exec(ctx, `
+		INSERT INTO event_entity (event_id, entity_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, entity_id, role) DO NOTHING
+	`, link.EventID, link.EntityID, link.Role)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			if pgErr.ConstraintName == "event_entity_entity_id_fkey" {
+				return ErrEntityNotFound
+			}
+			return ErrEventNotFound
+		}
+		slog.ErrorContext(ctx, "Insert event_entity failed", "err", err)
+		return err
+	}
+	return nil
+}
+
+// UnlinkEntityFromEvent ลบ link (role ว่าง = ทุกบทบาท)
+func UnlinkEntityFromEvent(ctx context.Context, link models.EventEntityLink) error {
+	query := `DELETE FROM event_entity WHERE event_id = $1 AND entity_id = $2`
+	args := []interface{}{link.EventID, link.EntityID}
+	if link.Role != "" {
+		query += ` AND role = $3`
+		args = append(args, link.Role)
+	}
+	tag, err := connection.DB.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// GetEntityPath คืน event ของ entity เรียงตามเวลา พร้อมเส้นทางที่ลากผ่านแต่ละ event
+func GetEntityPath(ctx context.Context, entityID int, roles []string) (models.EntityPath, error) {
+	path := models.EntityPath{Stops: []models.EntityPathStop{}}
+
+	ent, err := GetEntity(ctx, entityID)
+	if err != nil {
+		return path, err
+	}
+	path.Entity = ent
+
+	query := `SELECT event_id, ARRAY_AGG(role ORDER BY role) FROM event_entity WHERE entity_id = $1`
+	args := []interface{}{entityID}
+	if len(roles) > 0 {
+		query += ` AND role = ANY($2)`
+		args = append(args, roles)
+	}
+	query += ` GROUP BY event_id`
+
+	rows, err := connection.DB.Query(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return path, err
+	}
+	rolesByEvent := make(map[int][]string)
+	ids := make(map[int]struct{})
+	for rows.Next() {
+		var eventID int
+		var eventRoles []string
+		if err := rows.Scan(&eventID, &eventRoles); err != nil {
+			rows.Close()
+			return path, err
+		}
+		rolesByEvent[eventID] = eventRoles
+		ids[eventID] = struct{}{}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return path, err
+	}
+
+	details, err := loadEventDetails(ctx, ids)
+	if err != nil {
+		return path, err
+	}
+	for id, ev := range details {
+		path.Stops = append(path.Stops, models.EntityPathStop{Event: ev, Roles: rolesByEvent[id]})
+	}
+	sort.Slice(path.Stops, func(i, j int) bool {
+		a, b := path.Stops[i].Event, path.Stops[j].Event
+		if a.Date.Equal(b.Date.Time) {
+			return a.EventID < b.EventID
+		}
+		return a.Date.Before(b.Date.Time)
+	})
+
+	var coords [][2]float64
+	for _, stop := range path.Stops {
+		if quality.ValidCoordinates(stop.Event.Lat, stop.Event.Lon) {
+			coords = append(coords, [2]float64{stop.Event.Lon, stop.Event.Lat})
+		}
+	}
+	if len(coords) >= 2 {
+		path.Path = &models.Geometry{Type: models.GeometryLineString, Parts: [][][2]float64{coords}}
+	}
+	return path, nil
+}
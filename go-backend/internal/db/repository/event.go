@@ -3,23 +3,39 @@ package repository
 import (
 	"context"
 	"fmt"
-	"log"
-	"math"
+	"log/slog"
 	"strings"
 
 	"globe/internal/db/connection"
 	"globe/internal/db/models"
+	"globe/internal/geo"
 )
 
-func GetFilteredEvents(filter models.EventFilter) ([]models.EventResponse, error) {
+// eventSpanEndSQL คือวันสุดท้ายของช่วงเวลาของ event (ตรงกับ models.DateSpanEnd)
+const eventSpanEndSQL = `COALESCE(e.end_date, CASE e.date_precision
+		WHEN 'month'   THEN e.date + INTERVAL '1 month'   - INTERVAL '1 day'
+		WHEN 'season'  THEN e.date + INTERVAL '3 months'  - INTERVAL '1 day'
+		WHEN 'year'    THEN e.date + INTERVAL '1 year'    - INTERVAL '1 day'
+		WHEN 'decade'  THEN e.date + INTERVAL '10 years'  - INTERVAL '1 day'
+		WHEN 'century' THEN e.date + INTERVAL '100 years' - INTERVAL '1 day'
+		ELSE e.date
+	END)`
+
+// validCoordinatesSQL ตรงกับ quality.ValidCoordinates (NaN/Infinity ไม่ผ่าน BETWEEN)
+const validCoordinatesSQL = ` AND e.lat BETWEEN -90 AND 90 AND e.lon BETWEEN -180 AND 180`
+
+func GetFilteredEvents(ctx context.Context, filter models.EventFilter) ([]models.EventResponse, error) {
 	// 1. สร้าง base query
 	query := `
 		SELECT DISTINCT
 			e.event_id,
 			e.event_name,
 			e.date,
+			e.date_precision,
+			e.end_date,
 			e.lat,
 			e.lon,
+			e.geometry,
 			e.description,
 			COALESCE(ARRAY_AGG(DISTINCT t.tag_name) FILTER (WHERE t.tag_name IS NOT NULL), ARRAY[]::text[]) as tags,
 			COALESCE(ARRAY_AGG(DISTINCT ecm.cluster_id) FILTER (WHERE ecm.cluster_id IS NOT NULL), ARRAY[]::int[]) as clusters
@@ -30,7 +46,74 @@ func GetFilteredEvents(filter models.EventFilter) ([]models.EventResponse, error
 		WHERE 1=1
 	`
 
-	// 2. เพิ่มเงื่อนไข filter tags และ date
+	// 2. เพิ่มเงื่อนไข filter tags, date, viewport และ entity
+	conds, args := eventFilterSQL(filter)
+	query += conds
+
+	// 3. Group by และ order by
+	query += `
+		GROUP BY e.event_id, e.event_name, e.date, e.date_precision, e.end_date, e.lat, e.lon, e.geometry, e.description
+		ORDER BY e.date DESC
+	`
+
+	// Debug: Print query and args
+	slog.DebugContext(ctx, "Querying filtered events", "args", args)
+
+	// 4. Execute query
+	rows, err := connection.DB.Query(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.EventResponse
+	for rows.Next() {
+		var event models.EventResponse
+		err := rows.Scan(
+			&event.EventID,
+			&event.EventName,
+			&event.Date,
+			&event.DatePrecision,
+			&event.EndDate,
+			&event.Lat,
+			&event.Lon,
+			&event.Geometry,
+			&event.Description,
+			&event.Tags,
+			&event.Clusters,
+		)
+		if err != nil {
+			slog.ErrorContext(ctx, "Scanning row failed", "err", err)
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Rows error", "err", err)
+		return nil, err
+	}
+
+	// Debug: Print number of results
+	slog.DebugContext(ctx, "Found filtered events", "events", len(events))
+
+	// event ที่ lat/lon ใช้ไม่ได้ยังอยู่ในผลลัพธ์โดยมี lat/lon เป็น null (ดูรายละเอียดได้ที่ /api/quality/report)
+	// ส่วน clustering ตัดออกใน service.GetClusteringEvents
+	valid := events[:0]
+	for _, ev := range events {
+		if !postGIS.enabled && !filter.MatchesLocation(ev.Lat, ev.Lon, ev.Geometry) {
+			continue
+		}
+		valid = append(valid, ev)
+	}
+	return valid, nil
+}
+
+// eventFilterSQL สร้างเงื่อนไข WHERE (ขึ้นต้นด้วย " AND") ของ EventFilter สำหรับ event alias e
+// โดยใช้ placeholder ตั้งแต่ $1 ส่วน viewport กรองเฉพาะจุดใน SQL (geometry ต้องตรวจต่อใน Go)
+func eventFilterSQL(filter models.EventFilter) (string, []interface{}) {
+	query := ""
 	args := []interface{}{}
 	argCount := 1
 
@@ -58,80 +141,119 @@ func GetFilteredEvents(filter models.EventFilter) ([]models.EventResponse, error
 		}
 	}
 
-	// 2.2 เพิ่มเงื่อนไข filter date
-	if filter.DateFilter != nil {
-		if filter.DateFilter.Year != nil {
-			query += fmt.Sprintf(" AND EXTRACT(YEAR FROM e.date) = $%d", argCount)
-			args = append(args, *filter.DateFilter.Year)
-			argCount++
-		} else {
-			if filter.DateFilter.StartDate != nil {
-				query += fmt.Sprintf(" AND e.date >= $%d", argCount)
-				args = append(args, filter.DateFilter.StartDate)
-				argCount++
+	// 2.2 เพิ่มเงื่อนไข filter date (overlap: ช่วงของ event ทับกับช่วงของ filter)
+	start, end := filter.DateFilter.Bounds()
+	if end != nil {
+		query += fmt.Sprintf(" AND e.date <= $%d", argCount)
+		args = append(args, *end)
+		argCount++
+	}
+	if start != nil {
+		query += fmt.Sprintf(" AND %s >= $%d", eventSpanEndSQL, argCount)
+		args = append(args, *start)
+		argCount++
+	}
+
+	// 2.3 เพิ่มเงื่อนไขเชิงพื้นที่: โหมด PostGIS ตรวจ geog (รวม geometry) ใน SQL ได้ครบ
+	// ไม่อย่างนั้น SQL กรองเฉพาะจุดด้วยกรอบ ส่วน geometry และระยะจริงตรวจต่อใน Go (MatchesLocation)
+	var spatial []string
+	var spatialArgs []interface{}
+	if postGIS.enabled {
+		spatial, spatialArgs = postGISFilterSQL(filter, argCount)
+	} else {
+		var rects []geo.Rect
+		if v := filter.Viewport; v != nil {
+			rects = append(rects, geo.Rect{South: v.South, North: v.North, West: v.West, East: v.East})
+		}
+		if r := filter.Radius; r != nil {
+			rects = append(rects, geo.RadiusBounds(r.Lat, r.Lon, r.Km))
+		}
+		if a := filter.Area; a != nil {
+			rects = append(rects, geo.PathBounds(a.Coordinates()))
+		}
+		for _, r := range rects {
+			lonCond := "e.lon BETWEEN $%d AND $%d"
+			if r.West > r.East {
+				lonCond = "(e.lon >= $%d OR e.lon <= $%d)" // ข้าม antimeridian
 			}
-			if filter.DateFilter.EndDate != nil {
-				query += fmt.Sprintf(" AND e.date <= $%d", argCount)
-				args = append(args, filter.DateFilter.EndDate)
+			n := argCount + len(spatialArgs)
+			spatial = append(spatial, fmt.Sprintf("(e.geometry IS NOT NULL OR (e.lat BETWEEN $%d AND $%d AND "+lonCond+"))", n, n+1, n+2, n+3))
+			spatialArgs = append(spatialArgs, r.South, r.North, r.West, r.East)
+		}
+	}
+	for _, cond := range spatial {
+		query += " AND " + cond
+	}
+	args = append(args, spatialArgs...)
+	argCount += len(spatialArgs)
+
+	// 2.4 เพิ่มเงื่อนไข filter entity (บุคคล หน่วย องค์กร ประเทศ)
+	if ef := filter.EntityFilter; ef != nil && len(ef.EntityIDs) > 0 {
+		roleCond := ""
+		if len(ef.Roles) > 0 {
+			roleCond = fmt.Sprintf(" AND ee.role = ANY($%d)", argCount)
+			args = append(args, ef.Roles)
+			argCount++
+		}
+		if ef.Operator == "AND" {
+			for _, id := range ef.EntityIDs {
+				query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM event_entity ee WHERE ee.event_id = e.event_id AND ee.entity_id = $%d%s)", argCount, roleCond)
+				args = append(args, id)
 				argCount++
 			}
+		} else {
+			query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM event_entity ee WHERE ee.event_id = e.event_id AND ee.entity_id = ANY($%d)%s)", argCount, roleCond)
+			args = append(args, ef.EntityIDs)
+			argCount++
 		}
 	}
 
-	// 3. Group by และ order by
-	query += `
-		GROUP BY e.event_id, e.event_name, e.date, e.lat, e.lon, e.description
-		ORDER BY e.date DESC
-	`
-
-	// Debug: Print query and args
-	log.Printf("[DEBUG] Args: %v", args)
+	return query, args
+}
 
-	// 4. Execute query
-	rows, err := connection.DB.Query(context.Background(), query, args...)
-	if err != nil {
-		log.Printf("[ERROR] Query failed: %v", err)
-		return nil, err
-	}
-	defer rows.Close()
+// aggregateFilterSQL คือ eventFilterSQL สำหรับ query ที่นับ event ใน SQL
+// โดยตัด event ที่ผ่านกรอบใน SQL แต่ไม่ผ่าน MatchesLocation (ผลเดียวกับ GetFilteredEvents)
+func aggregateFilterSQL(ctx context.Context, filter models.EventFilter) (string, []interface{}, error) {
+	conds, args := eventFilterSQL(filter)
 
-	var events []models.EventResponse
-	for rows.Next() {
-		var event models.EventResponse
-		err := rows.Scan(
-			&event.EventID,
-			&event.EventName,
-			&event.Date,
-			&event.Lat,
-			&event.Lon,
-			&event.Description,
-			&event.Tags,
-			&event.Clusters,
-		)
+	if filter.HasLocation() && !postGIS.enabled {
+		excluded, err := locationExcludedEventIDs(ctx, filter, conds, args)
 		if err != nil {
-			log.Printf("[ERROR] Scanning row failed: %v", err)
-			continue
+			return "", nil, err
+		}
+		if len(excluded) > 0 {
+			conds += fmt.Sprintf(" AND e.event_id <> ALL($%d)", len(args)+1)
+			args = append(args, excluded)
 		}
-		events = append(events, event)
 	}
+	return conds, args, nil
+}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("[ERROR] Rows error: %v", err)
+// locationExcludedEventIDs คืน event ที่ผ่าน filter ใน SQL แต่ไม่ผ่าน MatchesLocation
+// ถ้ามีแค่ viewport จุดถูกกรองใน SQL ครบแล้ว จึงตรวจเฉพาะ event ที่มี geometry
+func locationExcludedEventIDs(ctx context.Context, filter models.EventFilter, conds string, args []interface{}) ([]int, error) {
+	query := `SELECT e.event_id, e.lat, e.lon, e.geometry FROM event e WHERE 1=1` + conds
+	if filter.Radius == nil && filter.Area == nil {
+		query += ` AND e.geometry IS NOT NULL`
+	}
+	rows, err := connection.DB.Query(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	// Debug: Print number of results
-	log.Printf("[DEBUG] Found %d events", len(events))
-
-	// แก้ไขค่า NaN เป็น 0
-	for i := range events {
-		if math.IsNaN(events[i].Lat) {
-			events[i].Lat = 0
+	excluded := []int{}
+	for rows.Next() {
+		var id int
+		var lat, lon float64
+		var g *models.Geometry
+		if err := rows.Scan(&id, &lat, &lon, &g); err != nil {
+			return nil, err
 		}
-		if math.IsNaN(events[i].Lon) {
-			events[i].Lon = 0
+		if !filter.MatchesLocation(lat, lon, g) {
+			excluded = append(excluded, id)
 		}
 	}
-
-	return events, nil
+	return excluded, rows.Err()
 }
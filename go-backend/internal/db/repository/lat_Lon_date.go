@@ -2,18 +2,18 @@ package repository
 
 import (
 	"context"
-	"log"
+	"log/slog"
 
 	"globe/internal/db/connection"
 	"globe/internal/db/models"
 )
 
-func GetEventLatLonDate() ([]models.EventLatLonDate, error) {
-	log.Println("[DEBUG] Start querying event lat, lon, date from database")
-	rows, err := connection.DB.Query(context.Background(),
-		`SELECT event_id, lat, lon, date FROM event`)
+func GetEventLatLonDate(ctx context.Context) ([]models.EventLatLonDate, error) {
+	slog.DebugContext(ctx, "Querying event lat, lon, date")
+	rows, err := connection.DB.Query(ctx,
+		`SELECT event_id, lat, lon, date, date_precision, end_date FROM event`)
 	if err != nil {
-		log.Printf("[ERROR] Query failed: %v", err)
+		slog.ErrorContext(ctx, "Query failed", "err", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -21,20 +21,26 @@ func GetEventLatLonDate() ([]models.EventLatLonDate, error) {
 	var events []models.EventLatLonDate
 
 	for rows.Next() {
-		var event models.EventLatLonDate
-		err := rows.Scan(&event.EventID, &event.Lat, &event.Lon, &event.Date)
+		var eventID int
+		var lat, lon float64
+		var date models.Date
+		var precision string
+		var endDate *models.Date
+		err := rows.Scan(&eventID, &lat, &lon, &date, &precision, &endDate)
 		if err != nil {
-			log.Printf("[ERROR] Scanning row failed: %v", err)
+			slog.ErrorContext(ctx, "Scanning row failed", "err", err)
 			continue
 		}
+		// event ที่เป็นช่วงเวลาจะส่งจุดกึ่งกลางไป clustering พร้อมค่าความไม่แน่นอน
+		event := models.NewEventLatLonDate(eventID, lat, lon, date, precision, endDate)
 		events = append(events, event)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("[ERROR] Rows error: %v", err)
+		slog.ErrorContext(ctx, "Rows error", "err", err)
 		return nil, err
 	}
 
-	log.Printf("[DEBUG] Total events fetched: %d", len(events))
+	slog.DebugContext(ctx, "Fetched event lat, lon, date", "events", len(events))
 	return events, nil
 }
\ No newline at end of file
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+	"globe/internal/geo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// nearbyStartPrecision คือความยาว geohash ที่เริ่มค้นหา (≈ 5 กม.) ถ้าได้ไม่ครบ K จะลดความยาวลงทีละหนึ่ง
+const nearbyStartPrecision = 5
+
+type nearbyCandidate struct {
+	eventID  int
+	km       float64
+	days     float64
+	distance float64
+}
+
+// ReindexGeohashes คำนวณ geohash ของทุก event ใหม่ด้วย geohash_encode (migration 0012)
+// ปกติ trigger event_geohash ตั้งค่าให้ตอน insert และเมื่อ lat/lon เปลี่ยน จึงใช้แค่ซ่อมข้อมูล
+// event ที่ lat/lon ใช้ไม่ได้จะมี geohash เป็น NULL และไม่ถูกค้นใน nearby
+func ReindexGeohashes(ctx context.Context) (int64, error) {
+	tag, err := connection.DB.Exec(ctx, `
+		UPDATE event SET geohash = geohash_encode(lat, lon, $1)
+		WHERE geohash IS DISTINCT FROM geohash_encode(lat, lon, $1)
+	`, geo.GeohashPrecision)
+	if err != nil {
+		slog.ErrorContext(ctx, "Update geohash failed", "err", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetNearbyEvents คืน K event ที่ใกล้จุดอ้างอิงที่สุดตาม metric
+// spatial/weighted ค้นจาก geohash ของ cell รอบจุด แล้วขยาย cell จนแน่ใจว่าไม่มี event ที่ใกล้กว่าอยู่นอก cell
+// temporal ค้นจาก index ของ e.date ทั้งสองทิศ
+func GetNearbyEvents(ctx context.Context, q models.NearbyQuery) ([]models.NearbyEvent, error) {
+	var refDay float64
+	if q.Date != nil {
+		refDay = q.Date.DayNumber()
+	}
+	score := func(c *nearbyCandidate, lat, lon float64, date models.Date) {
+		c.km = geo.HaversineKm(q.Lat, q.Lon, lat, lon)
+		c.days = math.Abs(date.DayNumber() - refDay)
+		switch q.Metric {
+		case models.NearbyMetricTemporal:
+			c.distance = c.days
+		case models.NearbyMetricWeighted:
+			c.distance = math.Sqrt(q.SpatialWeight*c.km*c.km + q.TemporalWeight*c.days*c.days)
+		default:
+			c.distance = c.km
+		}
+	}
+
+	var candidates []nearbyCandidate
+	var err error
+	if q.Metric == models.NearbyMetricTemporal {
+		candidates, err = temporalCandidates(ctx, q, score)
+	} else {
+		candidates, err = spatialCandidates(ctx, q, score)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) > q.K {
+		candidates = candidates[:q.K]
+	}
+
+	ids := make(map[int]struct{}, len(candidates))
+	for _, c := range candidates {
+		ids[c.eventID] = struct{}{}
+	}
+	details, err := loadEventDetails(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.NearbyEvent, 0, len(candidates))
+	for _, c := range candidates {
+		ev, ok := details[c.eventID]
+		if !ok {
+			continue
+		}
+		nearby := models.NearbyEvent{Event: ev, DistanceKm: c.km, Distance: c.distance}
+		if q.Date != nil {
+			days := c.days
+			nearby.DistanceDays = &days
+		}
+		result = append(result, nearby)
+	}
+	return result, nil
+}
+
+// GetNearbyEventsOf หา event ใกล้เคียงของ event ที่มีอยู่ (ใช้ตำแหน่งและวันที่ของ event นั้นเป็นจุดอ้างอิง)
+func GetNearbyEventsOf(ctx context.Context, eventID int, q models.NearbyQuery) ([]models.NearbyEvent, error) {
+	var date models.Date
+	err := connection.DB.QueryRow(ctx,
+		`SELECT lat, lon, date FROM event WHERE event_id = $1`, eventID,
+	).Scan(&q.Lat, &q.Lon, &date)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	q.Date = &date
+	q.ExcludeID = eventID
+	return GetNearbyEvents(ctx, q)
+}
+
+func spatialCandidates(ctx context.Context, q models.NearbyQuery, score func(*nearbyCandidate, float64, float64, models.Date)) ([]nearbyCandidate, error) {
+	for precision := nearbyStartPrecision; ; precision-- {
+		var prefixes []string
+		if precision > 0 {
+			prefixes = geo.GeohashNeighbors(q.Lat, q.Lon, precision)
+		}
+
+		// precision 0 = ไม่จำกัด cell (ค้นทั้งตาราง เกิดเมื่อข้อมูลมีน้อยกว่า K ในรัศมีหลายพันกิโลเมตร)
+		query := `SELECT event_id, lat, lon, date FROM event e WHERE e.geohash IS NOT NULL AND e.event_id <> $1`
+		args := []interface{}{q.ExcludeID}
+		if len(prefixes) > 0 {
+			conds := make([]string, len(prefixes))
+			for i, p := range prefixes {
+				conds[i] = fmt.Sprintf("e.geohash LIKE $%d", len(args)+1)
+				args = append(args, p+"%")
+			}
+			query += " AND (" + strings.Join(conds, " OR ") + ")"
+		}
+
+		candidates, err := scanNearbyCandidates(ctx, query, args, score)
+		if err != nil {
+			return nil, err
+		}
+		if precision == 0 {
+			return candidates, nil
+		}
+		if len(candidates) >= q.K {
+			// event นอก cell อยู่ห่างเกิน coverage กม. จึงมี distance อย่างน้อย coverage (spatial)
+			// หรือ sqrt(ws)*coverage (weighted) ถ้า event ลำดับที่ K ใกล้กว่านั้นก็หยุดได้
+			bound := candidates[q.K-1].distance
+			if q.Metric == models.NearbyMetricWeighted {
+				bound /= math.Sqrt(q.SpatialWeight)
+			}
+			if bound <= geo.GeohashCoverageKm(q.Lat, precision) {
+				return candidates, nil
+			}
+		}
+	}
+}
+
+func temporalCandidates(ctx context.Context, q models.NearbyQuery, score func(*nearbyCandidate, float64, float64, models.Date)) ([]nearbyCandidate, error) {
+	query := `
+		(SELECT event_id, lat, lon, date FROM event e
+		 WHERE e.geohash IS NOT NULL AND e.event_id <> $1 AND e.date >= $2
+		 ORDER BY e.date LIMIT $3)
+		UNION ALL
+		(SELECT event_id, lat, lon, date FROM event e
+		 WHERE e.geohash IS NOT NULL AND e.event_id <> $1 AND e.date < $2
+		 ORDER BY e.date DESC LIMIT $3)
+	`
+	return scanNearbyCandidates(ctx, query, []interface{}{q.ExcludeID, *q.Date, q.K}, score)
+}
+
+// scanNearbyCandidates รัน query แล้วคืน candidate เรียงตาม distance (เท่ากันเรียงตาม event_id)
+func scanNearbyCandidates(ctx context.Context, query string, args []interface{}, score func(*nearbyCandidate, float64, float64, models.Date)) ([]nearbyCandidate, error) {
+	rows, err := connection.DB.Query(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []nearbyCandidate
+	for rows.Next() {
+		var c nearbyCandidate
+		var lat, lon float64
+		var date models.Date
+		if err := rows.Scan(&c.eventID, &lat, &lon, &date); err != nil {
+			return nil, err
+		}
+		score(&c, lat, lon, date)
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance == candidates[j].distance {
+			return candidates[i].eventID < candidates[j].eventID
+		}
+		return candidates[i].distance < candidates[j].distance
+	})
+	return candidates, nil
+}
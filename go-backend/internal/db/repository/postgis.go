@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+	"globe/internal/geo"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrPostGISDisabled คือ error เมื่อเรียกฟังก์ชันที่ต้องใช้โหมด PostGIS ตอนที่ไม่ได้เปิด
+var ErrPostGISDisabled = errors.New("PostGIS mode is not enabled")
+
+// postGIS คือสถานะของโหมด PostGIS (ตั้งครั้งเดียวตอน start ด้วย EnablePostGIS)
+var postGIS struct {
+	enabled bool
+	hull    models.HullOptions
+}
+
+// EnablePostGIS เปิดโหมด PostGIS: filter เชิงพื้นที่ใช้ event.geog และ cluster มี hull
+// คอลัมน์ต้องมีอยู่แล้ว (go run . migrate postgis) hull คือรูปแบบที่ใช้ตอน insert cluster
+func EnablePostGIS(ctx context.Context, hull models.HullOptions) error {
+	var n int
+	err := connection.DB.QueryRow(ctx, `
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		  AND (table_name, column_name) IN (('event', 'geog'), ('cluster', 'hull'))
+	`).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n != 2 {
+		return errors.New("PostGIS columns are missing, run `go run . migrate postgis`")
+	}
+	postGIS.enabled = true
+	postGIS.hull = hull
+	return nil
+}
+
+// PostGISEnabled บอกว่าเปิดโหมด PostGIS อยู่หรือไม่
+func PostGISEnabled() bool {
+	return postGIS.enabled
+}
+
+// postGISFilterSQL สร้างเงื่อนไข viewport/radius/area บน e.geog โดยใช้ placeholder ตั้งแต่ $argCount
+// ขอบของ viewport แบ่งเป็นช่วงละ 1 องศา เพื่อให้ขอบบนล่างตามเส้นละติจูดแทนเส้น great circle
+func postGISFilterSQL(filter models.EventFilter, argCount int) ([]string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	next := func(v interface{}) int {
+		args = append(args, v)
+		return argCount + len(args) - 1
+	}
+
+	if v := filter.Viewport; v != nil {
+		var envelopes []string
+		for _, r := range (geo.Rect{South: v.South, North: v.North, West: v.West, East: v.East}).Split() {
+			envelopes = append(envelopes, fmt.Sprintf(
+				"ST_Intersects(e.geog, ST_Segmentize(ST_MakeEnvelope($%d, $%d, $%d, $%d, 4326), 1)::geography)",
+				next(r.West), next(r.South), next(r.East), next(r.North)))
+		}
+		cond := envelopes[0]
+		if len(envelopes) > 1 {
+			cond = "(" + envelopes[0] + " OR " + envelopes[1] + ")"
+		}
+		conds = append(conds, cond)
+	}
+	if r := filter.Radius; r != nil {
+		conds = append(conds, fmt.Sprintf(
+			"ST_DWithin(e.geog, ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography, $%d)",
+			next(r.Lon), next(r.Lat), next(r.Km*1000)))
+	}
+	if a := filter.Area; a != nil {
+		area, _ := json.Marshal(a)
+		conds = append(conds, fmt.Sprintf(
+			"ST_Intersects(e.geog, ST_SetSRID(ST_GeomFromGeoJSON($%d), 4326)::geography)", next(string(area))))
+	}
+	return conds, args
+}
+
+// RefreshClusterHulls คำนวณ hull ของทุก cluster จาก geog ของ event ใน subtree
+// concave ใช้ ST_ConcaveHull ถ้าผลไม่ใช่รูปเดียว (เช่น MultiPolygon) จะใช้ convex hull ของผลแทน
+func RefreshClusterHulls(ctx context.Context, opts models.HullOptions) (int, error) {
+	if !postGIS.enabled {
+		return 0, ErrPostGISDisabled
+	}
+	return refreshClusterHulls(ctx, connection.DB, opts)
+}
+
+// execer คือ connection.DB หรือ pgx.Tx
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// refreshClusterHulls คือ RefreshClusterHulls ที่รันผ่าน db (ใช้ใน transaction ของ InsertClustersAndMappings)
+func refreshClusterHulls(ctx context.Context, db execer, opts models.HullOptions) (int, error) {
+	hull := "ST_ConvexHull(ST_Collect(e.geog::geometry))"
+	var args []interface{}
+	if opts.Mode == models.HullConcave {
+		ratio := opts.Ratio
+		if ratio == 0 {
+			ratio = models.DefaultConcaveHullRatio
+		}
+		hull = "ST_ConcaveHull(ST_Collect(e.geog::geometry), $1)"
+		args = append(args, ratio)
+	}
+
+	tag, err := db.Exec(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT cluster_id AS root, cluster_id FROM cluster
+			UNION ALL
+			SELECT s.root, c.cluster_id FROM cluster c JOIN subtree s ON c.parent_cluster_id = s.cluster_id
+		),
+		hulls AS (
+			SELECT s.root AS cluster_id, `+hull+` AS hull
+			FROM subtree s
+			JOIN eventclustermap ecm ON ecm.cluster_id = s.cluster_id
+			JOIN event e ON e.event_id = ecm.event_id
+			WHERE e.geog IS NOT NULL
+			GROUP BY s.root
+		)
+		UPDATE cluster c SET hull = (CASE
+			WHEN GeometryType(h.hull) IN ('POINT', 'LINESTRING', 'POLYGON') THEN h.hull
+			ELSE ST_ConvexHull(h.hull)
+		END)::geography
+		FROM hulls h
+		WHERE c.cluster_id = h.cluster_id
+	`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Refresh cluster hulls failed", "err", err)
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
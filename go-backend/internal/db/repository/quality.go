@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+)
+
+// GetEventRecords ดึง event ทั้งหมดแบบ nullable เพื่อให้ตรวจคุณภาพได้ครบทุกแถว
+// (รวมแถวที่ lat/lon/date เป็น NULL ซึ่ง query อื่นจะ scan ไม่ผ่าน)
+func GetEventRecords(ctx context.Context) ([]models.EventRecord, error) {
+	rows, err := connection.DB.Query(ctx,
+		`SELECT event_id, event_name, date, date_precision, end_date, lat, lon, image, video
+		 FROM event ORDER BY event_id`)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []models.EventRecord
+	for rows.Next() {
+		var r models.EventRecord
+		if err := rows.Scan(&r.EventID, &r.EventName, &r.Date, &r.Precision, &r.EndDate, &r.Lat, &r.Lon, &r.Image, &r.Video); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Rows error", "err", err)
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrRelationNotFound = errors.New("relation not found")
+	ErrRelationExists   = errors.New("relation already exists")
+)
+
+// relationWriteError แปลง error ของ Postgres ให้เป็น error ของ repository
+func relationWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503": // foreign_key_violation
+			return ErrEventNotFound
+		case "23505": // unique_violation
+			return ErrRelationExists
+		}
+	}
+	return err
+}
+
+const relationColumns = `relation_id, source_event_id, target_event_id, relation_type, COALESCE(note, '')`
+
+// CreateRelation เพิ่ม edge ใหม่ คืน relation ที่มี relation_id แล้ว
+func CreateRelation(ctx context.Context, rel models.EventRelation) (models.EventRelation, error) {
+	err := connection.DB.QueryRow(ctx, `
+		INSERT INTO event_relation (source_event_id, target_event_id, relation_type, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING relation_id
+	`, rel.SourceEventID, rel.TargetEventID, rel.RelationType, rel.Note).Scan(&rel.RelationID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Insert event_relation failed", "err", err)
+		return rel, relationWriteError(err)
+	}
+	return rel, nil
+}
+
+func GetRelation(ctx context.Context, id int) (models.EventRelation, error) {
+	var rel models.EventRelation
+	err := connection.DB.QueryRow(ctx,
+		`SELECT `+relationColumns+` FROM event_relation WHERE relation_id = $1`, id,
+	).Scan(&rel.RelationID, &rel.SourceEventID, &rel.TargetEventID, &rel.RelationType, &rel.Note)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rel, ErrRelationNotFound
+	}
+	return rel, err
+}
+
+func UpdateRelation(ctx context.Context, rel models.EventRelation) (models.EventRelation, error) {
+	tag, err := connection.DB.Exec(ctx, `
+		UPDATE event_relation
+		SET source_event_id = $2, target_event_id = $3, relation_type = $4, note = $5
+		WHERE relation_id = $1
+	`, rel.RelationID, rel.SourceEventID, rel.TargetEventID, rel.RelationType, rel.Note)
+	if err != nil {
+		slog.ErrorContext(ctx, "Update event_relation failed", "err", err)
+		return rel, relationWriteError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return rel, ErrRelationNotFound
+	}
+	return rel, nil
+}
+
+func DeleteRelation(ctx context.Context, id int) error {
+	tag, err := connection.DB.Exec(ctx,
+		`DELETE FROM event_relation WHERE relation_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRelationNotFound
+	}
+	return nil
+}
+
+// GetRelationsForEvents คืนทุก edge ที่มีปลายด้านใดด้านหนึ่งอยู่ใน eventIDs
+// relationTypes ว่าง = ทุกชนิด
+func GetRelationsForEvents(ctx context.Context, eventIDs []int, relationTypes []string) ([]models.EventRelation, error) {
+	query := `SELECT ` + relationColumns + ` FROM event_relation
+		WHERE (source_event_id = ANY($1) OR target_event_id = ANY($1))`
+	args := []interface{}{eventIDs}
+	if len(relationTypes) > 0 {
+		query += ` AND relation_type = ANY($2)`
+		args = append(args, relationTypes)
+	}
+	query += ` ORDER BY relation_id`
+
+	rows, err := connection.DB.Query(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	relations := []models.EventRelation{}
+	for rows.Next() {
+		var rel models.EventRelation
+		if err := rows.Scan(&rel.RelationID, &rel.SourceEventID, &rel.TargetEventID, &rel.RelationType, &rel.Note); err != nil {
+			return nil, err
+		}
+		relations = append(relations, rel)
+	}
+	return relations, rows.Err()
+}
+
+// GetEventGraph ไล่ความสัมพันธ์แบบ BFS จาก rootID ไม่เกิน depth ขั้น (ไม่สนทิศทางของ edge)
+func GetEventGraph(ctx context.Context, rootID, depth int, relationTypes []string) (models.EventGraph, error) {
+	graph := models.EventGraph{RootEventID: rootID, Depth: depth}
+
+	root, err := loadEventDetails(ctx, map[int]struct{}{rootID: {}})
+	if err != nil {
+		return graph, err
+	}
+	if _, ok := root[rootID]; !ok {
+		return graph, ErrEventNotFound
+	}
+
+	visited := map[int]struct{}{rootID: {}}
+	edgeSeen := map[int]struct{}{}
+	edges := []models.EventRelation{}
+	frontier := []int{rootID}
+
+	for level := 0; level < depth && len(frontier) > 0; level++ {
+		relations, err := GetRelationsForEvents(ctx, frontier, relationTypes)
+		if err != nil {
+			return graph, err
+		}
+		next := []int{}
+		for _, rel := range relations {
+			if _, seen := edgeSeen[rel.RelationID]; !seen {
+				edgeSeen[rel.RelationID] = struct{}{}
+				edges = append(edges, rel)
+			}
+			for _, id := range []int{rel.SourceEventID, rel.TargetEventID} {
+				if _, ok := visited[id]; !ok {
+					visited[id] = struct{}{}
+					next = append(next, id)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	details, err := loadEventDetails(ctx, visited)
+	if err != nil {
+		return graph, err
+	}
+	graph.Nodes = make([]models.EventResponse, 0, len(details))
+	for _, ev := range details {
+		graph.Nodes = append(graph.Nodes, ev)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].Date.Before(graph.Nodes[j].Date.Time)
+	})
+	graph.Edges = edges
+	return graph, nil
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+)
+
+// Ping ตรวจว่ายังเชื่อมต่อฐานข้อมูลได้
+func Ping(ctx context.Context) error {
+	return connection.DB.Ping(ctx)
+}
+
+// GetDataCounts นับ event, event ที่อยู่ใน cluster และ cluster
+func GetDataCounts(ctx context.Context) (models.DataCounts, error) {
+	var counts models.DataCounts
+	err := connection.DB.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM event),
+			(SELECT COUNT(DISTINCT event_id) FROM eventclustermap),
+			(SELECT COUNT(*) FROM cluster)
+	`).Scan(&counts.Events, &counts.ClusteredEvents, &counts.Clusters)
+	return counts, err
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+)
+
+var ErrTooManyBuckets = fmt.Errorf("histogram exceeds %d buckets, use a larger bucket or a narrower date range", models.MaxHistogramBuckets)
+
+// GetTimelineHistogram นับ event ต่อช่วงเวลา (date_trunc) พร้อมแยกตาม tag
+// bucket ที่ไม่มี event จะถูกเติมด้วย count = 0
+func GetTimelineHistogram(ctx context.Context, q models.HistogramQuery) (models.TimelineHistogram, error) {
+	hist := models.TimelineHistogram{Bucket: q.Bucket, Buckets: []models.HistogramBucket{}}
+
+	conds, args, err := aggregateFilterSQL(ctx, q.EventFilter)
+	if err != nil {
+		return hist, err
+	}
+
+	// ช่วงของ histogram: ใช้ช่วงของ date filter ถ้ามี ไม่อย่างนั้นใช้ช่วงของข้อมูล
+	start, end := q.DateFilter.Bounds()
+	if start == nil || end == nil {
+		var minDate, maxDate *models.Date
+		err := connection.DB.QueryRow(ctx,
+			`SELECT MIN(e.date), MAX(e.date) FROM event e WHERE 1=1`+conds, args...,
+		).Scan(&minDate, &maxDate)
+		if err != nil {
+			slog.ErrorContext(ctx, "Query failed", "err", err)
+			return hist, err
+		}
+		if minDate == nil {
+			if hist.Bucket == "" || hist.Bucket == models.HistogramBucketAuto {
+				hist.Bucket = models.HistogramBucketYear
+			}
+			return hist, nil
+		}
+		if start == nil {
+			start = &minDate.Time
+		}
+		if end == nil {
+			end = &maxDate.Time
+		}
+	}
+	if end.Before(*start) {
+		return hist, nil
+	}
+
+	if hist.Bucket == "" || hist.Bucket == models.HistogramBucketAuto {
+		hist.Bucket = models.AutoHistogramBucket(*start, *end)
+	}
+	if models.HistogramBucketCount(hist.Bucket, *start, *end) > models.MaxHistogramBuckets {
+		return hist, ErrTooManyBuckets
+	}
+	hist.Start = &models.Date{Time: *start}
+	hist.End = &models.Date{Time: *end}
+
+	// event ที่เริ่มก่อน start (แต่ช่วงทับกัน) จะถูกนับใน bucket แรก
+	startArg, endArg := len(args)+1, len(args)+2
+	args = append(args, *start, *end)
+	query := fmt.Sprintf(`
+		WITH filtered AS (
+			SELECT e.event_id, date_trunc('%[1]s', GREATEST(e.date::timestamp, $%[2]d::timestamp)) AS bucket
+			FROM event e
+			WHERE 1=1 %[4]s
+		),
+		series AS (
+			SELECT generate_series(date_trunc('%[1]s', $%[2]d::timestamp), $%[3]d::timestamp, INTERVAL '1 %[1]s') AS bucket
+		),
+		counts AS (
+			SELECT bucket, COUNT(*) AS n FROM filtered GROUP BY bucket
+		),
+		tag_counts AS (
+			SELECT bucket, jsonb_object_agg(tag_name, n) AS tags
+			FROM (
+				SELECT f.bucket, t.tag_name, COUNT(*) AS n
+				FROM filtered f
+				JOIN eventtag et ON et.event_id = f.event_id
+				JOIN tag t ON t.tag_id = et.tag_id
+				GROUP BY f.bucket, t.tag_name
+			) bt
+			GROUP BY bucket
+		)
+		SELECT s.bucket, COALESCE(c.n, 0), COALESCE(tc.tags, '{}'::jsonb)
+		FROM series s
+		LEFT JOIN counts c ON c.bucket = s.bucket
+		LEFT JOIN tag_counts tc ON tc.bucket = s.bucket
+		ORDER BY s.bucket
+	`, hist.Bucket, startArg, endArg, conds)
+
+	rows, err := connection.DB.Query(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return hist, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.HistogramBucket
+		if err := rows.Scan(&b.Start, &b.Count, &b.Tags); err != nil {
+			return hist, err
+		}
+		hist.Total += b.Count
+		hist.Buckets = append(hist.Buckets, b)
+	}
+	return hist, rows.Err()
+}
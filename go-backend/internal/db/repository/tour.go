@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrTourNotFound      = errors.New("tour not found")
+	ErrTourEventNotFound = errors.New("tour step event not found")
+)
+
+// CreateTour บันทึก tour พร้อม steps ทั้งหมดใน transaction เดียว
+func CreateTour(ctx context.Context, tour models.Tour) (models.Tour, error) {
+	tx, err := connection.DB.Begin(ctx)
+	if err != nil {
+		return tour, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO tour (title, description) VALUES ($1, $2) RETURNING tour_id
+	`, tour.Title, tour.Description).Scan(&tour.TourID); err != nil {
+		slog.ErrorContext(ctx, "Insert tour failed", "err", err)
+		return tour, err
+	}
+	if err := insertTourSteps(ctx, tx, &tour); err != nil {
+		return tour, err
+	}
+	return tour, tx.Commit(ctx)
+}
+
+// UpdateTour แก้ title/description และแทนที่ steps ทั้งหมด
+func UpdateTour(ctx context.Context, tour models.Tour) (models.Tour, error) {
+	tx, err := connection.DB.Begin(ctx)
+	if err != nil {
+		return tour, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE tour SET title = $2, description = $3 WHERE tour_id = $1`,
+		tour.TourID, tour.Title, tour.Description)
+	if err != nil {
+		slog.ErrorContext(ctx, "Update tour failed", "err", err)
+		return tour, err
+	}
+	if tag.RowsAffected() == 0 {
+		return tour, ErrTourNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM tour_step WHERE tour_id = $1`, tour.TourID); err != nil {
+		return tour, err
+	}
+	if err := insertTourSteps(ctx, tx, &tour); err != nil {
+		return tour, err
+	}
+	return tour, tx.Commit(ctx)
+}
+
+func insertTourSteps(ctx context.Context, tx pgx.Tx, tour *models.Tour) error {
+	for i := range tour.Steps {
+		step := &tour.Steps[i]
+		step.Position = i
+		err := tx.QueryRow(ctx, `
+			INSERT INTO tour_step (
+				tour_id, position, event_id, cluster_id,
+				camera_lat, camera_lon, camera_altitude, camera_heading, camera_tilt,
+				window_start, window_end, narration, duration_seconds
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING step_id
+		`,
+			tour.TourID, step.Position, step.EventID, step.ClusterID,
+			step.Camera.Lat, step.Camera.Lon, step.Camera.Altitude, step.Camera.Heading, step.Camera.Tilt,
+			step.WindowStart, step.WindowEnd, step.Narration, step.DurationSeconds,
+		).Scan(&step.StepID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation ของ event_id
+				return fmt.Errorf("%w: step %d event_id %d", ErrTourEventNotFound, i, *step.EventID)
+			}
+			slog.ErrorContext(ctx, "Insert tour_step failed", "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func GetTour(ctx context.Context, id int) (models.Tour, error) {
+	var tour models.Tour
+	err := connection.DB.QueryRow(ctx,
+		`SELECT tour_id, title, COALESCE(description, '') FROM tour WHERE tour_id = $1`, id,
+	).Scan(&tour.TourID, &tour.Title, &tour.Description)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tour, ErrTourNotFound
+	}
+	if err != nil {
+		return tour, err
+	}
+
+	rows, err := connection.DB.Query(ctx, `
+		SELECT step_id, position, event_id, cluster_id,
+			camera_lat, camera_lon, camera_altitude, camera_heading, camera_tilt,
+			window_start, window_end, COALESCE(narration, ''), duration_seconds
+		FROM tour_step WHERE tour_id = $1 ORDER BY position
+	`, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return tour, err
+	}
+	defer rows.Close()
+
+	tour.Steps = []models.TourStep{}
+	for rows.Next() {
+		var s models.TourStep
+		if err := rows.Scan(
+			&s.StepID, &s.Position, &s.EventID, &s.ClusterID,
+			&s.Camera.Lat, &s.Camera.Lon, &s.Camera.Altitude, &s.Camera.Heading, &s.Camera.Tilt,
+			&s.WindowStart, &s.WindowEnd, &s.Narration, &s.DurationSeconds,
+		); err != nil {
+			return tour, err
+		}
+		tour.Steps = append(tour.Steps, s)
+	}
+	return tour, rows.Err()
+}
+
+// ListTours คืนรายการ tour (ไม่รวม steps)
+func ListTours(ctx context.Context) ([]models.Tour, error) {
+	rows, err := connection.DB.Query(ctx,
+		`SELECT tour_id, title, COALESCE(description, '') FROM tour ORDER BY tour_id`)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tours := []models.Tour{}
+	for rows.Next() {
+		var t models.Tour
+		if err := rows.Scan(&t.TourID, &t.Title, &t.Description); err != nil {
+			return nil, err
+		}
+		tours = append(tours, t)
+	}
+	return tours, rows.Err()
+}
+
+func DeleteTour(ctx context.Context, id int) error {
+	tx, err := connection.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM tour_step WHERE tour_id = $1`, id); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM tour WHERE tour_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTourNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+// GetTourPlayback โหลด tour แล้วแปลงแต่ละ step เป็นข้อมูล EventResponse ปัจจุบัน
+func GetTourPlayback(ctx context.Context, id int) (models.TourPlayback, error) {
+	tour, err := GetTour(ctx, id)
+	if err != nil {
+		return models.TourPlayback{}, err
+	}
+	playback := models.TourPlayback{
+		TourID:      tour.TourID,
+		Title:       tour.Title,
+		Description: tour.Description,
+		Steps:       make([]models.TourPlaybackStep, 0, len(tour.Steps)),
+	}
+
+	// รวม event ที่ต้องโหลด: event ของ step และ event ใน cluster ของ step
+	var clusterIDs []int
+	for _, s := range tour.Steps {
+		if s.ClusterID != nil {
+			clusterIDs = append(clusterIDs, *s.ClusterID)
+		}
+	}
+	clusterEvents, err := getClusterEventIDs(ctx, clusterIDs)
+	if err != nil {
+		return playback, err
+	}
+
+	ids := make(map[int]struct{})
+	for _, s := range tour.Steps {
+		if s.EventID != nil {
+			ids[*s.EventID] = struct{}{}
+		}
+	}
+	for _, eventIDs := range clusterEvents {
+		for _, eid := range eventIDs {
+			ids[eid] = struct{}{}
+		}
+	}
+	details, err := loadEventDetails(ctx, ids)
+	if err != nil {
+		return playback, err
+	}
+
+	for _, s := range tour.Steps {
+		step := models.TourPlaybackStep{TourStep: s, Events: []models.EventResponse{}}
+		switch {
+		case s.EventID == nil && s.ClusterID == nil:
+			step.Missing = true // event ถูกลบ (event_id ถูก set เป็น NULL)
+		case s.EventID != nil:
+			if ev, ok := details[*s.EventID]; ok {
+				step.Events = append(step.Events, ev)
+			} else {
+				step.Missing = true
+			}
+		case s.ClusterID != nil:
+			eventIDs, ok := clusterEvents[*s.ClusterID]
+			if !ok {
+				step.Missing = true
+			}
+			start, end := s.WindowStart.TimePtr(), s.WindowEnd.TimePtr()
+			for _, eid := range eventIDs {
+				ev, ok := details[eid]
+				if !ok || !models.DateSpanOverlaps(ev.Date.Time, ev.DatePrecision, ev.EndDate.TimePtr(), start, end) {
+					continue
+				}
+				step.Events = append(step.Events, ev)
+			}
+			sort.Slice(step.Events, func(i, j int) bool {
+				return step.Events[i].Date.Before(step.Events[j].Date.Time)
+			})
+		}
+		playback.Steps = append(playback.Steps, step)
+	}
+	return playback, nil
+}
+
+// getClusterEventIDs คืน map[cluster_id][]event_id ของ cluster ที่มีอยู่จริง
+func getClusterEventIDs(ctx context.Context, clusterIDs []int) (map[int][]int, error) {
+	out := make(map[int][]int)
+	if len(clusterIDs) == 0 {
+		return out, nil
+	}
+	rows, err := connection.DB.Query(ctx, `
+		SELECT c.cluster_id,
+			COALESCE(ARRAY_AGG(ecm.event_id) FILTER (WHERE ecm.event_id IS NOT NULL), '{}')
+		FROM cluster c
+		LEFT JOIN eventclustermap ecm ON c.cluster_id = ecm.cluster_id
+		WHERE c.cluster_id = ANY($1)
+		GROUP BY c.cluster_id
+	`, clusterIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var clusterID int
+		var eventIDs []int
+		if err := rows.Scan(&clusterID, &eventIDs); err != nil {
+			return nil, err
+		}
+		out[clusterID] = eventIDs
+	}
+	return out, rows.Err()
+}
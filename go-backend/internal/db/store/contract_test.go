@@ -0,0 +1,616 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"globe/internal/db/connection"
+	"globe/internal/db/migrate"
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// contract suite: ทุก backend ต้องคืนผลเดียวกันสำหรับ fixture ชุดเดียวกัน
+// Postgres ทดสอบเมื่อกำหนด DATABASE_URL เท่านั้น และใช้ schema ชั่วคราวเพื่อไม่แตะข้อมูลจริง
+
+type contractBackend struct {
+	name string
+	open func(t *testing.T) (Store, seedFunc)
+}
+
+type seedFunc func(t *testing.T, events []models.EventResponse, links []models.EventEntityLink)
+
+func TestStoreContract(t *testing.T) {
+	backends := []contractBackend{
+		{"memory", openMemoryContract},
+		{"sqlite", openSQLiteContract},
+		{"postgres", openPostgresContract},
+	}
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s, seed := b.open(t)
+			seed(t, contractEvents(), contractLinks)
+			runStoreContract(t, s)
+		})
+	}
+}
+
+func openMemoryContract(t *testing.T) (Store, seedFunc) {
+	m := NewMemory()
+	return m, func(t *testing.T, events []models.EventResponse, links []models.EventEntityLink) {
+		for _, ev := range events {
+			m.AddEvent(ev)
+		}
+		for _, link := range links {
+			m.LinkEntity(link)
+		}
+	}
+}
+
+func openSQLiteContract(t *testing.T) (Store, seedFunc) {
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "contract.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s, func(t *testing.T, events []models.EventResponse, links []models.EventEntityLink) {
+		tags := make(map[string]int)
+		for _, ev := range events {
+			var geometry *string
+			if ev.Geometry != nil {
+				b, err := json.Marshal(ev.Geometry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				g := string(b)
+				geometry = &g
+			}
+			var endDay *int64
+			if ev.EndDate != nil {
+				d := dateToDay(ev.EndDate.Time)
+				endDay = &d
+			}
+			if _, err := s.db.Exec(
+				`INSERT INTO event (event_id, event_name, date, date_precision, end_date, lat, lon, geometry, image, video, description)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				ev.EventID, ev.EventName, dateToDay(ev.Date.Time), ev.DatePrecision, endDay, ev.Lat, ev.Lon, geometry, ev.Image, ev.Video, ev.Description,
+			); err != nil {
+				t.Fatalf("insert event %d: %v", ev.EventID, err)
+			}
+			for _, tag := range ev.Tags {
+				id, ok := tags[tag]
+				if !ok {
+					id = len(tags) + 1
+					tags[tag] = id
+					if _, err := s.db.Exec(`INSERT INTO tag (tag_id, tag_name) VALUES (?, ?)`, id, tag); err != nil {
+						t.Fatalf("insert tag %q: %v", tag, err)
+					}
+				}
+				if _, err := s.db.Exec(`INSERT INTO eventtag (event_id, tag_id) VALUES (?, ?)`, ev.EventID, id); err != nil {
+					t.Fatalf("insert eventtag: %v", err)
+				}
+			}
+		}
+		for _, link := range links {
+			if _, err := s.db.Exec(`INSERT INTO event_entity (event_id, entity_id, role) VALUES (?, ?, ?)`,
+				link.EventID, link.EntityID, link.Role); err != nil {
+				t.Fatalf("insert event_entity: %v", err)
+			}
+		}
+	}
+}
+
+func openPostgresContract(t *testing.T) (Store, seedFunc) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	if _, ok := SQLitePath(dbURL); ok {
+		t.Skip("DATABASE_URL points to a SQLite file")
+	}
+	ctx := context.Background()
+
+	cfg, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		t.Fatalf("parse DATABASE_URL: %v", err)
+	}
+	schema := fmt.Sprintf("store_contract_%d", time.Now().UnixNano())
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		pool.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	prev := connection.DB
+	connection.DB = pool
+	t.Cleanup(func() {
+		connection.DB = prev
+		if _, err := pool.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+		pool.Close()
+	})
+	if _, err := migrate.Up(ctx, pool); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return NewPostgres(), func(t *testing.T, events []models.EventResponse, links []models.EventEntityLink) {
+		tags := make(map[string]int)
+		for _, ev := range events {
+			var geometry *string
+			if ev.Geometry != nil {
+				b, err := json.Marshal(ev.Geometry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				g := string(b)
+				geometry = &g
+			}
+			if _, err := pool.Exec(ctx,
+				`INSERT INTO event (event_id, event_name, date, date_precision, end_date, lat, lon, geometry, image, video, description)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb, $9, $10, $11)`,
+				ev.EventID, ev.EventName, ev.Date.Time, ev.DatePrecision, ev.EndDate.TimePtr(), ev.Lat, ev.Lon, geometry, ev.Image, ev.Video, ev.Description,
+			); err != nil {
+				t.Fatalf("insert event %d: %v", ev.EventID, err)
+			}
+			for _, tag := range ev.Tags {
+				id, ok := tags[tag]
+				if !ok {
+					if err := pool.QueryRow(ctx, `INSERT INTO tag (tag_name) VALUES ($1) RETURNING tag_id`, tag).Scan(&id); err != nil {
+						t.Fatalf("insert tag %q: %v", tag, err)
+					}
+					tags[tag] = id
+				}
+				if _, err := pool.Exec(ctx, `INSERT INTO eventtag (event_id, tag_id) VALUES ($1, $2)`, ev.EventID, id); err != nil {
+					t.Fatalf("insert eventtag: %v", err)
+				}
+			}
+		}
+		for _, link := range links {
+			if _, err := pool.Exec(ctx,
+				`INSERT INTO entity (entity_id, name, entity_type) VALUES ($1, $2, 'person') ON CONFLICT DO NOTHING`,
+				link.EntityID, fmt.Sprintf("entity %d", link.EntityID)); err != nil {
+				t.Fatalf("insert entity: %v", err)
+			}
+			if _, err := pool.Exec(ctx, `INSERT INTO event_entity (event_id, entity_id, role) VALUES ($1, $2, $3)`,
+				link.EventID, link.EntityID, link.Role); err != nil {
+				t.Fatalf("insert event_entity: %v", err)
+			}
+		}
+	}
+}
+
+func contractDate(s string) *models.Date {
+	t, err := models.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return &models.Date{Time: t}
+}
+
+// contractEvents มีทั้ง end_date, precision แบบปี/เดือน, geometry ที่อยู่นอกจุด lat/lon และ tag ตัวพิมพ์ใหญ่
+func contractEvents() []models.EventResponse {
+	return []models.EventResponse{
+		{EventID: 1, EventName: "Battle of the Somme", Date: *contractDate("1916-07-01"), DatePrecision: "day",
+			EndDate: contractDate("1916-11-18"), Lat: 50.0, Lon: 2.7, Tags: []string{"battle", "western front"}},
+		{EventID: 2, EventName: "Treaty of Versailles", Date: *contractDate("1919-06-28"), DatePrecision: "day",
+			Lat: 48.8, Lon: 2.1, Tags: []string{"treaty"}},
+		{EventID: 3, EventName: "Gallipoli campaign", Date: *contractDate("1915-01-01"), DatePrecision: "year",
+			Lat: 40.2, Lon: 26.4, Tags: []string{"battle", "ottoman"}},
+		{EventID: 4, EventName: "Trans-Siberian evacuation", Date: *contractDate("1916-10-01"), DatePrecision: "month",
+			Lat: 55.0, Lon: 82.9, Tags: []string{"railway"},
+			Geometry: &models.Geometry{Type: models.GeometryLineString, Parts: [][][2]float64{{{37.6, 55.7}, {82.9, 55.0}, {131.9, 43.1}}}}},
+		{EventID: 5, EventName: "Attack on Pearl Harbor", Date: *contractDate("1941-12-07"), DatePrecision: "day",
+			Lat: 21.3, Lon: -157.9, Tags: []string{"Battle", "pacific"}},
+	}
+}
+
+var contractLinks = []models.EventEntityLink{
+	{EventID: 1, EntityID: 1, Role: "commander"},
+	{EventID: 1, EntityID: 2, Role: "participant"},
+	{EventID: 3, EntityID: 2, Role: "commander"},
+}
+
+func contractClusters() []models.Cluster {
+	root := 100
+	return []models.Cluster{
+		{ClusterID: 100, CentroidLat: 48, CentroidLon: 30, CentroidTimeDays: "0", Level: 0, EventIDs: []int{1, 2, 3, 4}},
+		{ClusterID: 101, ParentClusterID: &root, CentroidLat: 49, CentroidLon: 2, CentroidTimeDays: "0", Level: 1, EventIDs: []int{1, 2}},
+		{ClusterID: 102, ParentClusterID: &root, CentroidLat: 47, CentroidLon: 54, CentroidTimeDays: "0", Level: 1, EventIDs: []int{3, 4}},
+		{ClusterID: 200, CentroidLat: 21.3, CentroidLon: -157.9, CentroidTimeDays: "0", Level: 0, EventIDs: []int{5}},
+	}
+}
+
+func runStoreContract(t *testing.T, s Store) {
+	ctx := context.Background()
+	year := func(y int) *models.DateFilter { return &models.DateFilter{Year: &y} }
+	between := func(start, end string) *models.DateFilter {
+		return &models.DateFilter{StartDate: contractDate(start), EndDate: contractDate(end)}
+	}
+
+	filterCases := []struct {
+		name   string
+		filter models.EventFilter
+		want   []int
+	}{
+		{"no filter", models.EventFilter{}, []int{5, 2, 4, 1, 3}},
+		{"tag or is case-insensitive", models.EventFilter{TagFilter: &models.TagFilter{Tags: []string{"battle"}, Operator: "OR"}}, []int{5, 1, 3}},
+		{"tag or any", models.EventFilter{TagFilter: &models.TagFilter{Tags: []string{"treaty", "railway"}}}, []int{2, 4}},
+		{"tag and substring", models.EventFilter{TagFilter: &models.TagFilter{Tags: []string{"battle", "front"}, Operator: "AND"}}, []int{1}},
+		{"year precision", models.EventFilter{DateFilter: year(1915)}, []int{3}},
+		{"year overlaps spans", models.EventFilter{DateFilter: year(1916)}, []int{4, 1}},
+		{"end_date overlap", models.EventFilter{DateFilter: between("1916-11-01", "1916-11-30")}, []int{1}},
+		{"month precision overlap", models.EventFilter{DateFilter: between("1916-10-15", "1916-10-20")}, []int{4, 1}},
+		{"viewport point", models.EventFilter{Viewport: &models.Viewport{North: 52, South: 45, West: 0, East: 10}}, []int{2, 1}},
+		{"viewport geometry", models.EventFilter{Viewport: &models.Viewport{North: 60, South: 50, West: 35, East: 45}}, []int{4}},
+		{"viewport antimeridian", models.EventFilter{Viewport: &models.Viewport{North: 30, South: 10, West: 170, East: -150}}, []int{5}},
+		{"entity or", models.EventFilter{EntityFilter: &models.EntityFilter{EntityIDs: []int{2}}}, []int{1, 3}},
+		{"entity role", models.EventFilter{EntityFilter: &models.EntityFilter{EntityIDs: []int{2}, Roles: []string{"commander"}}}, []int{3}},
+		{"entity and", models.EventFilter{EntityFilter: &models.EntityFilter{EntityIDs: []int{1, 2}, Operator: "AND"}}, []int{1}},
+		{"combined", models.EventFilter{
+			TagFilter:  &models.TagFilter{Tags: []string{"battle"}},
+			DateFilter: year(1916),
+			Viewport:   &models.Viewport{North: 60, South: 40, West: -10, East: 40},
+		}, []int{1}},
+	}
+	for _, tc := range filterCases {
+		t.Run("GetFilteredEvents/"+tc.name, func(t *testing.T) {
+			events, err := s.GetFilteredEvents(ctx, tc.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := eventIDs(events); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("event ids = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("GetFilteredEvents/fields", func(t *testing.T) {
+		events, err := s.GetFilteredEvents(ctx, models.EventFilter{EntityFilter: &models.EntityFilter{EntityIDs: []int{1}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("got %d events, want 1", len(events))
+		}
+		ev := events[0]
+		if ev.EventName != "Battle of the Somme" || ev.DatePrecision != "day" || ev.Lat != 50.0 || ev.Lon != 2.7 {
+			t.Errorf("unexpected event %+v", ev)
+		}
+		if day(&ev.Date) != "1916-07-01" || day(ev.EndDate) != "1916-11-18" {
+			t.Errorf("date = %s, end_date = %s", day(&ev.Date), day(ev.EndDate))
+		}
+		if !reflect.DeepEqual(ev.Tags, []string{"battle", "western front"}) {
+			t.Errorf("tags = %v", ev.Tags)
+		}
+		if len(ev.Clusters) != 0 {
+			t.Errorf("clusters = %v, want none before clustering", ev.Clusters)
+		}
+	})
+
+	t.Run("GetEventRecords", func(t *testing.T) {
+		records, err := s.GetEventRecords(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, r := range records {
+			ids = append(ids, r.EventID)
+		}
+		if !reflect.DeepEqual(ids, []int{1, 2, 3, 4, 5}) {
+			t.Fatalf("record ids = %v", ids)
+		}
+		somme, gallipoli := records[0], records[2]
+		if somme.EventName == nil || *somme.EventName != "Battle of the Somme" {
+			t.Errorf("event_name = %v", somme.EventName)
+		}
+		if day(somme.Date) != "1916-07-01" || day(somme.EndDate) != "1916-11-18" {
+			t.Errorf("date = %s, end_date = %s", day(somme.Date), day(somme.EndDate))
+		}
+		if somme.Lat == nil || *somme.Lat != 50.0 || somme.Lon == nil || *somme.Lon != 2.7 {
+			t.Errorf("lat/lon = %v/%v", somme.Lat, somme.Lon)
+		}
+		if gallipoli.Precision == nil || *gallipoli.Precision != "year" || gallipoli.EndDate != nil {
+			t.Errorf("precision = %v, end_date = %v", gallipoli.Precision, gallipoli.EndDate)
+		}
+		if somme.Image == nil || *somme.Image != "" || somme.Video == nil || *somme.Video != "" {
+			t.Errorf("image/video = %v/%v", somme.Image, somme.Video)
+		}
+	})
+
+	t.Run("GetEventLatLonDate", func(t *testing.T) {
+		events, err := s.GetEventLatLonDate(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 5 {
+			t.Errorf("got %d events, want 5", len(events))
+		}
+	})
+
+	// ผล clustering ซ้ำต้องถูกข้าม (ON CONFLICT DO NOTHING)
+	for i := 0; i < 2; i++ {
+		if err := s.InsertClustersAndMappings(ctx, contractClusters()); err != nil {
+			t.Fatalf("InsertClustersAndMappings (round %d): %v", i+1, err)
+		}
+	}
+
+	world := models.Viewport{North: 90, South: -90, West: -180, East: 180}
+	t.Run("GetHierarchicalClusters/bounds", func(t *testing.T) {
+		clusters, err := s.GetHierarchicalClusters(ctx, models.ClusterQuery{Viewport: world, MaxLevel: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byID := clustersByID(clusters)
+		if got := sortedKeys(byID); !reflect.DeepEqual(got, []int{100, 101, 102, 200}) {
+			t.Fatalf("cluster ids = %v", got)
+		}
+		want := []struct {
+			id                             int
+			minLat, maxLat, minLon, maxLon float64
+			minDate, maxDate               string
+			events                         []int
+		}{
+			{100, 40.2, 55.0, 2.1, 82.9, "1915-01-01", "1919-06-28", nil},
+			{101, 48.8, 50.0, 2.1, 2.7, "1916-07-01", "1919-06-28", []int{1, 2}},
+			// max_date คือวันสุดท้ายของ event ที่มี precision เป็นเดือน (1916-10)
+			{102, 40.2, 55.0, 26.4, 82.9, "1915-01-01", "1916-10-31", []int{3, 4}},
+			{200, 21.3, 21.3, -157.9, -157.9, "1941-12-07", "1941-12-07", []int{5}},
+		}
+		for _, w := range want {
+			c := byID[w.id]
+			if c.MinLat == nil || c.MaxLat == nil || c.MinLon == nil || c.MaxLon == nil {
+				t.Errorf("cluster %d has no bounds", w.id)
+				continue
+			}
+			if *c.MinLat != w.minLat || *c.MaxLat != w.maxLat || *c.MinLon != w.minLon || *c.MaxLon != w.maxLon {
+				t.Errorf("cluster %d bounds = [%v, %v] x [%v, %v]", w.id, *c.MinLat, *c.MaxLat, *c.MinLon, *c.MaxLon)
+			}
+			if day(c.MinDate) != w.minDate || day(c.MaxDate) != w.maxDate {
+				t.Errorf("cluster %d dates = %s..%s, want %s..%s", w.id, day(c.MinDate), day(c.MaxDate), w.minDate, w.maxDate)
+			}
+			if got := eventIDs(c.Events); !reflect.DeepEqual(got, w.events) {
+				t.Errorf("cluster %d events = %v, want %v", w.id, got, w.events)
+			}
+		}
+	})
+
+	t.Run("GetHierarchicalClusters/filters", func(t *testing.T) {
+		y := 1919
+		clusters, err := s.GetHierarchicalClusters(ctx, models.ClusterQuery{Viewport: world, MaxLevel: 1, DateFilter: &models.DateFilter{Year: &y}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byID := clustersByID(clusters)
+		if got := sortedKeys(byID); !reflect.DeepEqual(got, []int{100, 101}) {
+			t.Fatalf("cluster ids = %v, want [100 101]", got)
+		}
+		if got := eventIDs(byID[101].Events); !reflect.DeepEqual(got, []int{2}) {
+			t.Errorf("cluster 101 events = %v, want [2]", got)
+		}
+
+		pacific := models.Viewport{North: 30, South: 10, West: -170, East: -150}
+		clusters, err = s.GetHierarchicalClusters(ctx, models.ClusterQuery{Viewport: pacific, MaxLevel: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sortedKeys(clustersByID(clusters)); !reflect.DeepEqual(got, []int{200}) {
+			t.Errorf("cluster ids = %v, want [200]", got)
+		}
+
+		clusters, err = s.GetHierarchicalClusters(ctx, models.ClusterQuery{Viewport: world, MaxLevel: 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sortedKeys(clustersByID(clusters)); !reflect.DeepEqual(got, []int{100, 200}) {
+			t.Errorf("cluster ids = %v, want [100 200]", got)
+		}
+	})
+
+	t.Run("ClaimClusterJob", func(t *testing.T) {
+		var created []int
+		for i := 0; i < 5; i++ {
+			job, ok, err := s.CreateClusterJob(ctx, models.ClusterJob{Status: models.ClusterJobQueued, Engine: models.ClusterEnginePython}, 100)
+			if err != nil || !ok {
+				t.Fatalf("CreateClusterJob = %+v, %v, %v", job, ok, err)
+			}
+			created = append(created, job.JobID)
+		}
+		canceled, ok, err := s.CancelQueuedClusterJob(ctx, created[1])
+		if err != nil || !ok || canceled.Status != models.ClusterJobCanceled || canceled.FinishedAt == nil {
+			t.Fatalf("CancelQueuedClusterJob = %+v, %v, %v", canceled, ok, err)
+		}
+
+		// worker หลายตัวแย่งกันหยิบงาน ทุกงานในคิวต้องถูกหยิบครั้งเดียว
+		var mu sync.Mutex
+		var claimed []int
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					job, ok, err := s.ClaimClusterJob(ctx, fmt.Sprintf("worker-%d", w), time.Minute)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if !ok {
+						return
+					}
+					if job.Status != models.ClusterJobRunning || job.Attempts != 1 || job.StartedAt == nil ||
+						job.Owner != fmt.Sprintf("worker-%d", w) || job.LeaseUntil == nil {
+						t.Errorf("claimed job = %+v", job)
+					}
+					mu.Lock()
+					claimed = append(claimed, job.JobID)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		sort.Ints(claimed)
+		if want := []int{created[0], created[2], created[3], created[4]}; !reflect.DeepEqual(claimed, want) {
+			t.Errorf("claimed = %v, want %v", claimed, want)
+		}
+
+		// งานที่เริ่มหรือถูกยกเลิกไปแล้วยกเลิกซ้ำไม่ได้
+		for _, id := range []int{created[0], created[1], created[4] + 100} {
+			if _, ok, err := s.CancelQueuedClusterJob(ctx, id); err != nil || ok {
+				t.Errorf("CancelQueuedClusterJob(%d) = %v, %v, want false", id, ok, err)
+			}
+		}
+		if job, err := s.GetClusterJob(ctx, created[0]); err != nil || job.Status != models.ClusterJobRunning {
+			t.Errorf("job %d = %+v, %v, want running", created[0], job, err)
+		}
+	})
+
+	t.Run("CreateClusterJob/queue limit", func(t *testing.T) {
+		// ทุกงานก่อนหน้าถูกหยิบหรือยกเลิกแล้ว คิวว่าง: request พร้อมกันต้องได้งานไม่เกิน maxQueued
+		var mu sync.Mutex
+		var accepted []int
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				job, ok, err := s.CreateClusterJob(ctx, models.ClusterJob{Status: models.ClusterJobQueued, Engine: models.ClusterEnginePython}, 3)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if ok {
+					mu.Lock()
+					accepted = append(accepted, job.JobID)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if len(accepted) != 3 {
+			t.Errorf("accepted %d jobs, want 3", len(accepted))
+		}
+		for _, id := range accepted {
+			if _, ok, err := s.CancelQueuedClusterJob(ctx, id); err != nil || !ok {
+				t.Errorf("CancelQueuedClusterJob(%d) = %v, %v", id, ok, err)
+			}
+		}
+	})
+
+	t.Run("ClusterJob lease", func(t *testing.T) {
+		// งานจากขั้นก่อนหน้ายังถือ lease อยู่ ต้องไม่ถูกนำกลับเข้าคิว
+		if expired, err := s.RequeueExpiredClusterJobs(ctx, 3); err != nil || len(expired) != 0 {
+			t.Fatalf("RequeueExpiredClusterJobs = %+v, %v, want none", expired, err)
+		}
+
+		created, ok, err := s.CreateClusterJob(ctx, models.ClusterJob{Status: models.ClusterJobQueued, Engine: models.ClusterEnginePython}, 100)
+		if err != nil || !ok {
+			t.Fatalf("CreateClusterJob = %+v, %v, %v", created, ok, err)
+		}
+		// lease ติดลบคือหมดทันที เหมือน instance ที่ crash ไปแล้ว
+		crashed, ok, err := s.ClaimClusterJob(ctx, "crashed", -time.Second)
+		if err != nil || !ok || crashed.JobID != created.JobID {
+			t.Fatalf("ClaimClusterJob = %+v, %v, %v", crashed, ok, err)
+		}
+		if ok, err := s.RenewClusterJobLease(ctx, crashed.JobID, "other", time.Minute); err != nil || ok {
+			t.Errorf("RenewClusterJobLease by other owner = %v, %v, want false", ok, err)
+		}
+		other := crashed
+		other.Owner, other.Stage = "other", models.ClusterStageFetching
+		if err := s.UpdateClusterJob(ctx, other); !errors.Is(err, repository.ErrClusterJobLeaseLost) {
+			t.Errorf("UpdateClusterJob by other owner = %v, want ErrClusterJobLeaseLost", err)
+		}
+
+		expired, err := s.RequeueExpiredClusterJobs(ctx, 3)
+		if err != nil || len(expired) != 1 || expired[0].JobID != created.JobID ||
+			expired[0].Status != models.ClusterJobQueued || expired[0].StartedAt != nil || expired[0].LeaseUntil != nil {
+			t.Fatalf("RequeueExpiredClusterJobs = %+v, %v, want job %d queued", expired, err, created.JobID)
+		}
+		// worker เดิมกลับมาหลังงานถูกนำกลับเข้าคิว บันทึกหรือต่อ lease ไม่ได้แล้ว
+		crashed.Status = models.ClusterJobSucceeded
+		if err := s.UpdateClusterJob(ctx, crashed); !errors.Is(err, repository.ErrClusterJobLeaseLost) {
+			t.Errorf("UpdateClusterJob after requeue = %v, want ErrClusterJobLeaseLost", err)
+		}
+		if ok, err := s.RenewClusterJobLease(ctx, crashed.JobID, crashed.Owner, time.Minute); err != nil || ok {
+			t.Errorf("RenewClusterJobLease after requeue = %v, %v, want false", ok, err)
+		}
+
+		job, ok, err := s.ClaimClusterJob(ctx, "next", time.Minute)
+		if err != nil || !ok || job.JobID != created.JobID || job.Attempts != 2 {
+			t.Fatalf("ClaimClusterJob = %+v, %v, %v, want job %d attempt 2", job, ok, err, created.JobID)
+		}
+		if ok, err := s.RenewClusterJobLease(ctx, job.JobID, job.Owner, time.Minute); err != nil || !ok {
+			t.Errorf("RenewClusterJobLease = %v, %v, want true", ok, err)
+		}
+		now := time.Now()
+		job.Status, job.FinishedAt = models.ClusterJobSucceeded, &now
+		if err := s.UpdateClusterJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.GetClusterJob(ctx, job.JobID); err != nil || got.Status != models.ClusterJobSucceeded || got.LeaseUntil != nil {
+			t.Errorf("job %d = %+v, %v, want succeeded without lease", job.JobID, got, err)
+		}
+
+		// งานที่เริ่มมาครบ maxAttempts แล้วล้มเหลวแทนการกลับเข้าคิว
+		created, ok, err = s.CreateClusterJob(ctx, models.ClusterJob{Status: models.ClusterJobQueued, Engine: models.ClusterEnginePython}, 100)
+		if err != nil || !ok {
+			t.Fatalf("CreateClusterJob = %+v, %v, %v", created, ok, err)
+		}
+		if _, ok, err := s.ClaimClusterJob(ctx, "crashed", -time.Second); err != nil || !ok {
+			t.Fatalf("ClaimClusterJob = %v, %v", ok, err)
+		}
+		expired, err = s.RequeueExpiredClusterJobs(ctx, 1)
+		if err != nil || len(expired) != 1 || expired[0].Status != models.ClusterJobFailed ||
+			expired[0].Error != "interrupted 1 times" || expired[0].FinishedAt == nil {
+			t.Errorf("RequeueExpiredClusterJobs = %+v, %v, want job %d failed", expired, err, created.JobID)
+		}
+	})
+
+	t.Run("GetFilteredEvents/clusters", func(t *testing.T) {
+		events, err := s.GetFilteredEvents(ctx, models.EventFilter{EntityFilter: &models.EntityFilter{EntityIDs: []int{1}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || !reflect.DeepEqual(events[0].Clusters, []int{100, 101}) {
+			t.Errorf("events = %+v, want event 1 in clusters [100 101]", events)
+		}
+	})
+}
+
+func eventIDs(events []models.EventResponse) []int {
+	var ids []int
+	for _, ev := range events {
+		ids = append(ids, ev.EventID)
+	}
+	return ids
+}
+
+func clustersByID(clusters []models.Cluster) map[int]models.Cluster {
+	byID := make(map[int]models.Cluster, len(clusters))
+	for _, c := range clusters {
+		byID[c.ClusterID] = c
+	}
+	return byID
+}
+
+func day(d *models.Date) string {
+	if d == nil {
+		return "<nil>"
+	}
+	return d.UTC().Format("2006-01-02")
+}
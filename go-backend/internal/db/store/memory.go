@@ -0,0 +1,428 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+)
+
+// Memory เก็บ event และ cluster ไว้ในหน่วยความจำ และใช้ filter แบบเดียวกับ Postgres
+// ใช้สำหรับทดสอบ handler โดยไม่ต้องมีฐานข้อมูล
+type Memory struct {
+	mu       sync.RWMutex
+	events   map[int]models.EventResponse
+	links    []models.EventEntityLink
+	clusters map[int]models.Cluster
+	mappings map[int]map[int]struct{} // cluster_id -> event_id
+	jobs     map[int]models.ClusterJob
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		events:   make(map[int]models.EventResponse),
+		clusters: make(map[int]models.Cluster),
+		mappings: make(map[int]map[int]struct{}),
+		jobs:     make(map[int]models.ClusterJob),
+	}
+}
+
+// AddEvent เพิ่มหรือแทนที่ event (field Clusters จะคำนวณจาก mapping ตอนอ่าน)
+func (m *Memory) AddEvent(ev models.EventResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events[ev.EventID] = ev
+}
+
+// LinkEntity เชื่อม entity กับ event สำหรับ entity filter
+func (m *Memory) LinkEntity(link models.EventEntityLink) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.links = append(m.links, link)
+}
+
+func (m *Memory) GetFilteredEvents(ctx context.Context, filter models.EventFilter) ([]models.EventResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var events []models.EventResponse
+	for _, id := range m.sortedEventIDs() {
+		ev := m.eventWithClusters(id)
+		if !matchesTags(ev.Tags, filter.TagFilter) || !m.matchesEntities(ev.EventID, filter.EntityFilter) {
+			continue
+		}
+		if start, end := filter.DateFilter.Bounds(); !models.DateSpanOverlaps(ev.Date.Time, ev.DatePrecision, ev.EndDate.TimePtr(), start, end) {
+			continue
+		}
+		if !filter.MatchesLocation(ev.Lat, ev.Lon, ev.Geometry) {
+			continue
+		}
+		events = append(events, ev)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Date.After(events[j].Date.Time)
+	})
+	return events, nil
+}
+
+func (m *Memory) GetEventLatLonDate(ctx context.Context) ([]models.EventLatLonDate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var events []models.EventLatLonDate
+	for _, id := range m.sortedEventIDs() {
+		ev := m.events[id]
+		events = append(events, models.NewEventLatLonDate(ev.EventID, ev.Lat, ev.Lon, ev.Date, ev.DatePrecision, ev.EndDate))
+	}
+	return events, nil
+}
+
+func (m *Memory) GetEventRecords(ctx context.Context) ([]models.EventRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var records []models.EventRecord
+	for _, id := range m.sortedEventIDs() {
+		ev := m.events[id]
+		records = append(records, models.EventRecord{
+			EventID:   ev.EventID,
+			EventName: &ev.EventName,
+			Date:      &ev.Date,
+			Precision: &ev.DatePrecision,
+			EndDate:   ev.EndDate,
+			Lat:       &ev.Lat,
+			Lon:       &ev.Lon,
+			Image:     &ev.Image,
+			Video:     &ev.Video,
+		})
+	}
+	return records, nil
+}
+
+func (m *Memory) InsertClustersAndMappings(ctx context.Context, clusters []models.Cluster) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range clusters {
+		if _, exists := m.clusters[c.ClusterID]; !exists {
+			stored := c
+			stored.EventIDs, stored.Events = nil, nil
+			m.clusters[c.ClusterID] = stored
+		}
+		if m.mappings[c.ClusterID] == nil {
+			m.mappings[c.ClusterID] = make(map[int]struct{})
+		}
+		for _, eid := range c.EventIDs {
+			m.mappings[c.ClusterID][eid] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// GetHierarchicalClusters คำนวณกรอบ lat/lon และช่วงวันที่ของแต่ละ cluster จาก event ใน subtree
+// (ใน Postgres เป็น column ของตาราง cluster) แล้วใช้ repository.FilterClusterHierarchy ตัวเดียวกัน
+func (m *Memory) GetHierarchicalClusters(ctx context.Context, query models.ClusterQuery) ([]models.Cluster, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	children := make(map[int][]int)
+	for id, c := range m.clusters {
+		if c.ParentClusterID != nil {
+			children[*c.ParentClusterID] = append(children[*c.ParentClusterID], id)
+		}
+	}
+	var subtreeEvents func(id int) []int
+	subtreeEvents = func(id int) []int {
+		ids := sortedKeys(m.mappings[id])
+		for _, child := range children[id] {
+			ids = append(ids, subtreeEvents(child)...)
+		}
+		return ids
+	}
+
+	var clusters []models.Cluster
+	details := make(map[int]models.EventResponse)
+	for _, id := range sortedKeys(m.clusters) {
+		c := m.clusters[id]
+		if c.Level > query.MaxLevel {
+			continue
+		}
+		c.EventIDs = sortedKeys(m.mappings[id])
+		for _, eid := range subtreeEvents(id) {
+			ev, ok := m.events[eid]
+			if !ok {
+				continue
+			}
+			details[eid] = m.eventWithClusters(eid)
+			extendBounds(&c, ev)
+		}
+		clusters = append(clusters, c)
+	}
+	return repository.FilterClusterHierarchy(clusters, details, query), nil
+}
+
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *Memory) GetDataCounts(ctx context.Context) (models.DataCounts, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	clustered := make(map[int]struct{})
+	for _, events := range m.mappings {
+		for eid := range events {
+			clustered[eid] = struct{}{}
+		}
+	}
+	return models.DataCounts{Events: len(m.events), ClusteredEvents: len(clustered), Clusters: len(m.clusters)}, nil
+}
+
+func (m *Memory) CreateClusterJob(ctx context.Context, job models.ClusterJob, maxQueued int) (models.ClusterJob, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	queued := 0
+	for _, j := range m.jobs {
+		if j.Status == models.ClusterJobQueued {
+			queued++
+		}
+	}
+	if queued >= maxQueued {
+		return job, false, nil
+	}
+	job.JobID = len(m.jobs) + 1
+	job.CreatedAt = time.Now()
+	m.jobs[job.JobID] = job
+	return job, true, nil
+}
+
+func (m *Memory) UpdateClusterJob(ctx context.Context, job models.ClusterJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.jobs[job.JobID]
+	if !ok || stored.Status != models.ClusterJobRunning || stored.Owner != job.Owner {
+		return repository.ErrClusterJobLeaseLost
+	}
+	job.Engine, job.Params, job.CreatedAt, job.LeaseUntil = stored.Engine, stored.Params, stored.CreatedAt, stored.LeaseUntil
+	if job.Status != models.ClusterJobRunning {
+		job.LeaseUntil = nil
+	}
+	m.jobs[job.JobID] = job
+	return nil
+}
+
+func (m *Memory) GetClusterJob(ctx context.Context, id int) (models.ClusterJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return job, repository.ErrClusterJobNotFound
+	}
+	return job, nil
+}
+
+func (m *Memory) ListClusterJobs(ctx context.Context, query models.ClusterJobQuery) ([]models.ClusterJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := sortedKeys(m.jobs) // job_id เรียงตามเวลาที่สร้าง
+	if !query.OldestFirst {
+		sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	}
+	jobs := []models.ClusterJob{}
+	for _, id := range ids {
+		if query.Limit > 0 && len(jobs) == query.Limit {
+			break
+		}
+		if job := m.jobs[id]; query.Status == "" || job.Status == query.Status {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *Memory) ClaimClusterJob(ctx context.Context, owner string, lease time.Duration) (models.ClusterJob, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range sortedKeys(m.jobs) {
+		job := m.jobs[id]
+		if job.Status != models.ClusterJobQueued {
+			continue
+		}
+		now := time.Now()
+		until := now.Add(lease)
+		job.Status, job.Stage, job.Progress, job.Error = models.ClusterJobRunning, "", 0, ""
+		job.Attempts++
+		job.StartedAt, job.Owner, job.LeaseUntil = &now, owner, &until
+		m.jobs[id] = job
+		return job, true, nil
+	}
+	return models.ClusterJob{}, false, nil
+}
+
+func (m *Memory) RenewClusterJobLease(ctx context.Context, id int, owner string, lease time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok || job.Status != models.ClusterJobRunning || job.Owner != owner {
+		return false, nil
+	}
+	until := time.Now().Add(lease)
+	job.LeaseUntil = &until
+	m.jobs[id] = job
+	return true, nil
+}
+
+func (m *Memory) RequeueExpiredClusterJobs(ctx context.Context, maxAttempts int) ([]models.ClusterJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	jobs := []models.ClusterJob{}
+	for _, id := range sortedKeys(m.jobs) {
+		job := m.jobs[id]
+		if job.Status != models.ClusterJobRunning || (job.LeaseUntil != nil && !job.LeaseUntil.Before(now)) {
+			continue
+		}
+		if job.Attempts >= maxAttempts {
+			job.Status, job.FinishedAt = models.ClusterJobFailed, &now
+			job.Error = fmt.Sprintf("interrupted %d times", job.Attempts)
+		} else {
+			job.Status, job.Stage, job.Progress, job.StartedAt = models.ClusterJobQueued, "", 0, nil
+		}
+		job.LeaseUntil = nil
+		m.jobs[id] = job
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (m *Memory) CancelQueuedClusterJob(ctx context.Context, id int) (models.ClusterJob, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok || job.Status != models.ClusterJobQueued {
+		return job, false, nil
+	}
+	now := time.Now()
+	job.Status, job.FinishedAt = models.ClusterJobCanceled, &now
+	m.jobs[id] = job
+	return job, true, nil
+}
+
+func (m *Memory) sortedEventIDs() []int {
+	return sortedKeys(m.events)
+}
+
+// eventWithClusters คืน event พร้อม cluster ที่ map อยู่ (เรียงและไม่ซ้ำแบบ ARRAY_AGG DISTINCT)
+func (m *Memory) eventWithClusters(id int) models.EventResponse {
+	ev := m.events[id]
+	ev.Clusters = []int{}
+	for _, cid := range sortedKeys(m.mappings) {
+		if _, ok := m.mappings[cid][id]; ok {
+			ev.Clusters = append(ev.Clusters, cid)
+		}
+	}
+	ev.Tags = append([]string{}, ev.Tags...)
+	sort.Strings(ev.Tags)
+	return ev
+}
+
+// matchesEntities ตรงกับเงื่อนไข EXISTS ของ event_entity ใน eventFilterSQL
+func (m *Memory) matchesEntities(eventID int, ef *models.EntityFilter) bool {
+	if ef == nil || len(ef.EntityIDs) == 0 {
+		return true
+	}
+	linked := make(map[int]struct{})
+	for _, link := range m.links {
+		if link.EventID != eventID {
+			continue
+		}
+		if len(ef.Roles) > 0 && !containsString(ef.Roles, link.Role) {
+			continue
+		}
+		linked[link.EntityID] = struct{}{}
+	}
+	for _, id := range ef.EntityIDs {
+		_, ok := linked[id]
+		if ef.Operator == "AND" && !ok {
+			return false
+		}
+		if ef.Operator != "AND" && ok {
+			return true
+		}
+	}
+	return ef.Operator == "AND"
+}
+
+// matchesTags ตรงกับ tag_name ILIKE '%tag%' (AND = ทุก tag, อื่น ๆ = OR)
+func matchesTags(eventTags []string, tf *models.TagFilter) bool {
+	if tf == nil || len(tf.Tags) == 0 {
+		return true
+	}
+	matches := func(tag string) bool {
+		needle := strings.ToLower(tag)
+		for _, t := range eventTags {
+			if strings.Contains(strings.ToLower(t), needle) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, tag := range tf.Tags {
+		ok := matches(tag)
+		if tf.Operator == "AND" && !ok {
+			return false
+		}
+		if tf.Operator != "AND" && ok {
+			return true
+		}
+	}
+	return tf.Operator == "AND"
+}
+
+func extendBounds(c *models.Cluster, ev models.EventResponse) {
+	minF := func(p **float64, v float64) {
+		if *p == nil || v < **p {
+			*p = &v
+		}
+	}
+	maxF := func(p **float64, v float64) {
+		if *p == nil || v > **p {
+			*p = &v
+		}
+	}
+	minF(&c.MinLat, ev.Lat)
+	maxF(&c.MaxLat, ev.Lat)
+	minF(&c.MinLon, ev.Lon)
+	maxF(&c.MaxLon, ev.Lon)
+	if c.MinDate == nil || ev.Date.Before(c.MinDate.Time) {
+		d := ev.Date
+		c.MinDate = &d
+	}
+	// max_date คือวันสุดท้ายของช่วงเวลาของ event (เหมือน trigger eventclustermap_bbox)
+	end := models.Date{Time: models.DateSpanEnd(ev.Date.Time, ev.DatePrecision, ev.EndDate.TimePtr())}
+	if c.MaxDate == nil || end.After(c.MaxDate.Time) {
+		c.MaxDate = &end
+	}
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+)
+
+// Postgres ใช้ฟังก์ชันใน repository (connection.DB)
+type Postgres struct{}
+
+func NewPostgres() *Postgres {
+	return &Postgres{}
+}
+
+func (Postgres) GetFilteredEvents(ctx context.Context, filter models.EventFilter) ([]models.EventResponse, error) {
+	return repository.GetFilteredEvents(ctx, filter)
+}
+
+func (Postgres) GetEventLatLonDate(ctx context.Context) ([]models.EventLatLonDate, error) {
+	return repository.GetEventLatLonDate(ctx)
+}
+
+func (Postgres) GetEventRecords(ctx context.Context) ([]models.EventRecord, error) {
+	return repository.GetEventRecords(ctx)
+}
+
+func (Postgres) GetHierarchicalClusters(ctx context.Context, query models.ClusterQuery) ([]models.Cluster, error) {
+	return repository.GetHierarchicalClusters(ctx, query)
+}
+
+func (Postgres) InsertClustersAndMappings(ctx context.Context, clusters []models.Cluster) error {
+	return repository.InsertClustersAndMappings(ctx, clusters)
+}
+
+func (Postgres) Ping(ctx context.Context) error {
+	return repository.Ping(ctx)
+}
+
+func (Postgres) GetDataCounts(ctx context.Context) (models.DataCounts, error) {
+	return repository.GetDataCounts(ctx)
+}
+
+func (Postgres) CreateClusterJob(ctx context.Context, job models.ClusterJob, maxQueued int) (models.ClusterJob, bool, error) {
+	return repository.CreateClusterJob(ctx, job, maxQueued)
+}
+
+func (Postgres) UpdateClusterJob(ctx context.Context, job models.ClusterJob) error {
+	return repository.UpdateClusterJob(ctx, job)
+}
+
+func (Postgres) GetClusterJob(ctx context.Context, id int) (models.ClusterJob, error) {
+	return repository.GetClusterJob(ctx, id)
+}
+
+func (Postgres) ListClusterJobs(ctx context.Context, query models.ClusterJobQuery) ([]models.ClusterJob, error) {
+	return repository.ListClusterJobs(ctx, query)
+}
+
+func (Postgres) ClaimClusterJob(ctx context.Context, owner string, lease time.Duration) (models.ClusterJob, bool, error) {
+	return repository.ClaimClusterJob(ctx, owner, lease)
+}
+
+func (Postgres) RenewClusterJobLease(ctx context.Context, id int, owner string, lease time.Duration) (bool, error) {
+	return repository.RenewClusterJobLease(ctx, id, owner, lease)
+}
+
+func (Postgres) RequeueExpiredClusterJobs(ctx context.Context, maxAttempts int) ([]models.ClusterJob, error) {
+	return repository.RequeueExpiredClusterJobs(ctx, maxAttempts)
+}
+
+func (Postgres) CancelQueuedClusterJob(ctx context.Context, id int) (models.ClusterJob, bool, error) {
+	return repository.CancelQueuedClusterJob(ctx, id)
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// snapshotTables คือตารางที่คัดลอกจาก Postgres ลง SQLite (เรียงตาม foreign key)
+// วันที่แปลงเป็นจำนวนวันนับจาก 1970-01-01 ฝั่ง Postgres ซึ่งรองรับปีก่อนคริสตกาล
+var snapshotTables = []struct {
+	table  string
+	cols   []string
+	source string
+}{
+	{"event", []string{"event_id", "event_name", "date", "date_precision", "end_date", "lat", "lon", "geometry", "image", "video", "description"},
+		`SELECT event_id, event_name, date - DATE '1970-01-01', date_precision, end_date - DATE '1970-01-01',
+		        lat, lon, geometry::text, image, video, description FROM event`},
+	{"tag", []string{"tag_id", "tag_name"}, `SELECT tag_id, tag_name FROM tag`},
+	{"eventtag", []string{"event_id", "tag_id"}, `SELECT event_id, tag_id FROM eventtag`},
+	{"cluster", []string{"cluster_id", "parent_cluster_id", "centroid_lat", "centroid_lon", "centroid_time_days", "level",
+		"min_lat", "max_lat", "min_lon", "max_lon", "min_date", "max_date"},
+		`SELECT cluster_id, parent_cluster_id, centroid_lat, centroid_lon, centroid_time_days::double precision, level,
+		        min_lat, max_lat, min_lon, max_lon, min_date - DATE '1970-01-01', max_date - DATE '1970-01-01' FROM cluster`},
+	{"eventclustermap", []string{"event_id", "cluster_id"}, `SELECT event_id, cluster_id FROM eventclustermap`},
+	{"event_entity", []string{"event_id", "entity_id", "role"}, `SELECT event_id, entity_id, role FROM event_entity`},
+}
+
+type SnapshotTable struct {
+	Table string
+	Rows  int
+}
+
+// Snapshot คัดลอกข้อมูล event/tag/cluster จาก Postgres ลงไฟล์ SQLite ที่ path
+// เขียนลงไฟล์ชั่วคราวก่อนแล้วค่อยแทนที่ ไฟล์เดิมจึงไม่เสียถ้าคัดลอกไม่สำเร็จ
+func Snapshot(ctx context.Context, src *pgxpool.Pool, path string) ([]SnapshotTable, error) {
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+
+	dst, err := OpenSQLite(tmp)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := copySnapshot(ctx, src, dst)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return counts, os.Rename(tmp, path)
+}
+
+func copySnapshot(ctx context.Context, src *pgxpool.Pool, dst *SQLite) ([]SnapshotTable, error) {
+	// Postgres ที่มาจาก Supabase เดิมอาจไม่มี foreign key ครบ จึงคัดลอกตามจริงโดยไม่ตรวจ
+	if _, err := dst.db.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return nil, err
+	}
+	tx, err := dst.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var counts []SnapshotTable
+	for _, t := range snapshotTables {
+		rows, err := src.Query(ctx, t.source)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", t.table, err)
+		}
+		insert := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, t.table, strings.Join(t.cols, ", "), placeholders(len(t.cols)))
+		n := 0
+		for rows.Next() {
+			values, err := rows.Values()
+			if err == nil {
+				_, err = tx.ExecContext(ctx, insert, values...)
+			}
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("copy %s: %w", t.table, err)
+			}
+			n++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("read %s: %w", t.table, err)
+		}
+		counts = append(counts, SnapshotTable{Table: t.table, Rows: n})
+	}
+	return counts, tx.Commit()
+}
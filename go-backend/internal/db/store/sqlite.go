@@ -0,0 +1,738 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema คือตาราง event/tag/cluster แบบเดียวกับ Postgres สำหรับโหมด offline
+// วันที่เก็บเป็นจำนวนวันนับจาก 1970-01-01 (INTEGER) เพื่อให้เทียบกันได้ถึงปีก่อนคริสตกาล
+// ซึ่งฟังก์ชัน date() ของ SQLite ไม่รองรับ ส่วน geometry เก็บเป็น GeoJSON (TEXT)
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS event (
+    event_id       INTEGER PRIMARY KEY,
+    event_name     TEXT,
+    date           INTEGER,
+    date_precision TEXT NOT NULL DEFAULT 'day',
+    end_date       INTEGER,
+    lat            REAL,
+    lon            REAL,
+    geometry       TEXT,
+    image          TEXT,
+    video          TEXT,
+    description    TEXT
+);
+CREATE INDEX IF NOT EXISTS event_date_idx ON event (date);
+
+CREATE TABLE IF NOT EXISTS tag (
+    tag_id   INTEGER PRIMARY KEY,
+    tag_name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS eventtag (
+    event_id INTEGER NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    tag_id   INTEGER NOT NULL REFERENCES tag(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS cluster (
+    cluster_id         INTEGER PRIMARY KEY,
+    parent_cluster_id  INTEGER,
+    centroid_lat       REAL NOT NULL,
+    centroid_lon       REAL NOT NULL,
+    centroid_time_days REAL NOT NULL,
+    level              INTEGER NOT NULL,
+    min_lat            REAL,
+    max_lat            REAL,
+    min_lon            REAL,
+    max_lon            REAL,
+    min_date           INTEGER,
+    max_date           INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS eventclustermap (
+    event_id   INTEGER NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    cluster_id INTEGER NOT NULL REFERENCES cluster(cluster_id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, cluster_id)
+);
+
+-- เหมือน trigger eventclustermap_bbox ใน Postgres (min/max ของ SQLite คืน NULL ถ้ามีค่า NULL จึงต้อง COALESCE)
+CREATE TRIGGER IF NOT EXISTS eventclustermap_bbox AFTER INSERT ON eventclustermap
+BEGIN
+    UPDATE cluster SET
+        min_lat  = min(COALESCE(min_lat,  (SELECT lat  FROM event WHERE event_id = NEW.event_id)), (SELECT lat  FROM event WHERE event_id = NEW.event_id)),
+        max_lat  = max(COALESCE(max_lat,  (SELECT lat  FROM event WHERE event_id = NEW.event_id)), (SELECT lat  FROM event WHERE event_id = NEW.event_id)),
+        min_lon  = min(COALESCE(min_lon,  (SELECT lon  FROM event WHERE event_id = NEW.event_id)), (SELECT lon  FROM event WHERE event_id = NEW.event_id)),
+        max_lon  = max(COALESCE(max_lon,  (SELECT lon  FROM event WHERE event_id = NEW.event_id)), (SELECT lon  FROM event WHERE event_id = NEW.event_id)),
+        min_date = min(COALESCE(min_date, (SELECT date FROM event WHERE event_id = NEW.event_id)), (SELECT date FROM event WHERE event_id = NEW.event_id)),
+        max_date = max(COALESCE(max_date, (SELECT date FROM event WHERE event_id = NEW.event_id)), (SELECT date FROM event WHERE event_id = NEW.event_id))
+    WHERE cluster_id = NEW.cluster_id;
+END;
+
+CREATE TABLE IF NOT EXISTS event_entity (
+    event_id  INTEGER NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    entity_id INTEGER NOT NULL,
+    role      TEXT NOT NULL DEFAULT 'participant',
+    PRIMARY KEY (event_id, entity_id, role)
+);
+
+-- งาน clustering แบบ asynchronous (migration 0009 ของ Postgres) เวลาเก็บเป็น Unix milliseconds
+CREATE TABLE IF NOT EXISTS cluster_job (
+    job_id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    status      TEXT NOT NULL DEFAULT 'queued',
+    engine      TEXT NOT NULL,
+    params      TEXT NOT NULL DEFAULT '{}',
+    stage       TEXT NOT NULL DEFAULT '',
+    progress    REAL NOT NULL DEFAULT 0,
+    result      TEXT,
+    error       TEXT,
+    attempts    INTEGER NOT NULL DEFAULT 0,
+    created_at  INTEGER NOT NULL,
+    started_at  INTEGER,
+    finished_at INTEGER,
+    owner       TEXT,
+    lease_until INTEGER
+);
+CREATE INDEX IF NOT EXISTS cluster_job_status_idx ON cluster_job (status, created_at);
+`
+
+// sqliteEventSelect คืน event พร้อม tag และ cluster (เรียงและไม่ซ้ำแบบ ARRAY_AGG DISTINCT) เป็น JSON array
+const sqliteEventSelect = `
+	SELECT
+		e.event_id,
+		COALESCE(e.event_name, ''),
+		e.date,
+		e.date_precision,
+		e.end_date,
+		e.lat,
+		e.lon,
+		e.geometry,
+		COALESCE(e.image, ''),
+		COALESCE(e.video, ''),
+		COALESCE(e.description, ''),
+		(SELECT json_group_array(tag_name) FROM (
+			SELECT DISTINCT t.tag_name FROM eventtag et JOIN tag t ON et.tag_id = t.tag_id
+			WHERE et.event_id = e.event_id ORDER BY t.tag_name)),
+		(SELECT json_group_array(cluster_id) FROM (
+			SELECT DISTINCT cluster_id FROM eventclustermap
+			WHERE event_id = e.event_id ORDER BY cluster_id))
+	FROM event e
+	WHERE e.date IS NOT NULL AND e.lat IS NOT NULL AND e.lon IS NOT NULL`
+
+// SQLite เก็บข้อมูลในไฟล์ SQLite ไฟล์เดียว สำหรับเครื่องที่ต่อ Supabase ไม่ได้ (เช่น kiosk ในพิพิธภัณฑ์)
+// รองรับเฉพาะ query ของ event/tag/cluster ใน EventStore และ ClusterStore และงาน clustering ใน ClusterJobStore
+type SQLite struct {
+	db *sql.DB
+}
+
+// SQLitePath คืน path ของไฟล์เมื่อ DATABASE_URL ใช้ scheme sqlite
+// เช่น sqlite://globe.db (relative) หรือ sqlite:///var/lib/globe/globe.db (absolute)
+func SQLitePath(dbURL string) (string, bool) {
+	if !strings.HasPrefix(dbURL, "sqlite:") {
+		return "", false
+	}
+	path := strings.TrimPrefix(strings.TrimPrefix(dbURL, "sqlite:"), "//")
+	return path, path != ""
+}
+
+// OpenSQLite เปิดไฟล์ (สร้างใหม่ถ้ายังไม่มี) และสร้างตารางที่ยังไม่มี
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// เขียนได้ทีละ connection เท่านั้น ใช้ connection เดียวกันทั้งหมดเพื่อไม่ให้ติด SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create sqlite schema: %w", err)
+	}
+	if err := addSQLiteColumns(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("update sqlite schema: %w", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+// sqliteAddedColumns คือ column ที่เพิ่มหลังจากตารางถูกสร้างแล้ว (CREATE TABLE IF NOT EXISTS ไม่เพิ่มให้ไฟล์เดิม)
+var sqliteAddedColumns = []struct{ table, column, decl string }{
+	{"cluster_job", "owner", "TEXT"},
+	{"cluster_job", "lease_until", "INTEGER"},
+}
+
+// addSQLiteColumns เพิ่ม column ใน sqliteAddedColumns ที่ไฟล์ยังไม่มี
+func addSQLiteColumns(db *sql.DB) error {
+	for _, c := range sqliteAddedColumns {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.decl)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLite) GetFilteredEvents(ctx context.Context, filter models.EventFilter) ([]models.EventResponse, error) {
+	conds, args := sqliteFilterSQL(filter)
+	events, err := s.queryEvents(ctx, sqliteEventSelect+conds+` ORDER BY e.date DESC, e.event_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// วันสุดท้ายของช่วงเวลา geometry radius และ area ตรวจใน Go (ผลเดียวกับ eventSpanEndSQL และ MatchesLocation)
+	start, end := filter.DateFilter.Bounds()
+	valid := events[:0]
+	for _, ev := range events {
+		if !models.DateSpanOverlaps(ev.Date.Time, ev.DatePrecision, ev.EndDate.TimePtr(), start, end) {
+			continue
+		}
+		if !filter.MatchesLocation(ev.Lat, ev.Lon, ev.Geometry) {
+			continue
+		}
+		valid = append(valid, ev)
+	}
+	return valid, nil
+}
+
+func (s *SQLite) GetEventLatLonDate(ctx context.Context) ([]models.EventLatLonDate, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT event_id, lat, lon, date, date_precision, end_date FROM event
+		 WHERE date IS NOT NULL AND lat IS NOT NULL AND lon IS NOT NULL
+		 ORDER BY event_id`)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.EventLatLonDate
+	for rows.Next() {
+		var id int
+		var lat, lon float64
+		var day int64
+		var precision string
+		var endDay sql.NullInt64
+		if err := rows.Scan(&id, &lat, &lon, &day, &precision, &endDay); err != nil {
+			return nil, err
+		}
+		events = append(events, models.NewEventLatLonDate(id, lat, lon, dayToDate(day), precision, nullDayToDate(endDay)))
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLite) GetEventRecords(ctx context.Context) ([]models.EventRecord, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT event_id, event_name, date, date_precision, end_date, lat, lon, image, video
+		 FROM event ORDER BY event_id`)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []models.EventRecord
+	for rows.Next() {
+		var r models.EventRecord
+		var day, endDay sql.NullInt64
+		if err := rows.Scan(&r.EventID, &r.EventName, &day, &r.Precision, &endDay, &r.Lat, &r.Lon, &r.Image, &r.Video); err != nil {
+			return nil, err
+		}
+		r.Date, r.EndDate = nullDayToDate(day), nullDayToDate(endDay)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLite) InsertClustersAndMappings(ctx context.Context, clusters []models.Cluster) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range clusters {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO cluster (cluster_id, parent_cluster_id, centroid_lat, centroid_lon, centroid_time_days, level)
+			 VALUES (?, ?, ?, ?, ?, ?)
+			 ON CONFLICT (cluster_id) DO NOTHING`,
+			c.ClusterID, c.ParentClusterID, c.CentroidLat, c.CentroidLon, c.CentroidTimeDays, c.Level)
+		if err != nil {
+			slog.ErrorContext(ctx, "Insert cluster failed", "err", err)
+			return err
+		}
+		for _, eventID := range c.EventIDs {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO eventclustermap (event_id, cluster_id) VALUES (?, ?)
+				 ON CONFLICT (event_id, cluster_id) DO NOTHING`, eventID, c.ClusterID)
+			if err != nil {
+				slog.ErrorContext(ctx, "Insert eventclustermap failed", "err", err)
+				return err
+			}
+		}
+		if err := extendSQLiteMaxDate(ctx, tx, c.ClusterID); err != nil {
+			slog.ErrorContext(ctx, "Update cluster max_date failed", "err", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// extendSQLiteMaxDate ตั้ง max_date ของ cluster เป็นวันสุดท้ายของช่วงเวลาของ event ที่อยู่ใน cluster
+// (trigger ใช้แค่ date เพราะ SQLite คำนวณ precision แบบปฏิทินไม่ได้ ผลเดียวกับ event_span_end ของ Postgres)
+func extendSQLiteMaxDate(ctx context.Context, tx *sql.Tx, clusterID int) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT e.date, e.date_precision, e.end_date FROM eventclustermap m
+		 JOIN event e ON e.event_id = m.event_id
+		 WHERE m.cluster_id = ? AND e.date IS NOT NULL`, clusterID)
+	if err != nil {
+		return err
+	}
+	var maxDay sql.NullInt64
+	for rows.Next() {
+		var day int64
+		var precision string
+		var endDay sql.NullInt64
+		if err := rows.Scan(&day, &precision, &endDay); err != nil {
+			rows.Close()
+			return err
+		}
+		end := dateToDay(models.DateSpanEnd(dayToDate(day).Time, precision, nullDayToDate(endDay).TimePtr()))
+		if !maxDay.Valid || end > maxDay.Int64 {
+			maxDay = sql.NullInt64{Int64: end, Valid: true}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || !maxDay.Valid {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE cluster SET max_date = ? WHERE cluster_id = ?`, maxDay, clusterID)
+	return err
+}
+
+func (s *SQLite) GetHierarchicalClusters(ctx context.Context, query models.ClusterQuery) ([]models.Cluster, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			c.cluster_id, c.parent_cluster_id, c.centroid_lat, c.centroid_lon, c.centroid_time_days, c.level,
+			(SELECT json_group_array(event_id) FROM (
+				SELECT event_id FROM eventclustermap WHERE cluster_id = c.cluster_id ORDER BY event_id)),
+			c.min_lat, c.max_lat, c.min_lon, c.max_lon, c.min_date, c.max_date
+		FROM cluster c
+		WHERE c.level <= ?
+		ORDER BY c.cluster_id`, query.MaxLevel)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clusters []models.Cluster
+	for rows.Next() {
+		var c models.Cluster
+		var eventIDs string
+		var minDay, maxDay sql.NullInt64
+		if err := rows.Scan(
+			&c.ClusterID, &c.ParentClusterID, &c.CentroidLat, &c.CentroidLon, &c.CentroidTimeDays, &c.Level,
+			&eventIDs, &c.MinLat, &c.MaxLat, &c.MinLon, &c.MaxLon, &minDay, &maxDay,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(eventIDs), &c.EventIDs); err != nil {
+			return nil, err
+		}
+		c.MinDate, c.MaxDate = nullDayToDate(minDay), nullDayToDate(maxDay)
+		clusters = append(clusters, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	events, err := s.queryEvents(ctx, sqliteEventSelect+`
+		AND e.event_id IN (
+			SELECT m.event_id FROM eventclustermap m JOIN cluster c ON c.cluster_id = m.cluster_id
+			WHERE c.level <= ?)`, query.MaxLevel)
+	if err != nil {
+		return nil, err
+	}
+	details := make(map[int]models.EventResponse, len(events))
+	for _, ev := range events {
+		details[ev.EventID] = ev
+	}
+	return repository.FilterClusterHierarchy(clusters, details, query), nil
+}
+
+func (s *SQLite) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLite) GetDataCounts(ctx context.Context) (models.DataCounts, error) {
+	var counts models.DataCounts
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM event),
+			(SELECT COUNT(DISTINCT event_id) FROM eventclustermap),
+			(SELECT COUNT(*) FROM cluster)
+	`).Scan(&counts.Events, &counts.ClusteredEvents, &counts.Clusters)
+	return counts, err
+}
+
+const sqliteClusterJobColumns = `job_id, status, engine, params, stage, progress, result, COALESCE(error, ''),
+	attempts, created_at, started_at, finished_at, COALESCE(owner, ''), lease_until`
+
+// CreateClusterJob เหมือน repository.CreateClusterJob (SQLite เขียนได้ทีละ transaction คำสั่งเดียวจึง atomic อยู่แล้ว)
+func (s *SQLite) CreateClusterJob(ctx context.Context, job models.ClusterJob, maxQueued int) (models.ClusterJob, bool, error) {
+	params, err := json.Marshal(job.Params)
+	if err != nil {
+		return job, false, err
+	}
+	job, err = scanSQLiteClusterJob(s.db.QueryRowContext(ctx,
+		`INSERT INTO cluster_job (status, engine, params, created_at)
+		 SELECT ?, ?, ?, ?
+		 WHERE (SELECT COUNT(*) FROM cluster_job WHERE status = ?) < ?
+		 RETURNING `+sqliteClusterJobColumns,
+		job.Status, job.Engine, string(params), time.Now().UnixMilli(), models.ClusterJobQueued, maxQueued))
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Insert cluster_job failed", "err", err)
+		return job, false, err
+	}
+	return job, true, nil
+}
+
+func (s *SQLite) UpdateClusterJob(ctx context.Context, job models.ClusterJob) error {
+	var result sql.NullString
+	if job.Result != nil {
+		b, err := json.Marshal(job.Result)
+		if err != nil {
+			return err
+		}
+		result = sql.NullString{String: string(b), Valid: true}
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE cluster_job SET
+			status = ?1, stage = ?2, progress = ?3, result = ?4, error = NULLIF(?5, ''),
+			attempts = ?6, started_at = ?7, finished_at = ?8,
+			lease_until = CASE WHEN ?1 = ?11 THEN lease_until END
+		WHERE job_id = ?9 AND status = ?11 AND owner = ?10`,
+		job.Status, job.Stage, job.Progress, result, job.Error,
+		job.Attempts, timeToMillis(job.StartedAt), timeToMillis(job.FinishedAt), job.JobID, job.Owner, models.ClusterJobRunning)
+	if err != nil {
+		slog.ErrorContext(ctx, "Update cluster_job failed", "err", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return repository.ErrClusterJobLeaseLost
+	}
+	return nil
+}
+
+func (s *SQLite) GetClusterJob(ctx context.Context, id int) (models.ClusterJob, error) {
+	job, err := scanSQLiteClusterJob(s.db.QueryRowContext(ctx,
+		`SELECT `+sqliteClusterJobColumns+` FROM cluster_job WHERE job_id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, repository.ErrClusterJobNotFound
+	}
+	return job, err
+}
+
+func (s *SQLite) ListClusterJobs(ctx context.Context, query models.ClusterJobQuery) ([]models.ClusterJob, error) {
+	order := "DESC"
+	if query.OldestFirst {
+		order = "ASC"
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = -1 // ไม่จำกัด
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT `+sqliteClusterJobColumns+` FROM cluster_job
+		WHERE (? = '' OR status = ?)
+		ORDER BY created_at %[1]s, job_id %[1]s
+		LIMIT ?`, order), query.Status, query.Status, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.ClusterJob{}
+	for rows.Next() {
+		job, err := scanSQLiteClusterJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimClusterJob เหมือน repository.ClaimClusterJob (SQLite เขียนได้ทีละ transaction จึงไม่ต้องใช้ SKIP LOCKED)
+func (s *SQLite) ClaimClusterJob(ctx context.Context, owner string, lease time.Duration) (models.ClusterJob, bool, error) {
+	now := time.Now()
+	job, err := scanSQLiteClusterJob(s.db.QueryRowContext(ctx, `
+		UPDATE cluster_job SET
+			status = ?, stage = '', progress = 0, error = NULL,
+			attempts = attempts + 1, started_at = ?, owner = ?, lease_until = ?
+		WHERE job_id = (
+			SELECT job_id FROM cluster_job
+			WHERE status = ?
+			ORDER BY created_at, job_id
+			LIMIT 1
+		)
+		RETURNING `+sqliteClusterJobColumns,
+		models.ClusterJobRunning, now.UnixMilli(), owner, now.Add(lease).UnixMilli(), models.ClusterJobQueued))
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Claim cluster_job failed", "err", err)
+		return job, false, err
+	}
+	return job, true, nil
+}
+
+func (s *SQLite) RenewClusterJobLease(ctx context.Context, id int, owner string, lease time.Duration) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE cluster_job SET lease_until = ? WHERE job_id = ? AND status = ? AND owner = ?`,
+		time.Now().Add(lease).UnixMilli(), id, models.ClusterJobRunning, owner)
+	if err != nil {
+		slog.ErrorContext(ctx, "Renew cluster_job lease failed", "err", err)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RequeueExpiredClusterJobs เหมือน repository.RequeueExpiredClusterJobs
+func (s *SQLite) RequeueExpiredClusterJobs(ctx context.Context, maxAttempts int) ([]models.ClusterJob, error) {
+	now := time.Now().UnixMilli()
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE cluster_job SET
+			status      = CASE WHEN attempts >= ?3 THEN ?4 ELSE ?2 END,
+			error       = CASE WHEN attempts >= ?3 THEN 'interrupted ' || attempts || ' times' END,
+			finished_at = CASE WHEN attempts >= ?3 THEN ?5 END,
+			stage       = CASE WHEN attempts >= ?3 THEN stage ELSE '' END,
+			progress    = CASE WHEN attempts >= ?3 THEN progress ELSE 0 END,
+			started_at  = CASE WHEN attempts >= ?3 THEN started_at END,
+			lease_until = NULL
+		WHERE status = ?1 AND (lease_until IS NULL OR lease_until < ?5)
+		RETURNING `+sqliteClusterJobColumns,
+		models.ClusterJobRunning, models.ClusterJobQueued, maxAttempts, models.ClusterJobFailed, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "Requeue cluster_job failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.ClusterJob{}
+	for rows.Next() {
+		job, err := scanSQLiteClusterJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *SQLite) CancelQueuedClusterJob(ctx context.Context, id int) (models.ClusterJob, bool, error) {
+	job, err := scanSQLiteClusterJob(s.db.QueryRowContext(ctx, `
+		UPDATE cluster_job SET status = ?, finished_at = ?
+		WHERE job_id = ? AND status = ?
+		RETURNING `+sqliteClusterJobColumns, models.ClusterJobCanceled, time.Now().UnixMilli(), id, models.ClusterJobQueued))
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Cancel cluster_job failed", "err", err)
+		return job, false, err
+	}
+	return job, true, nil
+}
+
+func scanSQLiteClusterJob(row interface{ Scan(...any) error }) (models.ClusterJob, error) {
+	var job models.ClusterJob
+	var params string
+	var result sql.NullString
+	var created int64
+	var started, finished, leaseUntil sql.NullInt64
+	if err := row.Scan(&job.JobID, &job.Status, &job.Engine, &params, &job.Stage, &job.Progress, &result, &job.Error,
+		&job.Attempts, &created, &started, &finished, &job.Owner, &leaseUntil); err != nil {
+		return job, err
+	}
+	if err := json.Unmarshal([]byte(params), &job.Params); err != nil {
+		return job, err
+	}
+	if result.Valid {
+		job.Result = &models.ClusterJobResult{}
+		if err := json.Unmarshal([]byte(result.String), job.Result); err != nil {
+			return job, err
+		}
+	}
+	job.CreatedAt = time.UnixMilli(created)
+	job.StartedAt, job.FinishedAt, job.LeaseUntil = millisToTime(started), millisToTime(finished), millisToTime(leaseUntil)
+	return job, nil
+}
+
+func timeToMillis(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixMilli(), Valid: true}
+}
+
+func millisToTime(ms sql.NullInt64) *time.Time {
+	if !ms.Valid {
+		return nil
+	}
+	t := time.UnixMilli(ms.Int64)
+	return &t
+}
+
+func (s *SQLite) queryEvents(ctx context.Context, query string, args ...interface{}) ([]models.EventResponse, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Query failed", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.EventResponse
+	for rows.Next() {
+		var ev models.EventResponse
+		var day int64
+		var endDay sql.NullInt64
+		var geometry sql.NullString
+		var tags, clusters string
+		if err := rows.Scan(
+			&ev.EventID, &ev.EventName, &day, &ev.DatePrecision, &endDay, &ev.Lat, &ev.Lon, &geometry,
+			&ev.Image, &ev.Video, &ev.Description, &tags, &clusters,
+		); err != nil {
+			return nil, err
+		}
+		ev.Date, ev.EndDate = dayToDate(day), nullDayToDate(endDay)
+		if geometry.Valid {
+			ev.Geometry = &models.Geometry{}
+			if err := json.Unmarshal([]byte(geometry.String), ev.Geometry); err != nil {
+				return nil, fmt.Errorf("event %d geometry: %w", ev.EventID, err)
+			}
+		}
+		if err := json.Unmarshal([]byte(tags), &ev.Tags); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(clusters), &ev.Clusters); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// sqliteFilterSQL คือ eventFilterSQL ของ Postgres ในรูปแบบ SQLite (placeholder ?)
+// LIKE ของ SQLite ไม่สนตัวพิมพ์เล็กใหญ่ (ASCII) จึงใช้แทน ILIKE ได้
+// ส่วนวันสุดท้ายของช่วงเวลาตรวจใน Go เพราะ date() ของ SQLite ใช้กับปีติดลบไม่ได้
+func sqliteFilterSQL(filter models.EventFilter) (string, []interface{}) {
+	query := ""
+	args := []interface{}{}
+
+	if tf := filter.TagFilter; tf != nil && len(tf.Tags) > 0 {
+		const exists = " AND EXISTS (SELECT 1 FROM eventtag et2 JOIN tag t2 ON et2.tag_id = t2.tag_id WHERE et2.event_id = e.event_id AND (%s))"
+		if tf.Operator == "AND" {
+			for _, tag := range tf.Tags {
+				query += fmt.Sprintf(exists, "t2.tag_name LIKE ?")
+				args = append(args, "%"+tag+"%")
+			}
+		} else {
+			conds := make([]string, len(tf.Tags))
+			for i, tag := range tf.Tags {
+				conds[i] = "t2.tag_name LIKE ?"
+				args = append(args, "%"+tag+"%")
+			}
+			query += fmt.Sprintf(exists, strings.Join(conds, " OR "))
+		}
+	}
+
+	if _, end := filter.DateFilter.Bounds(); end != nil {
+		query += " AND e.date <= ?"
+		args = append(args, dateToDay(*end))
+	}
+
+	if v := filter.Viewport; v != nil {
+		lonCond := "e.lon BETWEEN ? AND ?"
+		if v.West > v.East {
+			lonCond = "(e.lon >= ? OR e.lon <= ?)" // ข้าม antimeridian
+		}
+		query += " AND (e.geometry IS NOT NULL OR (e.lat BETWEEN ? AND ? AND " + lonCond + "))"
+		args = append(args, v.South, v.North, v.West, v.East)
+	}
+
+	if ef := filter.EntityFilter; ef != nil && len(ef.EntityIDs) > 0 {
+		roleCond := ""
+		var roleArgs []interface{}
+		if len(ef.Roles) > 0 {
+			roleCond = " AND ee.role IN (" + placeholders(len(ef.Roles)) + ")"
+			for _, r := range ef.Roles {
+				roleArgs = append(roleArgs, r)
+			}
+		}
+		const exists = " AND EXISTS (SELECT 1 FROM event_entity ee WHERE ee.event_id = e.event_id AND ee.entity_id %s%s)"
+		if ef.Operator == "AND" {
+			for _, id := range ef.EntityIDs {
+				query += fmt.Sprintf(exists, "= ?", roleCond)
+				args = append(append(args, id), roleArgs...)
+			}
+		} else {
+			query += fmt.Sprintf(exists, "IN ("+placeholders(len(ef.EntityIDs))+")", roleCond)
+			for _, id := range ef.EntityIDs {
+				args = append(args, id)
+			}
+			args = append(args, roleArgs...)
+		}
+	}
+
+	return query, args
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// dateToDay แปลงวันที่เป็นจำนวนวันนับจาก 1970-01-01 (ค่าที่เก็บในคอลัมน์วันที่ของ SQLite)
+func dateToDay(t time.Time) int64 {
+	return int64(models.DayNumber(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)))
+}
+
+func dayToDate(day int64) models.Date {
+	return models.Date{Time: time.Unix(day*86400, 0).UTC()}
+}
+
+func nullDayToDate(day sql.NullInt64) *models.Date {
+	if !day.Valid {
+		return nil
+	}
+	d := dayToDate(day.Int64)
+	return &d
+}
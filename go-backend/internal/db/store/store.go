@@ -0,0 +1,67 @@
+// Package store แยก handler ออกจาก connection.DB ด้วย interface
+// เพื่อให้สลับระหว่าง Postgres, SQLite (โหมด offline) และ in-memory (สำหรับทดสอบ handler โดยไม่ต้องมีฐานข้อมูล) ได้
+package store
+
+import (
+	"context"
+	"time"
+
+	"globe/internal/db/models"
+)
+
+// EventStore อ่านข้อมูล event
+type EventStore interface {
+	// GetFilteredEvents คืน event ที่ผ่าน tag/date/viewport/entity filter เรียงตามวันที่ล่าสุดก่อน
+	GetFilteredEvents(ctx context.Context, filter models.EventFilter) ([]models.EventResponse, error)
+	// GetEventLatLonDate คืนตำแหน่งและเวลา (จุดกึ่งกลางของช่วง) ของทุก event สำหรับ clustering
+	GetEventLatLonDate(ctx context.Context) ([]models.EventLatLonDate, error)
+	// GetEventRecords คืนทุก event แบบ nullable สำหรับตรวจคุณภาพข้อมูล
+	GetEventRecords(ctx context.Context) ([]models.EventRecord, error)
+}
+
+// ClusterStore อ่านและบันทึกผล clustering
+type ClusterStore interface {
+	// GetHierarchicalClusters คืน cluster ที่ทับกับ viewport/ช่วงวันที่ โดย leaf มี event ที่ผ่าน filter
+	GetHierarchicalClusters(ctx context.Context, query models.ClusterQuery) ([]models.Cluster, error)
+	// InsertClustersAndMappings บันทึก cluster และ mapping กับ event (ข้ามตัวที่มีอยู่แล้ว) แบบ atomic
+	// ถ้าล้มเหลวหรือ ctx ถูกยกเลิกจะไม่มีอะไรถูกบันทึก จึงเรียกซ้ำกับ cluster ชุดเดิมได้
+	InsertClustersAndMappings(ctx context.Context, clusters []models.Cluster) error
+}
+
+// StatusStore รายงานสถานะของ backend สำหรับ /readyz และ /api/status
+type StatusStore interface {
+	// Ping ตรวจว่ายังเชื่อมต่อฐานข้อมูลได้
+	Ping(ctx context.Context) error
+	// GetDataCounts นับ event และ cluster
+	GetDataCounts(ctx context.Context) (models.DataCounts, error)
+}
+
+// ClusterJobStore เก็บสถานะของงาน clustering แบบ asynchronous เพื่อให้ประวัติไม่หายตอน restart
+type ClusterJobStore interface {
+	// CreateClusterJob บันทึกงานใหม่และคืนงานพร้อม job_id และ created_at
+	// การนับคิวกับการเพิ่มเป็น atomic: false ถ้ามีงานในคิวครบ maxQueued แล้ว
+	CreateClusterJob(ctx context.Context, job models.ClusterJob, maxQueued int) (models.ClusterJob, bool, error)
+	// UpdateClusterJob บันทึกสถานะ ความคืบหน้า และผลของงานที่ job.Owner กำลังทำ
+	// (repository.ErrClusterJobLeaseLost ถ้างานไม่ได้ running โดย job.Owner แล้ว)
+	UpdateClusterJob(ctx context.Context, job models.ClusterJob) error
+	// GetClusterJob คืนงานตาม id (repository.ErrClusterJobNotFound ถ้าไม่มีงาน)
+	GetClusterJob(ctx context.Context, id int) (models.ClusterJob, error)
+	// ListClusterJobs คืนงานตามสถานะเรียงตามเวลาที่สร้าง
+	ListClusterJobs(ctx context.Context, query models.ClusterJobQuery) ([]models.ClusterJob, error)
+	// ClaimClusterJob เปลี่ยนงานที่เก่าที่สุดในคิวเป็น running ของ owner พร้อม lease แบบ atomic (false ถ้าคิวว่าง)
+	ClaimClusterJob(ctx context.Context, owner string, lease time.Duration) (models.ClusterJob, bool, error)
+	// RenewClusterJobLease ต่อ lease ของงานที่ owner กำลังทำ (false ถ้างานไม่ได้เป็นของ owner แล้ว)
+	RenewClusterJobLease(ctx context.Context, id int, owner string, lease time.Duration) (bool, error)
+	// RequeueExpiredClusterJobs นำงาน running ที่ lease หมดแล้วกลับเข้าคิว (หรือให้ล้มเหลวเมื่อครบ maxAttempts) แบบ atomic
+	RequeueExpiredClusterJobs(ctx context.Context, maxAttempts int) ([]models.ClusterJob, error)
+	// CancelQueuedClusterJob เปลี่ยนงานเป็น canceled แบบ atomic เฉพาะเมื่อยังอยู่ในคิว (false ถ้าไม่ได้อยู่ในคิว)
+	CancelQueuedClusterJob(ctx context.Context, id int) (models.ClusterJob, bool, error)
+}
+
+// Store คือ backend ที่ใช้ได้ทุกบทบาท (Postgres, SQLite, Memory)
+type Store interface {
+	EventStore
+	ClusterStore
+	StatusStore
+	ClusterJobStore
+}
@@ -0,0 +1,230 @@
+package geo
+
+import "math"
+
+// EarthRadiusKm คือรัศมีเฉลี่ยของโลก (กิโลเมตร)
+const EarthRadiusKm = 6371.0088
+
+// ValidCoordinates ตรวจว่า lat/lon เป็นตัวเลขจริงและอยู่ในช่วงที่ถูกต้อง
+func ValidCoordinates(lat, lon float64) bool {
+	if math.IsNaN(lat) || math.IsNaN(lon) || math.IsInf(lat, 0) || math.IsInf(lon, 0) {
+		return false
+	}
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// HaversineKm returns the great-circle distance between two lat/lon points in kilometres.
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Rect คือกรอบ lat/lon (เช่น viewport) ถ้า West > East แปลว่ากรอบข้ามเส้น antimeridian
+type Rect struct {
+	South, North, West, East float64
+}
+
+// Split แยกกรอบที่ข้าม antimeridian ออกเป็นสองกรอบที่ไม่ข้าม
+func (r Rect) Split() []Rect {
+	if r.West <= r.East {
+		return []Rect{r}
+	}
+	return []Rect{
+		{South: r.South, North: r.North, West: r.West, East: 180},
+		{South: r.South, North: r.North, West: -180, East: r.East},
+	}
+}
+
+// ContainsPoint ตรวจว่าจุด (lat, lon) อยู่ในกรอบ
+func (r Rect) ContainsPoint(lat, lon float64) bool {
+	for _, p := range r.Split() {
+		if lat >= p.South && lat <= p.North && lon >= p.West && lon <= p.East {
+			return true
+		}
+	}
+	return false
+}
+
+// IntersectsPath ตรวจว่าเส้นทาง (พิกัด [lon, lat]) ผ่านกรอบหรือไม่
+func (r Rect) IntersectsPath(path [][2]float64) bool {
+	for _, p := range r.Split() {
+		for i, c := range path {
+			if p.containsXY(c[0], c[1]) {
+				return true
+			}
+			if i > 0 && p.crossesSegment(path[i-1], c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IntersectsPolygon ตรวจว่า polygon (rings พิกัด [lon, lat], ring แรกคือขอบนอก) ทับกับกรอบหรือไม่
+func (r Rect) IntersectsPolygon(rings [][][2]float64) bool {
+	if len(rings) == 0 {
+		return false
+	}
+	for _, ring := range rings {
+		if r.IntersectsPath(ring) {
+			return true
+		}
+	}
+	// กรอบอยู่ภายใน polygon ทั้งหมด: มุมของกรอบอยู่ใน polygon
+	for _, p := range r.Split() {
+		if PolygonContains(rings, p.West, p.South) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rect) containsXY(x, y float64) bool {
+	return y >= r.South && y <= r.North && x >= r.West && x <= r.East
+}
+
+// crossesSegment ตรวจว่าส่วนของเส้นตรง a-b ตัดขอบของกรอบ (r ต้องไม่ข้าม antimeridian)
+func (r Rect) crossesSegment(a, b [2]float64) bool {
+	corners := [4][2]float64{
+		{r.West, r.South}, {r.East, r.South}, {r.East, r.North}, {r.West, r.North},
+	}
+	for i := range corners {
+		if segmentsIntersect(a, b, corners[i], corners[(i+1)%4]) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolygonContains ตรวจว่าจุด (x=lon, y=lat) อยู่ใน polygon ด้วยวิธี ray casting (นับรูใน ring ถัดไป)
+func PolygonContains(rings [][][2]float64, x, y float64) bool {
+	inside := false
+	for _, ring := range rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			xi, yi := ring[i][0], ring[i][1]
+			xj, yj := ring[j][0], ring[j][1]
+			if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func segmentsIntersect(p1, p2, p3, p4 [2]float64) bool {
+	d1 := cross(p3, p4, p1)
+	d2 := cross(p3, p4, p2)
+	d3 := cross(p1, p2, p3)
+	d4 := cross(p1, p2, p4)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(p3, p4, p1)) || (d2 == 0 && onSegment(p3, p4, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, p3)) || (d4 == 0 && onSegment(p1, p2, p4))
+}
+
+func cross(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+func onSegment(a, b, p [2]float64) bool {
+	return math.Min(a[0], b[0]) <= p[0] && p[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= p[1] && p[1] <= math.Max(a[1], b[1])
+}
+
+// RadiusBounds คืนกรอบ lat/lon ที่ครอบวงกลมรัศมี km รอบจุด (lat, lon)
+// ถ้าวงกลมครอบขั้วโลกหรือกว้างเกินครึ่งโลก กรอบจะครอบทุก longitude
+func RadiusBounds(lat, lon, km float64) Rect {
+	dLat := km / (EarthRadiusKm * math.Pi / 180)
+	r := Rect{South: math.Max(-90, lat-dLat), North: math.Min(90, lat+dLat), West: -180, East: 180}
+	if r.South == -90 || r.North == 90 {
+		return r
+	}
+	// ระยะ longitude ที่กว้างที่สุดของวงกลมบนทรงกลม
+	sinRatio := math.Sin(km/EarthRadiusKm) / math.Cos(lat*math.Pi/180)
+	if sinRatio >= 1 || km/EarthRadiusKm >= math.Pi/2 {
+		return r
+	}
+	dLon := math.Asin(sinRatio) * 180 / math.Pi
+	r.West, r.East = wrapLon(lon-dLon), wrapLon(lon+dLon)
+	return r
+}
+
+// PathBounds คืนกรอบที่ครอบทุกจุด (พิกัด [lon, lat]) โดยไม่ข้าม antimeridian
+func PathBounds(coords [][2]float64) Rect {
+	r := Rect{South: 90, North: -90, West: 180, East: -180}
+	for _, c := range coords {
+		r.West, r.East = math.Min(r.West, c[0]), math.Max(r.East, c[0])
+		r.South, r.North = math.Min(r.South, c[1]), math.Max(r.North, c[1])
+	}
+	return r
+}
+
+// DistanceToPathKm คืนระยะสั้นที่สุดจากจุด (lat, lon) ถึงเส้นทาง (พิกัด [lon, lat])
+// ระยะถึงแต่ละช่วงของเส้นคำนวณบนระนาบ equirectangular รอบจุด จึงแม่นเมื่อระยะไม่เกินหลักร้อยกิโลเมตร
+func DistanceToPathKm(path [][2]float64, lat, lon float64) float64 {
+	kmPerDeg := EarthRadiusKm * math.Pi / 180
+	cosLat := math.Cos(lat * math.Pi / 180)
+	project := func(c [2]float64) [2]float64 {
+		return [2]float64{wrapLon(c[0]-lon) * cosLat * kmPerDeg, (c[1] - lat) * kmPerDeg}
+	}
+
+	best := math.Inf(1)
+	for i, c := range path {
+		best = math.Min(best, HaversineKm(lat, lon, c[1], c[0]))
+		if i == 0 {
+			continue
+		}
+		a, b := project(path[i-1]), project(c)
+		dx, dy := b[0]-a[0], b[1]-a[1]
+		if l2 := dx*dx + dy*dy; l2 > 0 {
+			t := math.Max(0, math.Min(1, -(a[0]*dx+a[1]*dy)/l2))
+			best = math.Min(best, math.Hypot(a[0]+t*dx, a[1]+t*dy))
+		}
+	}
+	return best
+}
+
+// PathIntersectsPolygon ตรวจว่าเส้นทางมีจุดอยู่ใน polygon หรือตัดขอบของ polygon
+func PathIntersectsPolygon(path [][2]float64, rings [][][2]float64) bool {
+	for i, c := range path {
+		if PolygonContains(rings, c[0], c[1]) {
+			return true
+		}
+		if i == 0 {
+			continue
+		}
+		for _, ring := range rings {
+			for j := 1; j < len(ring); j++ {
+				if segmentsIntersect(path[i-1], c, ring[j-1], ring[j]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// PolygonsIntersect ตรวจว่า polygon สองรูปทับกัน: ขอบตัดกันหรือรูปหนึ่งอยู่ในอีกรูป
+func PolygonsIntersect(a, b [][][2]float64) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	for _, ring := range a {
+		if PathIntersectsPolygon(ring, b) {
+			return true
+		}
+	}
+	return len(b[0]) > 0 && PolygonContains(a, b[0][0][0], b[0][0][1])
+}
+
+// wrapLon ปรับ longitude ให้อยู่ในช่วง [-180, 180)
+func wrapLon(lon float64) float64 {
+	return math.Mod(math.Mod(lon+180, 360)+360, 360) - 180
+}
@@ -0,0 +1,102 @@
+package geo
+
+import (
+	"math"
+	"strings"
+)
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeohashPrecision คือความยาว geohash ที่เก็บต่อ event (9 ตัวอักษร ≈ 5 ม.)
+const GeohashPrecision = 9
+
+// GeohashEncode คืน geohash ของจุด lat/lon ยาว precision ตัวอักษร
+func GeohashEncode(lat, lon float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+
+	var sb strings.Builder
+	even := true // bit คู่ = lon, bit คี่ = lat
+	bit, ch := 0, 0
+	for sb.Len() < precision {
+		if even {
+			mid := (lonRange[0] + lonRange[1]) / 2
+			if lon >= mid {
+				ch = ch<<1 | 1
+				lonRange[0] = mid
+			} else {
+				ch <<= 1
+				lonRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				latRange[0] = mid
+			} else {
+				ch <<= 1
+				latRange[1] = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			sb.WriteByte(geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// GeohashCellSize คืนขนาดของ cell (องศา lat, องศา lon) ที่ความยาว precision
+func GeohashCellSize(precision int) (float64, float64) {
+	lonBits := (5*precision + 1) / 2
+	latBits := 5 * precision / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+// GeohashNeighbors คืน geohash ของ cell รอบจุด lat/lon 3x3 (รวม cell ของจุดเอง ไม่มีตัวซ้ำ)
+// ทุกจุดที่ห่างจากจุดกลางไม่เกิน GeohashCoverageKm จะอยู่ใน cell เหล่านี้
+func GeohashNeighbors(lat, lon float64, precision int) []string {
+	latSize, lonSize := GeohashCellSize(precision)
+	center := GeohashEncode(lat, lon, precision)
+
+	// ใช้จุดกึ่งกลางของ cell เพื่อให้ขยับ ±1 cell ได้พอดี
+	cLat, cLon := geohashCellCenter(lat, lon, latSize, lonSize)
+	seen := map[string]struct{}{center: {}}
+	out := []string{center}
+	for _, dLat := range []float64{-1, 0, 1} {
+		for _, dLon := range []float64{-1, 0, 1} {
+			nLat := cLat + dLat*latSize
+			if nLat > 90 || nLat < -90 {
+				continue // เลยขั้วโลก
+			}
+			nLon := cLon + dLon*lonSize
+			if nLon >= 180 {
+				nLon -= 360
+			} else if nLon < -180 {
+				nLon += 360
+			}
+			h := GeohashEncode(nLat, nLon, precision)
+			if _, ok := seen[h]; !ok {
+				seen[h] = struct{}{}
+				out = append(out, h)
+			}
+		}
+	}
+	return out
+}
+
+// GeohashCoverageKm คือระยะที่รับประกันว่าครอบคลุมโดย GeohashNeighbors (ระยะหนึ่ง cell จากจุดกลาง)
+// ใกล้ขั้วโลกความกว้างของ cell จะแคบลงจึงใช้ cos ของ lat ขอบนอกสุด
+func GeohashCoverageKm(lat float64, precision int) float64 {
+	latSize, lonSize := GeohashCellSize(precision)
+	kmPerDeg := EarthRadiusKm * math.Pi / 180
+	edgeLat := math.Min(90, math.Abs(lat)+2*latSize)
+	return math.Min(latSize*kmPerDeg, lonSize*kmPerDeg*math.Cos(edgeLat*math.Pi/180))
+}
+
+func geohashCellCenter(lat, lon, latSize, lonSize float64) (float64, float64) {
+	cLat := (math.Floor((lat+90)/latSize)+0.5)*latSize - 90
+	cLon := (math.Floor((lon+180)/lonSize)+0.5)*lonSize - 180
+	return math.Min(cLat, 90-latSize/2), math.Min(cLon, 180-lonSize/2)
+}
@@ -0,0 +1,61 @@
+package geo
+
+import "math"
+
+// Grid แบ่งผิวโลกเป็น cell ขนาด CellSize องศา
+// ถ้า EqualArea เป็น true แถวจะแบ่งตาม sin(lat) (Lambert cylindrical equal-area)
+// ทำให้ทุก cell มีพื้นที่เท่ากัน แต่ความสูงของ cell (องศา lat) จะมากขึ้นใกล้ขั้วโลก
+type Grid struct {
+	CellSize  float64
+	EqualArea bool
+}
+
+func (g Grid) Rows() int {
+	return int(math.Round(180 / g.CellSize))
+}
+
+func (g Grid) Cols() int {
+	return int(math.Round(360 / g.CellSize))
+}
+
+// Cell คืน (row, col) ของจุด lat/lon โดย row 0 อยู่ขั้วโลกใต้ และ col 0 อยู่ที่ lon -180
+func (g Grid) Cell(lat, lon float64) (int, int) {
+	rows, cols := g.Rows(), g.Cols()
+	var y float64
+	if g.EqualArea {
+		y = (math.Sin(lat*math.Pi/180) + 1) / 2
+	} else {
+		y = (lat + 90) / 180
+	}
+	row := clampIndex(int(math.Floor(y*float64(rows))), rows)
+	col := clampIndex(int(math.Floor((lon+180)/360*float64(cols))), cols)
+	return row, col
+}
+
+// Bounds คืนกรอบของ cell
+func (g Grid) Bounds(row, col int) Rect {
+	rows, cols := float64(g.Rows()), float64(g.Cols())
+	lat := func(r float64) float64 {
+		if g.EqualArea {
+			return math.Asin(2*r/rows-1) * 180 / math.Pi
+		}
+		return r/rows*180 - 90
+	}
+	return Rect{
+		South: lat(float64(row)),
+		North: lat(float64(row + 1)),
+		West:  float64(col)/cols*360 - 180,
+		East:  float64(col+1)/cols*360 - 180,
+	}
+}
+
+// clampIndex ให้จุดบนขอบ (lat 90, lon 180) ตกอยู่ใน cell สุดท้าย
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
@@ -0,0 +1,168 @@
+package geo
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// Hierarchical Triangular Mesh (HTM): แบ่งทรงกลมเป็นสามเหลี่ยม 8 รูปจาก octahedron
+// แล้วแบ่งแต่ละรูปเป็น 4 รูปย่อยซ้ำ ๆ ทุก resolution
+// cell id ของ resolution r ยาว 4+2r bit: root S0..S3 = 8..11, N0..N3 = 12..15
+// และ id ของ cell ลูก = id พ่อ<<2 | k จึงหา cell พ่อได้ด้วยการ shift
+// cell ทุกรูปเป็นสามเหลี่ยมบนผิวทรงกลม พื้นที่ต่างกันไม่เกินราว 2 เท่า (ใช้ HTMAreaKm2 หาความหนาแน่น)
+// และ id ไม่เปลี่ยนตามข้อมูล (ต่างจาก cluster ที่ได้จาก divisive tree)
+
+// MaxHTMResolution จำกัดไว้ให้ id ใส่ใน int64 ได้ (resolution 20 ≈ 10 ม.)
+const MaxHTMResolution = 20
+
+type vec3 [3]float64
+
+var octahedron = [6]vec3{
+	{0, 0, 1}, {1, 0, 0}, {0, 1, 0}, {-1, 0, 0}, {0, -1, 0}, {0, 0, -1},
+}
+
+// htmRoots เรียงตาม id 8..15 (S0..S3, N0..N3) จุดยอดทวนเข็มนาฬิกาเมื่อมองจากนอกทรงกลม
+var htmRoots = [8][3]vec3{
+	{octahedron[1], octahedron[5], octahedron[2]},
+	{octahedron[2], octahedron[5], octahedron[3]},
+	{octahedron[3], octahedron[5], octahedron[4]},
+	{octahedron[4], octahedron[5], octahedron[1]},
+	{octahedron[1], octahedron[0], octahedron[4]},
+	{octahedron[4], octahedron[0], octahedron[3]},
+	{octahedron[3], octahedron[0], octahedron[2]},
+	{octahedron[2], octahedron[0], octahedron[1]},
+}
+
+// HTMCell คืน cell id ของจุด lat/lon ที่ resolution res (0 = 8 cell ทั้งโลก)
+func HTMCell(lat, lon float64, res int) int64 {
+	p := toVec(lat, lon)
+
+	id, tri := 0, htmRoots[0]
+	best := math.Inf(-1)
+	for i, root := range htmRoots {
+		if s := triangleScore(root, p); s > best {
+			id, tri, best = 8+i, root, s
+		}
+	}
+
+	for r := 0; r < res; r++ {
+		children := subdivide(tri)
+		k := 0
+		best = math.Inf(-1)
+		for i, child := range children {
+			if s := triangleScore(child, p); s > best {
+				k, best = i, s
+			}
+		}
+		id = id<<2 | k
+		tri = children[k]
+	}
+	return int64(id)
+}
+
+// HTMParent คืน id ของ cell ที่ resolution res ที่มี cell id อยู่ (res ต้องไม่เกิน resolution ของ id)
+func HTMParent(id int64, res int) int64 {
+	return id >> (2 * (HTMResolution(id) - res))
+}
+
+// HTMResolution คืน resolution ของ cell id
+func HTMResolution(id int64) int {
+	return (bits.Len64(uint64(id)) - 4) / 2
+}
+
+// HTMName คืนชื่อแบบอ่านง่ายของ cell เช่น "N0123"
+func HTMName(id int64) string {
+	res := HTMResolution(id)
+	root := HTMParent(id, 0)
+	name := "S"
+	if root >= 12 {
+		name = "N"
+	}
+	name += strconv.Itoa(int(root & 3))
+	for r := 1; r <= res; r++ {
+		name += strconv.Itoa(int(HTMParent(id, r) & 3))
+	}
+	return name
+}
+
+// HTMVertices คืนจุดยอดทั้งสามของ cell เป็น [lon, lat] (ลำดับแบบ GeoJSON) และจุดศูนย์กลาง (lat, lon)
+func HTMVertices(id int64) ([3][2]float64, float64, float64) {
+	tri := htmTriangle(id)
+
+	var out [3][2]float64
+	for i, v := range tri {
+		lat, lon := toLatLon(v)
+		out[i] = [2]float64{lon, lat}
+	}
+	centerLat, centerLon := toLatLon(normalize(vec3{
+		tri[0][0] + tri[1][0] + tri[2][0],
+		tri[0][1] + tri[1][1] + tri[2][1],
+		tri[0][2] + tri[1][2] + tri[2][2],
+	}))
+	return out, centerLat, centerLon
+}
+
+// HTMAreaKm2 คืนพื้นที่ของ cell (ตารางกิโลเมตร) จาก spherical excess ของสามเหลี่ยม
+// tan(E/2) = |a·(b×c)| / (1 + a·b + b·c + c·a) (Van Oosterom & Strackee)
+func HTMAreaKm2(id int64) float64 {
+	tri := htmTriangle(id)
+	a, b, c := tri[0], tri[1], tri[2]
+	excess := 2 * math.Atan2(math.Abs(dot(a, cross3(b, c))), 1+dot(a, b)+dot(b, c)+dot(c, a))
+	return excess * EarthRadiusKm * EarthRadiusKm
+}
+
+// htmTriangle คืนจุดยอดของ cell
+func htmTriangle(id int64) [3]vec3 {
+	res := HTMResolution(id)
+	tri := htmRoots[HTMParent(id, 0)-8]
+	for r := 1; r <= res; r++ {
+		tri = subdivide(tri)[HTMParent(id, r)&3]
+	}
+	return tri
+}
+
+// subdivide แบ่งสามเหลี่ยมเป็น 4 รูปตามลำดับมาตรฐานของ HTM
+func subdivide(t [3]vec3) [4][3]vec3 {
+	w0 := midpoint(t[1], t[2])
+	w1 := midpoint(t[0], t[2])
+	w2 := midpoint(t[0], t[1])
+	return [4][3]vec3{
+		{t[0], w2, w1},
+		{t[1], w0, w2},
+		{t[2], w1, w0},
+		{w0, w1, w2},
+	}
+}
+
+// triangleScore >= 0 เมื่อจุดอยู่ในสามเหลี่ยม ใช้ค่าที่มากที่สุดแทนการเช็ค >= 0
+// เพื่อให้จุดบนขอบ (หรือคลาดเคลื่อนจาก floating point) ได้ cell เสมอ
+func triangleScore(t [3]vec3, p vec3) float64 {
+	return math.Min(dot(cross3(t[0], t[1]), p), math.Min(dot(cross3(t[1], t[2]), p), dot(cross3(t[2], t[0]), p)))
+}
+
+func toVec(lat, lon float64) vec3 {
+	phi, lambda := lat*math.Pi/180, lon*math.Pi/180
+	return vec3{math.Cos(phi) * math.Cos(lambda), math.Cos(phi) * math.Sin(lambda), math.Sin(phi)}
+}
+
+func toLatLon(v vec3) (float64, float64) {
+	return math.Asin(math.Max(-1, math.Min(1, v[2]))) * 180 / math.Pi, math.Atan2(v[1], v[0]) * 180 / math.Pi
+}
+
+func midpoint(a, b vec3) vec3 {
+	return normalize(vec3{a[0] + b[0], a[1] + b[1], a[2] + b[2]})
+}
+
+func normalize(v vec3) vec3 {
+	n := math.Sqrt(dot(v, v))
+	return vec3{v[0] / n, v[1] / n, v[2] / n}
+}
+
+func dot(a, b vec3) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func cross3(a, b vec3) vec3 {
+	return vec3{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
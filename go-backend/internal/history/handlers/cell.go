@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"globe/internal/db/models"
+	"globe/internal/history/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetCellsHandler คืนจำนวน event ต่อ HTM cell (สามเหลี่ยมบนผิวโลกที่ id คงที่)
+// query: res=0..12, tags=a,b (OR), start_date, end_date (YYYY-MM-DD, รองรับปี BCE)
+func GetCellsHandler(c *fiber.Ctx) error {
+	res := c.QueryInt("res", 4)
+	if res < 0 || res > models.MaxCellResolution {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: fmt.Sprintf("res must be between 0 and %d", models.MaxCellResolution),
+		})
+	}
+
+	var filter models.EventFilter
+	if tags := c.Query("tags"); tags != "" {
+		filter.TagFilter = &models.TagFilter{Tags: strings.Split(tags, ","), Operator: "OR"}
+	}
+	dateFilter := &models.DateFilter{}
+	for param, target := range map[string]**models.Date{
+		"start_date": &dateFilter.StartDate,
+		"end_date":   &dateFilter.EndDate,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := models.ParseDate(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status:  "error",
+				Message: "Invalid " + param,
+				Error:   err.Error(),
+			})
+		}
+		*target = &models.Date{Time: t}
+	}
+	filter.DateFilter = dateFilter
+
+	cells, err := service.GetCellAggregates(c.UserContext(), res, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:  "error",
+			Message: "Failed to fetch cells",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   cells,
+	})
+}
+
+// ReindexCellsHandler คำนวณ cell ของทุก event ใหม่
+func ReindexCellsHandler(c *fiber.Ctx) error {
+	result, err := service.ReindexEventCells(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:  "error",
+			Message: "Failed to reindex cells",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Cells reindexed successfully",
+		Data:    result,
+	})
+}
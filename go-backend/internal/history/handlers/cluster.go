@@ -2,12 +2,11 @@ package handler
 
 import (
 	"globe/internal/db/models"
-	"globe/internal/db/repository"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func InsertClustersHandler(c *fiber.Ctx) error {
+func (h *Handler) InsertClustersHandler(c *fiber.Ctx) error {
 	var clusters []models.Cluster
 
 	// Parse JSON body เป็น slice ของ Cluster
@@ -19,7 +18,7 @@ func InsertClustersHandler(c *fiber.Ctx) error {
 	}
 
 	// Insert clusters and mappings
-	if err := repository.InsertClustersAndMappings(clusters); err != nil {
+	if err := h.clusters.InsertClustersAndMappings(c.UserContext(), clusters); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
 			"message": "Failed to insert clusters",
@@ -32,7 +31,7 @@ func InsertClustersHandler(c *fiber.Ctx) error {
 	})
 }
 
-func GetHierarchicalClustersHandler(c *fiber.Ctx) error {
+func (h *Handler) GetHierarchicalClustersHandler(c *fiber.Ctx) error {
 	var query models.ClusterQuery
 
 	// Parse request body into query struct
@@ -57,7 +56,7 @@ func GetHierarchicalClustersHandler(c *fiber.Ctx) error {
 	}
 
 	// Get hierarchical clusters
-	clusters, err := repository.GetHierarchicalClusters(query)
+	clusters, err := h.clusters.GetHierarchicalClusters(c.UserContext(), query)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"globe/internal/clusterjobs"
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+	"globe/internal/lifecycle"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxClusterJobsLimit คือจำนวนงานสูงสุดต่อหน้าใน GET /api/cluster-jobs
+const maxClusterJobsLimit = 100
+
+var clusterJobStatuses = map[string]bool{
+	models.ClusterJobQueued:    true,
+	models.ClusterJobRunning:   true,
+	models.ClusterJobSucceeded: true,
+	models.ClusterJobFailed:    true,
+	models.ClusterJobCanceled:  true,
+}
+
+// CreateClusterJobRequest คือ body ของ POST /api/cluster-jobs (ไม่ส่ง body = ค่าเริ่มต้นทั้งหมด)
+type CreateClusterJobRequest struct {
+	Engine string                  `json:"engine"` // ค่าเริ่มต้น python
+	Params models.ClusterJobParams `json:"params"`
+}
+
+// CreateClusterJobHandler เพิ่มงาน clustering เข้าคิวและตอบ 202 ทันที
+// ติดตามสถานะได้ที่ GET /api/cluster-jobs/:id
+func (h *Handler) CreateClusterJobHandler(c *fiber.Ctx) error {
+	var req CreateClusterJobRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status:  "error",
+				Message: "Invalid cluster job",
+				Error:   err.Error(),
+			})
+		}
+	}
+
+	job, err := h.clusterJobs.Submit(c.UserContext(), req.Engine, req.Params)
+	if err != nil {
+		return clusterJobError(c, err, "Failed to queue cluster job")
+	}
+	c.Location(fmt.Sprintf("/api/cluster-jobs/%d", job.JobID))
+	return c.Status(fiber.StatusAccepted).JSON(Response{
+		Status:  "success",
+		Message: "Cluster job queued",
+		Data:    job,
+	})
+}
+
+// ListClusterJobsHandler คืนประวัติงานล่าสุดก่อน (?status=queued|running|succeeded|failed|canceled&limit=20)
+func (h *Handler) ListClusterJobsHandler(c *fiber.Ctx) error {
+	query := models.ClusterJobQuery{Status: c.Query("status"), Limit: c.QueryInt("limit", 20)}
+	if query.Status != "" && !clusterJobStatuses[query.Status] {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid status",
+		})
+	}
+	if query.Limit < 1 || query.Limit > maxClusterJobsLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: fmt.Sprintf("limit must be between 1 and %d", maxClusterJobsLimit),
+		})
+	}
+
+	jobs, err := h.clusterJobs.List(c.UserContext(), query)
+	if err != nil {
+		return clusterJobError(c, err, "Failed to fetch cluster jobs")
+	}
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Cluster jobs retrieved successfully",
+		Data:    jobs,
+	})
+}
+
+// GetClusterJobHandler คืนสถานะ ขั้นตอน ความคืบหน้า และสรุปผลของงาน
+func (h *Handler) GetClusterJobHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid cluster job id",
+		})
+	}
+	job, err := h.clusterJobs.Get(c.UserContext(), id)
+	if err != nil {
+		return clusterJobError(c, err, "Failed to fetch cluster job")
+	}
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Cluster job retrieved successfully",
+		Data:    job,
+	})
+}
+
+// CancelClusterJobHandler ยกเลิกงานในคิวทันที หรือสั่งหยุดงานที่กำลังทำ (สถานะเป็น canceled เมื่องานหยุดจริง)
+func (h *Handler) CancelClusterJobHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid cluster job id",
+		})
+	}
+	job, err := h.clusterJobs.Cancel(c.UserContext(), id)
+	if err != nil {
+		return clusterJobError(c, err, "Failed to cancel cluster job")
+	}
+	message := "Cluster job canceled"
+	if job.Status == models.ClusterJobRunning {
+		message = "Cluster job cancellation requested"
+	}
+	return c.JSON(Response{
+		Status:  "success",
+		Message: message,
+		Data:    job,
+	})
+}
+
+func clusterJobError(c *fiber.Ctx, err error, message string) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, repository.ErrClusterJobNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, clusterjobs.ErrUnknownEngine):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, clusterjobs.ErrJobFinished), errors.Is(err, clusterjobs.ErrJobRunningElsewhere):
+		status = fiber.StatusConflict
+	case errors.Is(err, clusterjobs.ErrQueueFull), errors.Is(err, lifecycle.ErrShuttingDown):
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(Response{
+		Status:  "error",
+		Message: message,
+		Error:   err.Error(),
+	})
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+	"globe/internal/history/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handler) GetDuplicateReportHandler(c *fiber.Ctx) error {
+	var query models.DedupQuery
+
+	// body ว่างได้ ใช้ค่า default ทั้งหมด
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&query); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status:  "error",
+				Message: "Invalid dedup parameters",
+				Error:   err.Error(),
+			})
+		}
+	}
+
+	if query.NameThreshold > 1 || query.MinScore > 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "name_threshold and min_score must be between 0 and 1",
+		})
+	}
+
+	candidates, err := service.FindDuplicateCandidates(c.UserContext(), h.events, query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:  "error",
+			Message: "Failed to build duplicate report",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Duplicate report generated successfully",
+		Data:    candidates,
+	})
+}
+
+func MergeEventsHandler(c *fiber.Ctx) error {
+	var req models.MergeRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid merge request",
+			Error:   err.Error(),
+		})
+	}
+
+	result, err := service.MergeEvents(c.UserContext(), req)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, repository.ErrNothingToMerge):
+			status = fiber.StatusBadRequest
+		case errors.Is(err, repository.ErrEventNotFound):
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(Response{
+			Status:  "error",
+			Message: "Failed to merge events",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Events merged successfully",
+		Data:    result,
+	})
+}
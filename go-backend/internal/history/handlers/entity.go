@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func CreateEntityHandler(c *fiber.Ctx) error {
+	var ent models.Entity
+	if err := c.BodyParser(&ent); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity",
+			Error:   err.Error(),
+		})
+	}
+	if msg := validateEntity(ent); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	created, err := repository.CreateEntity(c.UserContext(), ent)
+	if err != nil {
+		return entityError(c, err, "Failed to create entity")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(Response{
+		Status:  "success",
+		Message: "Entity created successfully",
+		Data:    created,
+	})
+}
+
+// ListEntitiesHandler คืน entity ทั้งหมด (query: type=person|organisation|unit|country)
+func ListEntitiesHandler(c *fiber.Ctx) error {
+	entityType := c.Query("type")
+	if entityType != "" && !models.ValidEntityType(entityType) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity type",
+		})
+	}
+
+	entities, err := repository.ListEntities(c.UserContext(), entityType)
+	if err != nil {
+		return entityError(c, err, "Failed to fetch entities")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   entities,
+	})
+}
+
+func GetEntityHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity id",
+		})
+	}
+
+	ent, err := repository.GetEntity(c.UserContext(), id)
+	if err != nil {
+		return entityError(c, err, "Failed to fetch entity")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   ent,
+	})
+}
+
+func UpdateEntityHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity id",
+		})
+	}
+
+	var ent models.Entity
+	if err := c.BodyParser(&ent); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity",
+			Error:   err.Error(),
+		})
+	}
+	ent.EntityID = id
+	if msg := validateEntity(ent); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	updated, err := repository.UpdateEntity(c.UserContext(), ent)
+	if err != nil {
+		return entityError(c, err, "Failed to update entity")
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Entity updated successfully",
+		Data:    updated,
+	})
+}
+
+func DeleteEntityHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity id",
+		})
+	}
+
+	if err := repository.DeleteEntity(c.UserContext(), id); err != nil {
+		return entityError(c, err, "Failed to delete entity")
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Entity deleted successfully",
+	})
+}
+
+// LinkEntityEventHandler เชื่อม entity กับ event: body {"event_id": 1, "role": "commander"}
+func LinkEntityEventHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity id",
+		})
+	}
+
+	var link models.EventEntityLink
+	if err := c.BodyParser(&link); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid link",
+			Error:   err.Error(),
+		})
+	}
+	link.EntityID = id
+	if link.EventID == 0 || !models.ValidEntityRole(link.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "event_id and a valid role (commander, participant, belligerent, other) are required",
+		})
+	}
+
+	if err := repository.LinkEntityToEvent(c.UserContext(), link); err != nil {
+		return entityError(c, err, "Failed to link entity to event")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(Response{
+		Status:  "success",
+		Message: "Entity linked to event successfully",
+		Data:    link,
+	})
+}
+
+// UnlinkEntityEventHandler ลบ link (query: role=... ถ้าไม่ระบุจะลบทุกบทบาท)
+func UnlinkEntityEventHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity id",
+		})
+	}
+	eventID, err := c.ParamsInt("eventId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid event id",
+		})
+	}
+
+	link := models.EventEntityLink{EventID: eventID, EntityID: id, Role: c.Query("role")}
+	if err := repository.UnlinkEntityFromEvent(c.UserContext(), link); err != nil {
+		return entityError(c, err, "Failed to unlink entity from event")
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Entity unlinked from event successfully",
+	})
+}
+
+// GetEntityEventsHandler คืน event ของ entity เรียงตามเวลาเป็นเส้นทาง (query: roles=commander,participant)
+func GetEntityEventsHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid entity id",
+		})
+	}
+
+	var roles []string
+	for _, r := range strings.Split(c.Query("roles"), ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, r)
+		}
+	}
+
+	path, err := repository.GetEntityPath(c.UserContext(), id, roles)
+	if err != nil {
+		return entityError(c, err, "Failed to fetch entity events")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   path,
+	})
+}
+
+func validateEntity(ent models.Entity) string {
+	if strings.TrimSpace(ent.Name) == "" {
+		return "name is required"
+	}
+	if !models.ValidEntityType(ent.EntityType) {
+		return "Invalid entity_type (person, organisation, unit, country)"
+	}
+	return ""
+}
+
+func entityError(c *fiber.Ctx, err error, message string) error {
+	status := fiber.StatusInternalServerError
+	if errors.Is(err, repository.ErrEntityNotFound) || errors.Is(err, repository.ErrEventNotFound) ||
+		errors.Is(err, repository.ErrLinkNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(Response{
+		Status:  "error",
+		Message: message,
+		Error:   err.Error(),
+	})
+}
@@ -1,8 +1,9 @@
 package handler
 
 import (
+	"fmt"
+
 	"globe/internal/db/models"
-	"globe/internal/db/repository"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,7 +16,7 @@ type Response struct {
 	Error   string      `json:"error,omitempty"`
 }
 
-func GetFilteredEventsHandler(c *fiber.Ctx) error {
+func (h *Handler) GetFilteredEventsHandler(c *fiber.Ctx) error {
 	var filter models.EventFilter
 
 	// Parse request body into filter struct
@@ -29,17 +30,25 @@ func GetFilteredEventsHandler(c *fiber.Ctx) error {
 
 	// Validate filter
 	if filter.DateFilter != nil && filter.DateFilter.Year != nil {
+		// ปีแบบ astronomical: 0 = 1 BCE, -43 = 44 BCE
 		year := *filter.DateFilter.Year
-		if year < 1900 || year > 2100 {
+		if year < models.MinYear || year > models.MaxYear {
 			return c.Status(fiber.StatusBadRequest).JSON(Response{
 				Status:  "error",
-				Message: "Invalid year range (1900-2100)",
+				Message: fmt.Sprintf("Invalid year range (%d to %d, 0 = 1 BCE)", models.MinYear, models.MaxYear),
 			})
 		}
 	}
 
+	if msg := validateLocationFilter(filter); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
 	// Get filtered events
-	events, err := repository.GetFilteredEvents(filter)
+	events, err := h.events.GetFilteredEvents(c.UserContext(), filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(Response{
 			Status:  "error",
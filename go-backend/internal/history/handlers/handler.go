@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"globe/internal/clusterjobs"
+	"globe/internal/db/store"
+	"globe/internal/lifecycle"
+	"globe/internal/pyservice"
+)
+
+// Handler เก็บ store ที่ handler ของ event และ cluster ใช้ (ฉีด store.NewMemory() ได้ตอนทดสอบ)
+// client ของ Python service ที่ใช้ตอนสั่ง clustering, registry ของงานที่ต้องรอตอนปิด server
+// และคิวของงาน clustering แบบ asynchronous
+type Handler struct {
+	events      store.EventStore
+	clusters    store.ClusterStore
+	status      store.StatusStore
+	py          *pyservice.Client
+	jobs        *lifecycle.Jobs
+	clusterJobs *clusterjobs.Runner
+}
+
+func NewHandler(db store.Store, py *pyservice.Client, jobs *lifecycle.Jobs, clusterJobs *clusterjobs.Runner) *Handler {
+	return &Handler{
+		events:      db,
+		clusters:    db,
+		status:      db,
+		py:          py,
+		jobs:        jobs,
+		clusterJobs: clusterJobs,
+	}
+}
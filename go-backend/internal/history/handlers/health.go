@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"globe/internal/clusterjobs"
+	"globe/internal/db/connection"
+	"globe/internal/db/migrate"
+	"globe/internal/db/repository"
+	"globe/internal/lifecycle"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// clusteringJob คือชื่องานใน lifecycle.Jobs ของการ clustering (ทั้งใน request และจากคิว)
+const clusteringJob = clusterjobs.LifecycleJob
+
+// healthCheckTimeout คือเวลาสูงสุดของแต่ละ dependency ใน /readyz และ /api/status
+// dependency ที่ตอบช้ากว่านี้ถือว่าไม่พร้อม
+const healthCheckTimeout = 2 * time.Second
+
+// Check คือผลตรวจ dependency หนึ่งตัว
+type Check struct {
+	Status    string `json:"status"` // ok | error
+	Detail    string `json:"detail,omitempty"`
+	LatencyMs *int64 `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthzHandler บอกว่า process ยังทำงานอยู่ (ไม่ตรวจ dependency)
+func HealthzHandler(c *fiber.Ctx) error {
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "ok",
+	})
+}
+
+// ReadyzHandler ตรวจฐานข้อมูล, schema version และ Python service
+// ถ้าตัวใดตัวหนึ่งล้มเหลว หมดเวลา หรือ server กำลังปิด จะตอบ 503
+func (h *Handler) ReadyzHandler(c *fiber.Ctx) error {
+	checks := map[string]Check{
+		"database":          h.checkDatabase(c.UserContext()),
+		"schema":            checkSchema(c.UserContext()),
+		"clustering_engine": h.checkPyService(c.UserContext()),
+	}
+	ready := !h.jobs.Closed()
+	for _, check := range checks {
+		if check.Status != "ok" {
+			ready = false
+		}
+	}
+
+	if !ready {
+		message := "Dependencies are not ready"
+		if h.jobs.Closed() {
+			message = "Server is shutting down"
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(Response{
+			Status:  "error",
+			Message: message,
+			Data:    fiber.Map{"checks": checks},
+		})
+	}
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "ready",
+		Data:    fiber.Map{"checks": checks},
+	})
+}
+
+// GetStatusHandler รายงานงาน clustering ที่กำลังทำ จำนวนข้อมูล เวลาของ clustering ครั้งล่าสุด
+// และ latency กับสถานะ circuit breaker ของ Python service
+func (h *Handler) GetStatusHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), healthCheckTimeout)
+	defer cancel()
+
+	counts, err := h.status.GetDataCounts(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(Response{
+			Status:  "error",
+			Message: "Failed to read data counts",
+			Error:   err.Error(),
+		})
+	}
+
+	active := []lifecycle.Job{}
+	for _, job := range h.jobs.Running() {
+		if job.Name == clusteringJob {
+			active = append(active, job)
+		}
+	}
+	var last interface{}
+	if run, ok := h.jobs.LastRun(clusteringJob); ok {
+		last = run
+	}
+
+	backend := "postgres"
+	if connection.DB == nil {
+		backend = "sqlite"
+	}
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Status retrieved successfully",
+		Data: fiber.Map{
+			"backend":           backend,
+			"postgis":           repository.PostGISEnabled(),
+			"shutting_down":     h.jobs.Closed(),
+			"active_clustering": active,
+			"last_clustering":   last,
+			"counts":            counts,
+			"python_service":    h.checkPyService(ctx),
+			"python_circuit":    h.py.Circuit(),
+		},
+	})
+}
+
+func (h *Handler) checkDatabase(parent context.Context) Check {
+	ctx, cancel := context.WithTimeout(parent, healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := h.status.Ping(ctx); err != nil {
+		return Check{Status: "error", Error: err.Error()}
+	}
+	return Check{Status: "ok", LatencyMs: millis(time.Since(start))}
+}
+
+// checkSchema ตรวจว่า schema ของ Postgres ตรงกับ binary (ไฟล์ SQLite สร้าง schema เองตอนเปิด)
+func checkSchema(parent context.Context) Check {
+	if connection.DB == nil {
+		return Check{Status: "ok", Detail: "sqlite"}
+	}
+	ctx, cancel := context.WithTimeout(parent, healthCheckTimeout)
+	defer cancel()
+	if err := migrate.Verify(ctx, connection.DB); err != nil {
+		return Check{Status: "error", Error: err.Error()}
+	}
+	version, err := migrate.SchemaVersion(ctx, connection.DB)
+	if err != nil {
+		return Check{Status: "error", Error: err.Error()}
+	}
+	return Check{Status: "ok", Detail: fmt.Sprintf("version %04d", version)}
+}
+
+func (h *Handler) checkPyService(parent context.Context) Check {
+	ctx, cancel := context.WithTimeout(parent, healthCheckTimeout)
+	defer cancel()
+	latency, err := h.py.Ping(ctx)
+	if err != nil {
+		return Check{Status: "error", Detail: h.py.URL(), LatencyMs: millis(latency), Error: err.Error()}
+	}
+	return Check{Status: "ok", Detail: h.py.URL(), LatencyMs: millis(latency)}
+}
+
+func millis(d time.Duration) *int64 {
+	ms := d.Milliseconds()
+	return &ms
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"fmt"
+
+	"globe/internal/db/models"
+	"globe/internal/history/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetHeatmapHandler คืนความเข้มของ event ต่อ cell สำหรับ heat layer บนลูกโลก
+// body: EventFilter + "grid" (latlon|equal_area), "cell_size", "center", "sigma_days"
+func (h *Handler) GetHeatmapHandler(c *fiber.Ctx) error {
+	var query models.HeatmapQuery
+	if err := c.BodyParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid heatmap parameters",
+			Error:   err.Error(),
+		})
+	}
+
+	if query.Grid != "" && query.Grid != models.HeatmapGridLatLon && query.Grid != models.HeatmapGridEqualArea {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid grid (latlon, equal_area)",
+		})
+	}
+	if query.CellSize != 0 && (query.CellSize < models.MinHeatmapCellSize || query.CellSize > models.MaxHeatmapCellSize) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: fmt.Sprintf("cell_size must be between %g and %g degrees", models.MinHeatmapCellSize, models.MaxHeatmapCellSize),
+		})
+	}
+	if query.SigmaDays < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "sigma_days must not be negative",
+		})
+	}
+
+	if msg := validateLocationFilter(query.EventFilter); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	heatmap, err := service.BuildHeatmap(c.UserContext(), h.events, query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:  "error",
+			Message: "Failed to build heatmap",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   heatmap,
+	})
+}
@@ -1,89 +1,54 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"math"
-	"os"
-	"time"
+	"errors"
+	"log/slog"
 
+	"globe/internal/clusterjobs"
 	"globe/internal/db/models"
-	"globe/internal/db/repository"
-	"globe/internal/history/service"
+	"globe/internal/pyservice"
 
-	"github.com/go-resty/resty/v2"
 	"github.com/gofiber/fiber/v2"
 )
 
-func GetEventLatLonDateHandler(c *fiber.Ctx) error {
-	events, err := service.GetEventLatLonDate()
+// GetEventLatLonDateHandler รัน clustering ภายใน request (ถูกจำกัดด้วย WRITE_TIMEOUT)
+// ข้อมูลขนาดใหญ่ให้ใช้ POST /api/cluster-jobs แทน
+func (h *Handler) GetEventLatLonDateHandler(c *fiber.Ctx) error {
+	// ลงทะเบียนเป็นงาน clustering ตอนปิด server จะรองานนี้ ถ้าเกินเวลาจะยกเลิกและไม่ insert cluster
+	ctx, done, err := h.jobs.Start(c.UserContext(), clusteringJob)
 	if err != nil {
-		log.Println("Error fetching event lat, lon, date:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch event lat, lon, date",
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
+	defer done()
 
-	// ตรวจสอบและแก้ไขค่า NaN ใน events
-	for i := range events {
-		if math.IsNaN(events[i].Lat) {
-			events[i].Lat = 0 // หรือค่าที่เหมาะสม
-		}
-		if math.IsNaN(events[i].Lon) {
-			events[i].Lon = 0
-		}
-	}
-
-	pyPort := os.Getenv("PY_PORT")
-	pythonURL := fmt.Sprintf("http://localhost:%s/process", pyPort)
-
-	requestBody := fiber.Map{
-		"events": events,
-	}
-
-	client := resty.New().SetTimeout(10 * time.Second)
-
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetBody(requestBody).
-		Post(pythonURL)
-
-	if err != nil {
-		log.Println("Error sending request to Python:", err)
-		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
-			"error": "Failed to send data to Python service",
-		})
-	}
-
-	// 1. Decode response body เป็น struct
-	var pyResp struct {
-		Status string `json:"status"`
-		Data   struct {
-			Clusters []models.Cluster `json:"clusters"`
-			// ... field อื่นๆ ถ้าต้องการ
-		} `json:"data"`
-	}
-
-	if err := json.Unmarshal(resp.Body(), &pyResp); err != nil {
-		log.Println("Error decoding Python response:", err)
+	params := models.ClusterJobParams{IncludeFlagged: c.QueryBool("include_flagged")}
+	out, err := clusterjobs.Run(ctx, h.events, h.clusters, clusterjobs.PythonEngine(h.py), params, nil)
+	switch {
+	case errors.Is(err, clusterjobs.ErrFetchEvents):
+		slog.ErrorContext(ctx, "Fetching events for clustering failed", "err", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Invalid response from Python service",
+			"error": "Failed to fetch event lat, lon, date",
 		})
-	}
-
-	// 2. Insert clusters ลง DB
-	if err := repository.InsertClustersAndMappings(pyResp.Data.Clusters); err != nil {
-		log.Println("Error inserting clusters:", err)
+	case errors.Is(err, clusterjobs.ErrInsertClusters):
+		slog.ErrorContext(ctx, "Inserting clusters failed", "err", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to insert clusters",
 		})
+	case err != nil:
+		// client retry เองเมื่อ Python service ไม่ตอบ และตอบ error ตามประเภท (503 / 504 / 502)
+		slog.ErrorContext(ctx, "Python service processing failed", "err", err)
+		return c.Status(pyservice.HTTPStatus(err)).JSON(fiber.Map{
+			"error":   "Failed to process data in Python service",
+			"details": err.Error(),
+		})
 	}
 
-	// 3. ส่ง response กลับ client (หรือจะส่ง pyResp กลับไปเลยก็ได้)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":   "success",
 		"message":  "Clusters inserted successfully",
-		"clusters": pyResp.Data.Clusters,
+		"clusters": out.Clusters,
+		"excluded": out.Excluded,
 	})
 }
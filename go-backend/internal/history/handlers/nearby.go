@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetNearbyEventsHandler คืน K event ที่ใกล้ event :id ที่สุด
+// query: k, metric=spatial|temporal|weighted, spatial_weight, temporal_weight
+func GetNearbyEventsHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid event id",
+		})
+	}
+
+	query, msg := nearbyQuery(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	events, err := repository.GetNearbyEventsOf(c.UserContext(), id, query)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, repository.ErrEventNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(Response{
+			Status:  "error",
+			Message: "Failed to fetch nearby events",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   events,
+	})
+}
+
+// GetEventsNearHandler คืน K event ที่ใกล้จุด lat/lon (และ date) ที่สุด
+// query: lat, lon, date (จำเป็นสำหรับ metric temporal/weighted), k, metric, spatial_weight, temporal_weight
+func GetEventsNearHandler(c *fiber.Ctx) error {
+	query, msg := nearbyQuery(c)
+	if msg == "" {
+		lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+		lon, lonErr := strconv.ParseFloat(c.Query("lon"), 64)
+		if latErr != nil || lonErr != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			msg = "lat and lon are required (lat -90 to 90, lon -180 to 180)"
+		}
+		query.Lat, query.Lon = lat, lon
+	}
+	if msg == "" && c.Query("date") != "" {
+		t, err := models.ParseDate(c.Query("date"))
+		if err != nil {
+			msg = "Invalid date"
+		}
+		query.Date = &models.Date{Time: t}
+	}
+	if msg == "" && query.Date == nil && query.Metric != models.NearbyMetricSpatial {
+		msg = "date is required for the " + query.Metric + " metric"
+	}
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	events, err := repository.GetNearbyEvents(c.UserContext(), query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:  "error",
+			Message: "Failed to fetch nearby events",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   events,
+	})
+}
+
+// ReindexGeohashesHandler คำนวณ geohash ของทุก event ใหม่ (คืนจำนวน event ที่ geohash เปลี่ยน)
+func ReindexGeohashesHandler(c *fiber.Ctx) error {
+	count, err := repository.ReindexGeohashes(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:  "error",
+			Message: "Failed to reindex geohashes",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Geohashes reindexed successfully",
+		Data:    fiber.Map{"indexed": count},
+	})
+}
+
+// nearbyQuery อ่าน k, metric และน้ำหนักจาก query string (คืนข้อความ error ถ้าไม่ถูกต้อง)
+func nearbyQuery(c *fiber.Ctx) (models.NearbyQuery, string) {
+	query := models.NearbyQuery{
+		K:              c.QueryInt("k", models.DefaultNearbyK),
+		Metric:         c.Query("metric", models.NearbyMetricSpatial),
+		SpatialWeight:  c.QueryFloat("spatial_weight", models.DefaultNearbySpatialWeight),
+		TemporalWeight: c.QueryFloat("temporal_weight", models.DefaultNearbyTemporalWeight),
+	}
+	if query.K < 1 || query.K > models.MaxNearbyK {
+		return query, fmt.Sprintf("k must be between 1 and %d", models.MaxNearbyK)
+	}
+	if !models.ValidNearbyMetric(query.Metric) {
+		return query, "Invalid metric (spatial, temporal, weighted)"
+	}
+	if query.SpatialWeight < 0 || query.TemporalWeight < 0 {
+		return query, "weights must not be negative"
+	}
+	// ขอบเขตการขยาย cell หารด้วย sqrt(spatial_weight) ถ้าเป็น 0 จะกลายเป็นการค้นทั้งตาราง
+	if query.Metric == models.NearbyMetricWeighted && query.SpatialWeight <= 0 {
+		return query, "spatial_weight must be greater than 0 for the weighted metric"
+	}
+	return query, ""
+}
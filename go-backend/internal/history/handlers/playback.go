@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+
+	"globe/internal/db/models"
+	"globe/internal/history/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPlaybackFramesHandler คืน frame ของ animation สำหรับโหมด play ใน response เดียว
+// body: EventFilter + "start", "end", "step" (day|week|month|year), "step_size"
+func (h *Handler) GetPlaybackFramesHandler(c *fiber.Ctx) error {
+	var query models.PlaybackQuery
+	if err := c.BodyParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid playback parameters",
+			Error:   err.Error(),
+		})
+	}
+
+	if query.Start == nil || query.End == nil || query.End.Before(query.Start.Time) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "start and end are required and end must not be before start",
+		})
+	}
+	if !models.ValidPlaybackStep(query.Step) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid step (day, week, month, year)",
+		})
+	}
+	if query.StepSize < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "step_size must not be negative",
+		})
+	}
+
+	if msg := validateLocationFilter(query.EventFilter); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	frames, err := service.BuildPlaybackFrames(c.UserContext(), h.events, query)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, service.ErrTooManyFrames) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(Response{
+			Status:  "error",
+			Message: "Failed to build playback frames",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   frames,
+	})
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RefreshClusterHullsHandler คำนวณ hull ของทุก cluster ใหม่ (เฉพาะโหมด PostGIS)
+// body: {"mode": "convex"|"concave", "ratio": 0.8}
+func RefreshClusterHullsHandler(c *fiber.Ctx) error {
+	var opts models.HullOptions
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&opts); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status:  "error",
+				Message: "Invalid hull options",
+				Error:   err.Error(),
+			})
+		}
+	}
+	if !models.ValidHullOptions(opts) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid hull options (mode: convex, concave; ratio: 0 to 1)",
+		})
+	}
+
+	n, err := repository.RefreshClusterHulls(c.UserContext(), opts)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, repository.ErrPostGISDisabled) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(Response{
+			Status:  "error",
+			Message: "Failed to refresh cluster hulls",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Cluster hulls refreshed",
+		Data:    fiber.Map{"clusters": n},
+	})
+}
+
+// validateLocationFilter ตรวจ radius และ area ของ EventFilter (คืนข้อความ error หรือ "")
+func validateLocationFilter(filter models.EventFilter) string {
+	if r := filter.Radius; r != nil {
+		if r.Km <= 0 {
+			return "radius.km must be positive"
+		}
+		if r.Lat < -90 || r.Lat > 90 || r.Lon < -180 || r.Lon > 180 {
+			return "radius center must be a valid lat/lon"
+		}
+	}
+	if a := filter.Area; a != nil && a.Type != models.GeometryPolygon {
+		return "area must be a GeoJSON Polygon"
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"globe/internal/db/connection"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PostgresOnly ใส่หน้า handler ที่เรียก repository โดยตรง (relation, entity, tour, timeline histogram,
+// cells, nearby, merge และ PostGIS) ซึ่งต้องมี connection.DB
+// backend อื่น (เช่นโหมด SQLite) จะได้ 501 แทน 404 เพื่อให้ client แยกได้ว่า endpoint มีอยู่แต่ backend นี้ไม่รองรับ
+func PostgresOnly(c *fiber.Ctx) error {
+	if connection.DB == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(Response{
+			Status:  "error",
+			Message: "Not supported by this storage backend",
+			Error:   "this endpoint requires a Postgres DATABASE_URL",
+		})
+	}
+	return c.Next()
+}
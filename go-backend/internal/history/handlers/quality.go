@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"globe/internal/history/service"
+	"globe/internal/quality"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetQualityReportHandler คืนรายการปัญหาคุณภาพข้อมูลแยกตาม event
+// query: check_media=true เพื่อตรวจ media URL แบบ online, min_year/max_year เพื่อกำหนดช่วงวันที่
+func (h *Handler) GetQualityReportHandler(c *fiber.Ctx) error {
+	opts := quality.DefaultOptions()
+	opts.CheckMedia = c.QueryBool("check_media")
+
+	// ตรวจว่ามีการส่งค่ามาหรือไม่จากตัว query เอง เพราะปี 0 (1 BCE) เป็นค่าที่ใช้ได้
+	if v := c.Query("min_year"); v != "" {
+		minYear, err := strconv.Atoi(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status:  "error",
+				Message: "min_year must be an integer",
+			})
+		}
+		opts.MinDate = time.Date(minYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if v := c.Query("max_year"); v != "" {
+		maxYear, err := strconv.Atoi(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status:  "error",
+				Message: "max_year must be an integer",
+			})
+		}
+		opts.MaxDate = time.Date(maxYear, 12, 31, 23, 59, 59, 0, time.UTC)
+	}
+	if opts.MaxDate.Before(opts.MinDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "max_year must not be before min_year",
+		})
+	}
+
+	report, err := service.GetQualityReport(c.UserContext(), h.events, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:  "error",
+			Message: "Failed to build quality report",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Quality report generated successfully",
+		Data:    report,
+	})
+}
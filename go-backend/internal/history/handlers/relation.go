@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxGraphDepth = 5
+
+func CreateRelationHandler(c *fiber.Ctx) error {
+	var rel models.EventRelation
+	if err := c.BodyParser(&rel); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid relation",
+			Error:   err.Error(),
+		})
+	}
+	if msg := validateRelation(rel); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	created, err := repository.CreateRelation(c.UserContext(), rel)
+	if err != nil {
+		return relationError(c, err, "Failed to create relation")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(Response{
+		Status:  "success",
+		Message: "Relation created successfully",
+		Data:    created,
+	})
+}
+
+func GetRelationHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid relation id",
+		})
+	}
+
+	rel, err := repository.GetRelation(c.UserContext(), id)
+	if err != nil {
+		return relationError(c, err, "Failed to fetch relation")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   rel,
+	})
+}
+
+func UpdateRelationHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid relation id",
+		})
+	}
+
+	var rel models.EventRelation
+	if err := c.BodyParser(&rel); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid relation",
+			Error:   err.Error(),
+		})
+	}
+	rel.RelationID = id
+	if msg := validateRelation(rel); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	updated, err := repository.UpdateRelation(c.UserContext(), rel)
+	if err != nil {
+		return relationError(c, err, "Failed to update relation")
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Relation updated successfully",
+		Data:    updated,
+	})
+}
+
+func DeleteRelationHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid relation id",
+		})
+	}
+
+	if err := repository.DeleteRelation(c.UserContext(), id); err != nil {
+		return relationError(c, err, "Failed to delete relation")
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Relation deleted successfully",
+	})
+}
+
+// GetEventRelationsHandler คืน edge ทั้งหมดที่เชื่อมกับ event (ทั้งขาเข้าและขาออก)
+func GetEventRelationsHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid event id",
+		})
+	}
+
+	relations, err := repository.GetRelationsForEvents(c.UserContext(), []int{id}, relationTypesQuery(c))
+	if err != nil {
+		return relationError(c, err, "Failed to fetch relations")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   relations,
+	})
+}
+
+// GetEventGraphHandler ไล่กราฟความสัมพันธ์จาก event
+// query: depth=N (default 1, สูงสุด 5), types=causes,part_of
+func GetEventGraphHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid event id",
+		})
+	}
+
+	depth := c.QueryInt("depth", 1)
+	if depth < 0 || depth > maxGraphDepth {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "depth must be between 0 and 5",
+		})
+	}
+
+	graph, err := repository.GetEventGraph(c.UserContext(), id, depth, relationTypesQuery(c))
+	if err != nil {
+		return relationError(c, err, "Failed to build event graph")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   graph,
+	})
+}
+
+func validateRelation(rel models.EventRelation) string {
+	if rel.SourceEventID == 0 || rel.TargetEventID == 0 {
+		return "source_event_id and target_event_id are required"
+	}
+	if rel.SourceEventID == rel.TargetEventID {
+		return "An event cannot be related to itself"
+	}
+	if !models.ValidRelationType(rel.RelationType) {
+		return "Invalid relation_type (causes, part_of, preceded_by, same_campaign, related_to)"
+	}
+	return ""
+}
+
+func relationTypesQuery(c *fiber.Ctx) []string {
+	var types []string
+	for _, t := range strings.Split(c.Query("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+func relationError(c *fiber.Ctx, err error, message string) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, repository.ErrRelationNotFound), errors.Is(err, repository.ErrEventNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, repository.ErrRelationExists):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(Response{
+		Status:  "error",
+		Message: message,
+		Error:   err.Error(),
+	})
+}
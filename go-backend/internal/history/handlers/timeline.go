@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetTimelineHistogramHandler นับ event ต่อ bucket สำหรับ timeline scrubber
+// body: EventFilter (tag_filter, date_filter, viewport, entity_filter) + "bucket": day|week|month|year|auto
+func GetTimelineHistogramHandler(c *fiber.Ctx) error {
+	var query models.HistogramQuery
+	if err := c.BodyParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid histogram parameters",
+			Error:   err.Error(),
+		})
+	}
+
+	if query.Bucket != "" && !models.ValidHistogramBucket(query.Bucket) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid bucket (day, week, month, year, auto)",
+		})
+	}
+	if query.DateFilter != nil && query.DateFilter.Year != nil {
+		year := *query.DateFilter.Year
+		if year < models.MinYear || year > models.MaxYear {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status:  "error",
+				Message: fmt.Sprintf("Invalid year range (%d to %d, 0 = 1 BCE)", models.MinYear, models.MaxYear),
+			})
+		}
+	}
+
+	if msg := validateLocationFilter(query.EventFilter); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	hist, err := repository.GetTimelineHistogram(c.UserContext(), query)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, repository.ErrTooManyBuckets) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(Response{
+			Status:  "error",
+			Message: "Failed to build timeline histogram",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   hist,
+	})
+}
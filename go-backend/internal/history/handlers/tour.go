@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func CreateTourHandler(c *fiber.Ctx) error {
+	var tour models.Tour
+	if err := c.BodyParser(&tour); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid tour",
+			Error:   err.Error(),
+		})
+	}
+	if msg := validateTour(tour); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	created, err := repository.CreateTour(c.UserContext(), tour)
+	if err != nil {
+		return tourError(c, err, "Failed to create tour")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(Response{
+		Status:  "success",
+		Message: "Tour created successfully",
+		Data:    created,
+	})
+}
+
+func ListToursHandler(c *fiber.Ctx) error {
+	tours, err := repository.ListTours(c.UserContext())
+	if err != nil {
+		return tourError(c, err, "Failed to fetch tours")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   tours,
+	})
+}
+
+// GetTourHandler คืน playback payload: steps พร้อมข้อมูล event ปัจจุบันของแต่ละ step
+func GetTourHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid tour id",
+		})
+	}
+
+	playback, err := repository.GetTourPlayback(c.UserContext(), id)
+	if err != nil {
+		return tourError(c, err, "Failed to fetch tour")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Data:   playback,
+	})
+}
+
+func UpdateTourHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid tour id",
+		})
+	}
+
+	var tour models.Tour
+	if err := c.BodyParser(&tour); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid tour",
+			Error:   err.Error(),
+		})
+	}
+	tour.TourID = id
+	if msg := validateTour(tour); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: msg,
+		})
+	}
+
+	updated, err := repository.UpdateTour(c.UserContext(), tour)
+	if err != nil {
+		return tourError(c, err, "Failed to update tour")
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Tour updated successfully",
+		Data:    updated,
+	})
+}
+
+func DeleteTourHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:  "error",
+			Message: "Invalid tour id",
+		})
+	}
+
+	if err := repository.DeleteTour(c.UserContext(), id); err != nil {
+		return tourError(c, err, "Failed to delete tour")
+	}
+
+	return c.JSON(Response{
+		Status:  "success",
+		Message: "Tour deleted successfully",
+	})
+}
+
+func validateTour(tour models.Tour) string {
+	if strings.TrimSpace(tour.Title) == "" {
+		return "title is required"
+	}
+	for i, s := range tour.Steps {
+		if (s.EventID == nil) == (s.ClusterID == nil) {
+			return fmt.Sprintf("step %d must reference exactly one of event_id or cluster_id", i)
+		}
+		if s.Camera.Lat < -90 || s.Camera.Lat > 90 || s.Camera.Lon < -180 || s.Camera.Lon > 180 {
+			return fmt.Sprintf("step %d has an invalid camera position", i)
+		}
+		if s.WindowStart != nil && s.WindowEnd != nil && s.WindowEnd.Before(s.WindowStart.Time) {
+			return fmt.Sprintf("step %d window_end is before window_start", i)
+		}
+		if s.DurationSeconds < 0 {
+			return fmt.Sprintf("step %d duration_seconds must not be negative", i)
+		}
+	}
+	return ""
+}
+
+func tourError(c *fiber.Ctx, err error, message string) error {
+	status := fiber.StatusInternalServerError
+	if errors.Is(err, repository.ErrTourNotFound) || errors.Is(err, repository.ErrTourEventNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(Response{
+		Status:  "error",
+		Message: message,
+		Error:   err.Error(),
+	})
+}
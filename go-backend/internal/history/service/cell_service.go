@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+)
+
+// GetCellAggregates นับ event ต่อ HTM cell (อ่านอย่างเดียว event ที่ยังไม่ถูก index คำนวณ cell ตอนนับ)
+func GetCellAggregates(ctx context.Context, res int, filter models.EventFilter) ([]models.CellAggregate, error) {
+	return repository.GetCellAggregates(ctx, res, filter)
+}
+
+// ReindexEventCells คำนวณ cell ของทุก event ใหม่
+func ReindexEventCells(ctx context.Context) (models.CellIndexResult, error) {
+	return repository.ReindexEventCells(ctx, true)
+}
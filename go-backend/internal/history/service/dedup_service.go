@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"globe/internal/db/models"
+	"globe/internal/db/repository"
+	"globe/internal/db/store"
+	"globe/internal/geo"
+)
+
+// ค่า default ของ DedupQuery
+const (
+	defaultNameThreshold = 0.6
+	defaultMaxDaysApart  = 7
+	defaultMaxDistanceKm = 100
+	defaultMinScore      = 0.7
+	defaultMaxCandidates = 500
+)
+
+// น้ำหนักของแต่ละองค์ประกอบในคะแนนรวม
+const (
+	nameWeight     = 0.5
+	dateWeight     = 0.25
+	distanceWeight = 0.25
+)
+
+// FindDuplicateCandidates จับคู่ event ที่น่าจะซ้ำกันจากชื่อ วันที่ และระยะทาง
+func FindDuplicateCandidates(ctx context.Context, eventStore store.EventStore, query models.DedupQuery) ([]models.DuplicateCandidate, error) {
+	applyDedupDefaults(&query)
+
+	filter := models.EventFilter{}
+	if query.Filter != nil {
+		filter = *query.Filter
+	}
+	all, err := eventStore.GetFilteredEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	// event ที่ lat/lon ใช้ไม่ได้วัดระยะทางไม่ได้
+	events := all[:0]
+	for _, ev := range all {
+		if geo.ValidCoordinates(ev.Lat, ev.Lon) {
+			events = append(events, ev)
+		}
+	}
+
+	// เรียงตามวันที่ แล้วเทียบเฉพาะคู่ที่อยู่ในหน้าต่างวันที่เดียวกัน
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date.Time)
+	})
+	names := make([]string, len(events))
+	for i := range events {
+		names[i] = normalizeName(events[i].EventName)
+	}
+
+	maxDays := float64(query.MaxDaysApart)
+	candidates := []models.DuplicateCandidate{}
+	for i := range events {
+		for j := i + 1; j < len(events); j++ {
+			daysApart := events[j].Date.DayNumber() - events[i].Date.DayNumber()
+			if daysApart > maxDays {
+				break
+			}
+
+			distKm := geo.HaversineKm(events[i].Lat, events[i].Lon, events[j].Lat, events[j].Lon)
+			if distKm > query.MaxDistanceKm {
+				continue
+			}
+
+			nameSim := NameSimilarity(names[i], names[j])
+			if nameSim < query.NameThreshold {
+				continue
+			}
+
+			score := nameWeight*nameSim +
+				dateWeight*proximity(daysApart, maxDays) +
+				distanceWeight*proximity(distKm, query.MaxDistanceKm)
+			if score < query.MinScore {
+				continue
+			}
+
+			candidates = append(candidates, models.DuplicateCandidate{
+				EventA:         events[i],
+				EventB:         events[j],
+				NameSimilarity: round3(nameSim),
+				DaysApart:      round3(daysApart),
+				DistanceKm:     round3(distKm),
+				Score:          round3(score),
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > query.MaxCandidates {
+		candidates = candidates[:query.MaxCandidates]
+	}
+	return candidates, nil
+}
+
+// MergeEvents รวม duplicates เข้าไปใน survivor
+func MergeEvents(ctx context.Context, req models.MergeRequest) (models.MergeResult, error) {
+	return repository.MergeEvents(ctx, req)
+}
+
+func applyDedupDefaults(q *models.DedupQuery) {
+	if q.NameThreshold <= 0 {
+		q.NameThreshold = defaultNameThreshold
+	}
+	if q.MaxDaysApart <= 0 {
+		q.MaxDaysApart = defaultMaxDaysApart
+	}
+	if q.MaxDistanceKm <= 0 {
+		q.MaxDistanceKm = defaultMaxDistanceKm
+	}
+	if q.MinScore <= 0 {
+		q.MinScore = defaultMinScore
+	}
+	if q.MaxCandidates <= 0 {
+		q.MaxCandidates = defaultMaxCandidates
+	}
+}
+
+// NameSimilarity คืนค่าความคล้ายของชื่อ (0-1) โดยเลือกค่าที่สูงกว่าระหว่าง
+// edit distance ของทั้งสตริง กับ edit distance หลังเรียง token (ทนต่อการสลับคำ)
+func NameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	return math.Max(levenshteinRatio(a, b), levenshteinRatio(sortTokens(a), sortTokens(b)))
+}
+
+// normalizeName ตัดเครื่องหมายวรรคตอนและช่องว่างซ้ำ แปลงเป็นตัวพิมพ์เล็ก
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func sortTokens(s string) string {
+	tokens := strings.Fields(s)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+func levenshteinRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// proximity แปลงระยะ (0..limit) เป็นคะแนน (1..0)
+func proximity(value, limit float64) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return math.Max(0, 1-value/limit)
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -1,15 +1,16 @@
 package service
 
 import (
-    "log"
+    "context"
+    "log/slog"
     "globe/internal/db/models"
     "globe/internal/db/repository"
 )
 
-func GetEventLatLonDate() ([]models.EventLatLonDate, error) {
-    events, err := repository.GetEventLatLonDate()
+func GetEventLatLonDate(ctx context.Context) ([]models.EventLatLonDate, error) {
+    events, err := repository.GetEventLatLonDate(ctx)
     if err != nil {
-        log.Println("Error fetching event lat, lon, date:", err)
+        slog.ErrorContext(ctx, "Fetching event lat, lon, date failed", "err", err)
         return nil, err
     }
     return events, nil
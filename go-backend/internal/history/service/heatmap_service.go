@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"globe/internal/db/models"
+	"globe/internal/db/store"
+	"globe/internal/geo"
+)
+
+// BuildHeatmap รวม event ที่ผ่าน filter เป็นความเข้มต่อ cell ของ grid
+func BuildHeatmap(ctx context.Context, eventStore store.EventStore, q models.HeatmapQuery) (models.Heatmap, error) {
+	if q.Grid == "" {
+		q.Grid = models.HeatmapGridLatLon
+	}
+	if q.CellSize == 0 {
+		q.CellSize = models.DefaultHeatmapCellSize
+	}
+	heatmap := models.Heatmap{
+		Grid:      q.Grid,
+		CellSize:  q.CellSize,
+		SigmaDays: q.SigmaDays,
+		Cells:     []models.HeatmapCell{},
+	}
+
+	events, err := eventStore.GetFilteredEvents(ctx, q.EventFilter)
+	if err != nil {
+		return heatmap, err
+	}
+
+	var center float64
+	weighted := q.SigmaDays > 0
+	if weighted {
+		switch start, end := q.DateFilter.Bounds(); {
+		case q.Center != nil:
+			heatmap.Center = q.Center
+		case start != nil && end != nil:
+			mid, _ := models.DateSpanMidpoint(*start, models.PrecisionDay, end)
+			heatmap.Center = &models.Date{Time: mid}
+		default:
+			weighted = false // ไม่มีจุดศูนย์กลาง: นับแบบไม่ถ่วงน้ำหนัก
+		}
+		if heatmap.Center != nil {
+			center = heatmap.Center.DayNumber()
+		}
+	}
+
+	grid := geo.Grid{CellSize: q.CellSize, EqualArea: q.Grid == models.HeatmapGridEqualArea}
+	type key struct{ row, col int }
+	cells := make(map[key]*models.HeatmapCell)
+	for _, ev := range events {
+		if !geo.ValidCoordinates(ev.Lat, ev.Lon) {
+			continue
+		}
+		weight := 1.0
+		if weighted {
+			mid, _ := models.DateSpanMidpoint(ev.Date.Time, ev.DatePrecision, ev.EndDate.TimePtr())
+			weight = gaussianWeight(models.DayNumber(mid), center, q.SigmaDays)
+		}
+
+		row, col := grid.Cell(ev.Lat, ev.Lon)
+		cell, ok := cells[key{row, col}]
+		if !ok {
+			b := grid.Bounds(row, col)
+			cell = &models.HeatmapCell{Row: row, Col: col, South: b.South, North: b.North, West: b.West, East: b.East}
+			cells[key{row, col}] = cell
+		}
+		cell.Count++
+		cell.Intensity += weight
+	}
+
+	for _, cell := range cells {
+		heatmap.Cells = append(heatmap.Cells, *cell)
+		heatmap.MaxIntensity = math.Max(heatmap.MaxIntensity, cell.Intensity)
+	}
+	sort.Slice(heatmap.Cells, func(i, j int) bool {
+		a, b := heatmap.Cells[i], heatmap.Cells[j]
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Col < b.Col
+	})
+	return heatmap, nil
+}
+
+// gaussianWeight คือ Gaussian ที่มีค่าสูงสุด 1 ที่ mu (ไม่หารด้วย sigma*sqrt(2π) แบบฝั่ง Python
+// เพื่อให้ intensity เทียบได้ระหว่าง sigma ต่างกัน)
+func gaussianWeight(x, mu, sigma float64) float64 {
+	return math.Exp(-0.5 * math.Pow((x-mu)/sigma, 2))
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"globe/internal/db/models"
+	"globe/internal/db/store"
+)
+
+var ErrTooManyFrames = fmt.Errorf("playback exceeds %d frames, use a larger step or a shorter window", models.MaxPlaybackFrames)
+
+// BuildPlaybackFrames สร้าง frame ของ animation ในช่วง [start, end] จาก event ที่ผ่าน filter
+// event จะเห็นใน frame ที่ช่วงวันที่ของมัน (precision/end_date) ทับกับช่วงของ frame
+func BuildPlaybackFrames(ctx context.Context, eventStore store.EventStore, q models.PlaybackQuery) (models.PlaybackFrames, error) {
+	if q.Start == nil || q.End == nil {
+		return models.PlaybackFrames{}, errors.New("start and end are required")
+	}
+	if q.StepSize <= 0 {
+		q.StepSize = 1
+	}
+	start, end := q.Start.Time, q.End.Time
+
+	// จุดเริ่มของแต่ละ frame (+ ขอบปลายของ frame สุดท้าย)
+	bounds := []time.Time{start}
+	for i := 1; ; i++ {
+		if len(bounds) > models.MaxPlaybackFrames {
+			return models.PlaybackFrames{}, ErrTooManyFrames
+		}
+		t := models.AddPlaybackSteps(start, q.Step, i*q.StepSize)
+		bounds = append(bounds, t)
+		if t.After(end) {
+			break
+		}
+	}
+	frameCount := len(bounds) - 1
+
+	filter := q.EventFilter
+	filter.DateFilter = &models.DateFilter{StartDate: q.Start, EndDate: q.End}
+	events, err := eventStore.GetFilteredEvents(ctx, filter)
+	if err != nil {
+		return models.PlaybackFrames{}, err
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Date.Equal(events[j].Date.Time) {
+			return events[i].EventID < events[j].EventID
+		}
+		return events[i].Date.Before(events[j].Date.Time)
+	})
+
+	result := models.PlaybackFrames{
+		Start:    *q.Start,
+		End:      *q.End,
+		Step:     q.Step,
+		StepSize: q.StepSize,
+		Events:   []models.EventResponse{},
+		Frames:   make([]models.PlaybackFrame, frameCount),
+	}
+	for i := range result.Frames {
+		result.Frames[i] = models.PlaybackFrame{
+			Index: i,
+			Start: models.Date{Time: bounds[i]},
+			End:   models.Date{Time: bounds[i+1]},
+			Enter: []int{},
+			Leave: []int{},
+		}
+	}
+
+	delta := make([]int, frameCount+1)
+	for _, ev := range events {
+		spanEnd := models.DateSpanEnd(ev.Date.Time, ev.DatePrecision, ev.EndDate.TimePtr())
+		// first = frame ที่มี ev.Date, last = frame ที่มีวันสุดท้ายของ event (ตัดที่ frame สุดท้าย)
+		first := sort.Search(frameCount, func(i int) bool { return bounds[i+1].After(ev.Date.Time) })
+		last := sort.Search(frameCount, func(i int) bool { return bounds[i+1].After(spanEnd) })
+		if last >= frameCount {
+			last = frameCount - 1
+		}
+		if first >= frameCount || last < first {
+			continue
+		}
+
+		result.Events = append(result.Events, ev)
+		result.Frames[first].Enter = append(result.Frames[first].Enter, ev.EventID)
+		if last+1 < frameCount {
+			result.Frames[last+1].Leave = append(result.Frames[last+1].Leave, ev.EventID)
+		}
+		delta[first]++
+		delta[last+1]--
+	}
+
+	visible := 0
+	for i := range result.Frames {
+		visible += delta[i]
+		result.Frames[i].Visible = visible
+	}
+	return result, nil
+}
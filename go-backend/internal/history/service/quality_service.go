@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"globe/internal/db/models"
+	"globe/internal/db/store"
+	"globe/internal/quality"
+)
+
+// GetQualityReport ตรวจคุณภาพข้อมูล event ทั้งหมด
+func GetQualityReport(ctx context.Context, eventStore store.EventStore, opts quality.Options) (models.QualityReport, error) {
+	records, err := eventStore.GetEventRecords(ctx)
+	if err != nil {
+		return models.QualityReport{}, err
+	}
+	return quality.BuildReport(ctx, records, opts), nil
+}
+
+// GetClusteringEvents คืน event สำหรับส่งไป clustering
+// event ที่ lat/lon ใช้ไม่ได้จะถูกตัดออกเสมอ ส่วน event ที่ติด flag อื่น
+// จะถูกตัดออกด้วยเว้นแต่ includeFlagged เป็น true
+func GetClusteringEvents(ctx context.Context, eventStore store.EventStore, includeFlagged bool) ([]models.EventLatLonDate, []int, error) {
+	events, err := eventStore.GetEventLatLonDate(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var flagged map[int]struct{}
+	if !includeFlagged {
+		records, err := eventStore.GetEventRecords(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		flagged = quality.FlaggedIDs(records, quality.DefaultOptions())
+	}
+
+	kept := make([]models.EventLatLonDate, 0, len(events))
+	excluded := []int{}
+	for _, ev := range events {
+		if !quality.ValidCoordinates(ev.Lat, ev.Lon) {
+			excluded = append(excluded, ev.EventID)
+			continue
+		}
+		if _, bad := flagged[ev.EventID]; bad {
+			excluded = append(excluded, ev.EventID)
+			continue
+		}
+		kept = append(kept, ev)
+	}
+
+	if len(excluded) > 0 {
+		slog.WarnContext(ctx, "Excluded flagged events from clustering", "events", len(excluded))
+	}
+	return kept, excluded, nil
+}
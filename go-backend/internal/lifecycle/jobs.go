@@ -0,0 +1,138 @@
+// Package lifecycle ติดตามงานเบื้องหลัง (เช่น clustering) เพื่อให้ตอนปิด server
+// รองานที่กำลังทำอยู่ให้เสร็จก่อน และยกเลิกงานที่เกินเวลาพร้อมรายงานว่ามีอะไรถูกยกเลิก
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown คือ error เมื่อเริ่มงานใหม่หลังจาก server เริ่มปิดตัวแล้ว
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Job คืองานที่กำลังทำอยู่
+type Job struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name"`
+	Started time.Time `json:"started"`
+}
+
+// Run คืองานที่จบแล้วล่าสุดของแต่ละชื่อ
+type Run struct {
+	Job
+	Finished   time.Time `json:"finished"`
+	DurationMs int64     `json:"duration_ms"`
+	Aborted    bool      `json:"aborted"` // ถูกยกเลิกตอนปิด server
+}
+
+// Jobs คือ registry ของงานที่กำลังทำ ทุกงานได้ context ที่ถูกยกเลิกเมื่อ Abort
+type Jobs struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	base    context.Context
+	abort   context.CancelFunc
+	running map[int64]Job
+	last    map[string]Run
+	nextID  int64
+	closed  bool
+}
+
+func NewJobs() *Jobs {
+	base, abort := context.WithCancel(context.Background())
+	return &Jobs{base: base, abort: abort, running: make(map[int64]Job), last: make(map[string]Run)}
+}
+
+// Start ลงทะเบียนงานชื่อ name คืน context ของงาน (ถูกยกเลิกเมื่อ parent จบหรือเมื่อ Abort)
+// และ done ที่ต้องเรียกเมื่องานจบ คืน ErrShuttingDown ถ้าปิดรับงานแล้ว
+func (j *Jobs) Start(parent context.Context, name string) (context.Context, func(), error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return nil, nil, ErrShuttingDown
+	}
+	j.nextID++
+	job := Job{ID: j.nextID, Name: name, Started: time.Now()}
+	j.running[job.ID] = job
+	j.wg.Add(1)
+
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(j.base, cancel)
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			aborted := !stop()
+			cancel()
+			finished := time.Now()
+			j.mu.Lock()
+			delete(j.running, job.ID)
+			j.last[job.Name] = Run{
+				Job: job, Finished: finished, DurationMs: finished.Sub(job.Started).Milliseconds(), Aborted: aborted,
+			}
+			j.mu.Unlock()
+			j.wg.Done()
+		})
+	}
+	return ctx, done, nil
+}
+
+// Running คืนงานที่กำลังทำอยู่ เรียงตามเวลาเริ่ม
+func (j *Jobs) Running() []Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	jobs := make([]Job, 0, len(j.running))
+	for _, job := range j.running {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].ID < jobs[b].ID })
+	return jobs
+}
+
+// LastRun คืนงานชื่อ name ที่จบล่าสุด
+func (j *Jobs) LastRun(name string) (Run, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	run, ok := j.last[name]
+	return run, ok
+}
+
+// Closed บอกว่าปิดรับงานใหม่แล้ว (server กำลังปิด)
+func (j *Jobs) Closed() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.closed
+}
+
+// Close ปิดรับงานใหม่ งานที่กำลังทำยังทำต่อได้
+func (j *Jobs) Close() {
+	j.mu.Lock()
+	j.closed = true
+	j.mu.Unlock()
+}
+
+// Wait รอจนทุกงานจบหรือ ctx หมดเวลา ถ้าหมดเวลาจะยกเลิกงานที่เหลือ
+// แล้วรอให้งานเหล่านั้นคืนตัวอีกไม่เกิน grace คืนรายการงานที่ถูกยกเลิก
+func (j *Jobs) Wait(ctx context.Context, grace time.Duration) []Job {
+	j.Close()
+	finished := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+	}
+
+	aborted := j.Running()
+	j.abort()
+	select {
+	case <-finished:
+	case <-time.After(grace):
+	}
+	return aborted
+}
@@ -0,0 +1,77 @@
+// Package logging ตั้งค่า log/slog ของทั้ง backend และแนบ request ID ที่อยู่ใน context
+// ลงทุก log ที่เรียกด้วย slog.*Context(ctx, ...)
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// รูปแบบของ log
+const (
+	FormatJSON = "json"
+	FormatText = "text" // อ่านง่ายกว่าตอนพัฒนาในเครื่อง
+)
+
+// ParseLevel แปลง debug, info, warn, error เป็น slog.Level
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (debug, info, warn, error)", s)
+	}
+	return level, nil
+}
+
+// Setup ตั้ง slog.Default ให้เขียนลง w ตาม level และ format
+// log.Printf เดิม (รวมของ library) จะถูกส่งผ่าน handler เดียวกันที่ระดับ INFO
+func Setup(w io.Writer, level slog.Level, format string) {
+	opts := &slog.HandlerOptions{AddSource: true, Level: level}
+	var h slog.Handler
+	if strings.EqualFold(format, FormatText) {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(contextHandler{h}))
+}
+
+type requestIDKey struct{}
+
+// WithRequestID คืน context ที่มี request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID คืน request ID ใน ctx ("" ถ้าไม่มี)
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler เพิ่ม request_id และ trace_id/span_id (ถ้ามี span ที่ถูก sample) จาก context ของ log record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// RequestIDHeader คือ header ที่รับ request ID จาก client/proxy และส่งกลับใน response
+// รวมถึงส่งต่อไปยัง Python service
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength จำกัดความยาวของ request ID ที่รับจากภายนอก
+const maxRequestIDLength = 128
+
+// redactedHeaders คือ header ที่ไม่เขียนค่าลง log (ชื่อตัวพิมพ์เล็ก)
+var redactedHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+	"apikey":              true, // Supabase
+}
+
+// probePaths คือ endpoint ที่ถูกเรียกเป็นระยะโดย orchestrator/Prometheus
+// ถ้าสำเร็จจะเขียน access log ที่ระดับ DEBUG เพื่อไม่ให้กลบ log อื่น
+var probePaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Middleware ตั้ง request ID (ใช้ของ client ถ้าถูกต้อง ไม่งั้นสร้างใหม่) ใส่ใน UserContext และ response header
+// แล้วเขียน access log หนึ่งบรรทัดต่อ request โดยซ่อนค่า header ที่เป็นความลับ
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		id := c.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		} else {
+			id = utils.CopyString(id)
+		}
+		c.Set(RequestIDHeader, id)
+		ctx := WithRequestID(c.UserContext(), id)
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case probePaths[c.Route().Path]:
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.IP()),
+			slog.Any("headers", RedactHeaders(c.GetReqHeaders())),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("err", err.Error()))
+		}
+		slog.LogAttrs(ctx, level, "Request", attrs...)
+		return err
+	}
+}
+
+// RedactHeaders คืน header ทั้งหมดโดยแทนค่าของ header ที่เป็นความลับด้วย [REDACTED]
+func RedactHeaders(headers map[string][]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if redactedHeaders[strings.ToLower(name)] {
+			out[name] = "[REDACTED]"
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+
+	"globe/internal/db/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// QueryTracer วัด latency ของทุก query ที่ผ่าน pgx (ใส่ใน pgxpool.Config.ConnConfig.Tracer)
+// label query คือฟังก์ชันใน module นี้ที่สั่ง query เช่น repository.GetFilteredEvents
+// ที่ LOG_LEVEL=debug จะเขียน log ของทุก query พร้อม request ID ด้วย
+type QueryTracer struct{}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	name  string
+	start time.Time
+}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{name: QueryName(), start: time.Now()})
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(q.start)
+	dbQueryDuration.WithLabelValues(q.name).Observe(elapsed.Seconds())
+	if data.Err != nil {
+		dbQueryErrors.WithLabelValues(q.name).Inc()
+	}
+	slog.DebugContext(ctx, "Query", "query", q.name, "duration_ms", float64(elapsed.Microseconds())/1000, "err", data.Err)
+}
+
+// modulePrefix คือ prefix ของชื่อฟังก์ชันใน module globe
+const modulePrefix = "globe/"
+
+// tracerPackages คือ package ที่มี pgx tracer ซึ่งต้องข้ามตอนหาชื่อ query
+var tracerPackages = []string{modulePrefix + "internal/metrics.", modulePrefix + "internal/tracing."}
+
+// QueryName หาฟังก์ชันแรกใน call stack ที่อยู่ใน module นี้ (นอก package ของ pgx tracer)
+// และคืนในรูป package.Function เช่น repository.GetFilteredEvents หรือ "other" ถ้าไม่พบ
+func QueryName() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		fn := frame.Function
+		if strings.HasPrefix(fn, modulePrefix) && !isTracerFrame(fn) {
+			name := fn[strings.LastIndex(fn, "/")+1:]
+			// ตัด closure และ method receiver ออก: repository.InsertClustersAndMappings.func1 -> repository.InsertClustersAndMappings
+			if parts := strings.SplitN(name, ".", 3); len(parts) == 3 {
+				name = parts[0] + "." + parts[1]
+			}
+			return name
+		}
+		if !more {
+			return "other"
+		}
+	}
+}
+
+func isTracerFrame(fn string) bool {
+	for _, p := range tracerPackages {
+		if strings.HasPrefix(fn, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterPool เปิด metrics ของ pgx pool (จำนวน connection และการรอ acquire)
+func RegisterPool(pool *pgxpool.Pool) {
+	gauge := func(name, help string, value func(*pgxpool.Stat) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: namespace, Subsystem: "db_pool", Name: name, Help: help},
+			func() float64 { return value(pool.Stat()) })
+	}
+	counter := func(name, help string, value func(*pgxpool.Stat) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Namespace: namespace, Subsystem: "db_pool", Name: name, Help: help},
+			func() float64 { return value(pool.Stat()) })
+	}
+	Registry.MustRegister(
+		gauge("acquired_conns", "Connections currently in use.", func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) }),
+		gauge("idle_conns", "Idle connections.", func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) }),
+		gauge("total_conns", "Open connections.", func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) }),
+		gauge("max_conns", "Maximum pool size.", func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) }),
+		counter("acquires_total", "Successful connection acquires.", func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) }),
+		counter("acquire_wait_seconds_total", "Total time spent acquiring connections.", func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() }),
+		counter("empty_acquires_total", "Acquires that had to wait because no idle connection was available.", func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) }),
+		counter("canceled_acquires_total", "Acquires canceled by their context.", func(s *pgxpool.Stat) float64 { return float64(s.CanceledAcquireCount()) }),
+	)
+}
+
+// countsTimeout คือเวลาสูงสุดของการนับข้อมูลตอน scrape
+const countsTimeout = 2 * time.Second
+
+// dataCounts อ่านจำนวน event และ cluster จาก store ทุกครั้งที่ถูก scrape
+type dataCounts struct {
+	counts func(ctx context.Context) (models.DataCounts, error)
+
+	events, clusteredEvents, clusters *prometheus.Desc
+}
+
+// RegisterDataCounts เปิด gauge ของจำนวน event และ cluster โดยเรียก counts ตอน scrape
+func RegisterDataCounts(counts func(ctx context.Context) (models.DataCounts, error)) {
+	Registry.MustRegister(&dataCounts{
+		counts:          counts,
+		events:          prometheus.NewDesc(namespace+"_events", "Events in the database.", nil, nil),
+		clusteredEvents: prometheus.NewDesc(namespace+"_clustered_events", "Events assigned to at least one cluster.", nil, nil),
+		clusters:        prometheus.NewDesc(namespace+"_clusters", "Clusters in the database.", nil, nil),
+	})
+}
+
+func (d *dataCounts) Describe(ch chan<- *prometheus.Desc) {
+	ch <- d.events
+	ch <- d.clusteredEvents
+	ch <- d.clusters
+}
+
+func (d *dataCounts) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countsTimeout)
+	defer cancel()
+	counts, err := d.counts(ctx)
+	if err != nil {
+		slog.Error("Counting events for metrics failed", "err", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(d.events, prometheus.GaugeValue, float64(counts.Events))
+	ch <- prometheus.MustNewConstMetric(d.clusteredEvents, prometheus.GaugeValue, float64(counts.ClusteredEvents))
+	ch <- prometheus.MustNewConstMetric(d.clusters, prometheus.GaugeValue, float64(counts.Clusters))
+}
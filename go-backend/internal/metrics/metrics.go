@@ -0,0 +1,141 @@
+// Package metrics เก็บ Prometheus metrics ของ backend (HTTP, ฐานข้อมูล, clustering และ Python service)
+// และเปิดให้ scrape ที่ GET /metrics
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "globe"
+
+// Registry แยกจาก prometheus.DefaultRegisterer เพื่อให้มีเฉพาะ metrics ของ backend นี้
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Postgres query latency by calling function (e.g. repository.GetFilteredEvents).",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"query"})
+
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Postgres queries that returned an error, by calling function.",
+	}, []string{"query"})
+
+	clusteringDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "clustering_duration_seconds",
+		Help:      "Duration of clustering runs (fetch events, Python service, insert clusters) by outcome.",
+		Buckets:   []float64{.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"outcome"})
+
+	pyServiceRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pyservice_requests_total",
+		Help:      "Calls to the Python clustering service by endpoint and outcome.",
+	}, []string{"endpoint", "outcome"})
+
+	pyServiceDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pyservice_request_duration_seconds",
+		Help:      "Latency of calls to the Python clustering service by endpoint.",
+		Buckets:   []float64{.005, .01, .05, .1, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"endpoint"})
+)
+
+// ผลของงาน clustering และการเรียก Python service
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomeAborted = "aborted" // ถูกยกเลิก (client ตัดการเชื่อมต่อหรือ server ปิด)
+
+	OutcomeTransportError = "transport_error" // เชื่อมต่อไม่ได้
+	OutcomeTimeout        = "timeout"
+	OutcomeBadStatus      = "bad_status"   // HTTP status ไม่ใช่ 2xx
+	OutcomeBadResponse    = "bad_response" // body อ่านไม่ได้
+	OutcomeCircuitOpen    = "circuit_open" // ไม่ได้เรียกเพราะ circuit breaker เปิดอยู่
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		dbQueryDuration, dbQueryErrors,
+		clusteringDuration,
+		pyServiceRequests, pyServiceDuration,
+	)
+}
+
+// Handler คือ GET /metrics
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware นับ request และวัด latency ตาม route pattern (เช่น /api/events/:id/nearby)
+// ไม่ใช้ path จริงเพื่อไม่ให้จำนวน label โตตาม id
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// error handler ของ fiber จะตั้ง status ทีหลัง จึงคำนวณเองแบบเดียวกัน
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		route := c.Route().Path
+		if status == fiber.StatusNotFound && route == "/" && c.Path() != "/" {
+			route = "unmatched"
+		}
+
+		// ค่าจาก fiber.Ctx ใช้ buffer ซ้ำหลังจบ request ต้อง copy ก่อนเก็บเป็น label
+		labels := []string{utils.CopyString(c.Method()), route, strconv.Itoa(status)}
+		httpRequests.WithLabelValues(labels...).Inc()
+		httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// ObserveClustering บันทึกเวลาของงาน clustering หนึ่งครั้งที่เริ่มตอน start
+func ObserveClustering(outcome string, start time.Time) {
+	clusteringDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObservePyService บันทึกผลและเวลาของการเรียก Python service หนึ่งครั้ง (นับทุก retry แยกกัน)
+// การเรียกที่ถูก circuit breaker ปฏิเสธนับแค่จำนวน เพราะไม่มี request จริง
+func ObservePyService(endpoint, outcome string, latency time.Duration) {
+	pyServiceRequests.WithLabelValues(endpoint, outcome).Inc()
+	if outcome != OutcomeCircuitOpen {
+		pyServiceDuration.WithLabelValues(endpoint).Observe(latency.Seconds())
+	}
+}
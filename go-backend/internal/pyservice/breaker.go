@@ -0,0 +1,100 @@
+package pyservice
+
+import (
+	"sync"
+	"time"
+)
+
+// สถานะของ circuit breaker
+const (
+	CircuitClosed   = "closed"    // เรียกได้ตามปกติ
+	CircuitOpen     = "open"      // งดเรียกจนกว่าจะครบ cooldown
+	CircuitHalfOpen = "half-open" // ปล่อยให้ลองเรียกทีละครั้งเพื่อดูว่า service กลับมาหรือยัง
+)
+
+// breaker เปิด circuit เมื่อเรียก service ล้มเหลวติดกัน threshold ครั้ง
+// ระหว่างเปิดจะตอบ ErrCircuitOpen ทันทีแทนการรอ timeout ทุก request
+// ครบ cooldown แล้วจะให้ลองเรียกหนึ่งครั้ง ถ้าสำเร็จจึงปิด circuit ถ้าล้มเหลวจะเปิดต่ออีก cooldown
+type breaker struct {
+	threshold int // 0 = ไม่ใช้ breaker
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool // มีการเรียกทดลองในสถานะ half-open อยู่
+	now      func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow บอกว่าเรียก service ได้หรือไม่ ถ้าได้ต้องตามด้วย record หรือ release เสมอ
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.stateLocked() {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// record บันทึกผลของการเรียก failed คือ service ไม่ตอบหรือตอบว่าใช้งานไม่ได้
+func (b *breaker) record(failed bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	probe := b.probing
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+	b.failures++
+	if probe || b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
+
+// release คืนสิทธิ์การเรียกโดยไม่นับผล (เช่นผู้เรียกยกเลิกเองก่อนได้คำตอบ)
+func (b *breaker) release() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) state() string {
+	if b.threshold <= 0 {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
+
+func (b *breaker) stateLocked() string {
+	switch {
+	case b.openedAt.IsZero():
+		return CircuitClosed
+	case b.now().Sub(b.openedAt) < b.cooldown:
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
@@ -1,71 +1,247 @@
 package pyservice
 
 import (
-    "encoding/json"
-    "fmt"
-    "github.com/gofiber/fiber/v2"
-)
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"globe/internal/config"
+	"globe/internal/logging"
+	"globe/internal/metrics"
+	"globe/internal/tracing"
 
-// กำหนด URL ของ Python service
-const (
-    baseURL = "http://localhost:8000"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Client struct เก็บข้อมูลที่จำเป็นสำหรับการเชื่อมต่อ
+// maxBackoff คือเวลารอสูงสุดระหว่าง retry
+const maxBackoff = 5 * time.Second
+
+// Client คือ client ของ Python clustering service ที่ทุก handler ใช้ร่วมกัน
+// มี timeout ต่อครั้ง, retry แบบ backoff สำหรับ endpoint ที่เรียกซ้ำได้ และ circuit breaker
 type Client struct {
-    baseURL string    // URL ของ Python service
-    app     *fiber.App  // Fiber app instance
+	baseURL string        // URL ของ Python service (PY_SERVICE_URL)
+	timeout time.Duration // timeout ต่อการเรียกหนึ่งครั้ง (PY_SERVICE_TIMEOUT)
+	retries int           // จำนวนครั้งที่เรียกซ้ำได้เมื่อ service ไม่ตอบ (PY_SERVICE_RETRIES)
+	backoff time.Duration // เวลารอก่อน retry ครั้งแรก เพิ่มเป็นสองเท่าทุกครั้ง (PY_SERVICE_RETRY_BACKOFF)
+	http    *http.Client
+	breaker *breaker
+}
+
+// NewClient สร้าง Client ตามการตั้งค่า PY_SERVICE_*
+func NewClient(cfg config.PyService) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: cfg.ConnectTimeout}).DialContext
+	return &Client{
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		timeout: cfg.Timeout,
+		retries: cfg.Retries,
+		backoff: cfg.RetryBackoff,
+		http:    &http.Client{Transport: transport},
+		breaker: newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}
+}
+
+// URL คือ base URL ของ Python service
+func (c *Client) URL() string {
+	return c.baseURL
+}
+
+// Circuit คือสถานะของ circuit breaker (closed, open, half-open)
+func (c *Client) Circuit() string {
+	return c.breaker.state()
+}
+
+// Process ส่ง event ไป POST /process และ decode response ลง out
+// การ clustering ไม่มีผลข้างเคียงฝั่ง Python จึง retry ได้เมื่อ service ไม่ตอบ
+func (c *Client) Process(ctx context.Context, events any, out any) error {
+	body, err := json.Marshal(map[string]any{"events": events})
+	if err != nil {
+		return fmt.Errorf("error marshaling events: %w", err)
+	}
+	return c.call(ctx, http.MethodPost, "/process", body, true, out)
+}
+
+// Ping เรียก GET / ของ Python service หนึ่งครั้ง (ไม่ retry และไม่ผ่าน circuit breaker
+// เพื่อให้ /readyz เห็นสถานะจริงของ service) และคืนเวลาที่ใช้
+func (c *Client) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	res := c.attempt(ctx, http.MethodGet, "/", nil, nil)
+	latency := time.Since(start)
+	metrics.ObservePyService("/", res.outcome, latency)
+	if res.kind != nil {
+		return latency, &Error{Endpoint: "GET /", Kind: res.kind, StatusCode: res.status, Attempts: 1, Err: res.err}
+	}
+	return latency, nil
+}
+
+// call เรียก endpoint พร้อม retry (ถ้า idempotent) และบันทึกผลลง circuit breaker
+func (c *Client) call(ctx context.Context, method, path string, body []byte, idempotent bool, out any) (err error) {
+	name := method + " " + path
+	ctx, span := tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("http.request.method", method), semconv.URLFull(c.baseURL+path)))
+	defer func() { tracing.End(span, err) }()
+
+	if !c.breaker.allow() {
+		metrics.ObservePyService(path, metrics.OutcomeCircuitOpen, 0)
+		return &Error{Endpoint: name, Kind: ErrCircuitOpen}
+	}
+
+	attempts := 1
+	if idempotent {
+		attempts += c.retries
+	}
+	var res result
+	n := 0
+	for n < attempts {
+		if n > 0 {
+			wait := c.backoffFor(n)
+			slog.WarnContext(ctx, "Retrying Python service call", "endpoint", name, "attempt", n+1, "wait_ms", wait.Milliseconds(), "err", res.err)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				c.breaker.release()
+				return fmt.Errorf("%s: %w", name, ctx.Err())
+			}
+		}
+
+		start := time.Now()
+		res = c.attempt(ctx, method, path, body, out)
+		n++
+		metrics.ObservePyService(path, res.outcome, time.Since(start))
+
+		// ผู้เรียกยกเลิกเอง (client ตัดการเชื่อมต่อหรือ server ปิด) ไม่ใช่ความผิดของ service
+		if errors.Is(ctx.Err(), context.Canceled) {
+			c.breaker.release()
+			return fmt.Errorf("%s: %w", name, ctx.Err())
+		}
+		if res.kind == nil || !res.retryable || ctx.Err() != nil {
+			break
+		}
+	}
+
+	span.SetAttributes(attribute.Int("http.request.resend_count", n-1))
+	if res.status != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(res.status))
+	}
+	c.breaker.record(res.retryable)
+	if res.kind == nil {
+		return nil
+	}
+	return &Error{Endpoint: name, Kind: res.kind, StatusCode: res.status, Attempts: n, Err: res.err}
 }
 
-// สร้าง Client ใหม่
-func NewClient() *Client {
-    return &Client{
-        baseURL: baseURL,
-        app:     fiber.New(),
-    }
+// result คือผลของการเรียกหนึ่งครั้ง
+type result struct {
+	status    int
+	outcome   string // label outcome ของ metrics
+	kind      error  // nil เมื่อสำเร็จ
+	err       error
+	retryable bool // service ไม่ตอบหรือตอบว่าไม่พร้อม จึงลองใหม่ได้และนับเป็นความล้มเหลวของ breaker
 }
 
-// ProcessData ส่งข้อมูลไปยัง Python service
-func (c *Client) ProcessData(data interface{}) (map[string]interface{}, error) {
-    // 1. แปลงข้อมูลเป็น JSON
-    jsonData, err := json.Marshal(data)
-    if err != nil {
-        return nil, fmt.Errorf("error marshaling data: %v", err)
-    }
-
-    // 2. สร้าง HTTP agent สำหรับส่ง request
-    agent := fiber.AcquireAgent()
-    defer fiber.ReleaseAgent(agent)  // ปล่อย agent เมื่อเสร็จ
-
-    // 3. ตั้งค่า HTTP request
-    req := agent.Request()
-    req.Header.SetMethod(fiber.MethodPost)  // ใช้ HTTP POST
-    req.SetRequestURI(fmt.Sprintf("%s/process", c.baseURL))  // ตั้งค่า URL
-    req.Header.SetContentType("application/json")  // กำหนด content type เป็น JSON
-    req.SetBody(jsonData)  // ใส่ข้อมูลที่จะส่ง
-
-    // 4. ส่ง request
-    if err := agent.Parse(); err != nil {
-        return nil, fmt.Errorf("error parsing request: %v", err)
-    }
-
-    // 5. รับ response
-    code, body, errs := agent.Bytes()
-    if len(errs) > 0 {
-        return nil, fmt.Errorf("error sending request: %v", errs[0])
-    }
-
-    // 6. ตรวจสอบ status code
-    if code != fiber.StatusOK {
-        return nil, fmt.Errorf("unexpected status code: %d", code)
-    }
-
-    // 7. แปลง response เป็น map
-    var result map[string]interface{}
-    if err := json.Unmarshal(body, &result); err != nil {
-        return nil, fmt.Errorf("error decoding response: %v", err)
-    }
-
-    // 8. ส่งผลลัพธ์กลับ
-    return result, nil
-}
\ No newline at end of file
+// attempt ส่ง request หนึ่งครั้งภายใน timeout ของ client
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte, out any) result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return result{outcome: metrics.OutcomeTransportError, kind: ErrUnreachable, err: err}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(logging.RequestIDHeader, logging.RequestID(ctx))
+	tracing.Inject(ctx, req.Header.Set)
+
+	resp, err := c.http.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		var data []byte
+		data, err = io.ReadAll(resp.Body)
+		if err == nil {
+			return decode(resp.StatusCode, data, out)
+		}
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return result{outcome: metrics.OutcomeTimeout, kind: ErrTimeout, err: err, retryable: true}
+	}
+	return result{outcome: metrics.OutcomeTransportError, kind: ErrUnreachable, err: err, retryable: true}
+}
+
+// decode ตรวจ status code ก่อน decode body เพื่อให้ error ของ Python (เช่น 500) ไม่กลายเป็น error ของ JSON
+func decode(status int, data []byte, out any) result {
+	if status < 200 || status > 299 {
+		return result{
+			status:  status,
+			outcome: metrics.OutcomeBadStatus,
+			kind:    ErrBadResponse,
+			err:     fmt.Errorf("status %d: %s", status, errorMessage(data)),
+			// 502/503/504 มาจาก proxy หรือ service ที่ยังไม่พร้อม ส่วน 4xx/500 ลองใหม่ก็ได้ผลเดิม
+			retryable: status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout,
+		}
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return result{status: status, outcome: metrics.OutcomeBadResponse, kind: ErrBadResponse, err: fmt.Errorf("error decoding response: %w", err)}
+		}
+	}
+	return result{status: status, outcome: metrics.OutcomeSuccess}
+}
+
+// maxErrorBody คือความยาวสูงสุดของ body ที่ยกมาใส่ใน error
+const maxErrorBody = 200
+
+// errorMessage ดึงข้อความจาก body ของ error: {"message": ...} ของ router หรือ {"detail": ...} ของ FastAPI
+func errorMessage(data []byte) string {
+	var body struct {
+		Message string `json:"message"`
+		Detail  any    `json:"detail"`
+	}
+	if json.Unmarshal(data, &body) == nil {
+		if body.Message != "" {
+			return body.Message
+		}
+		if body.Detail != nil {
+			return fmt.Sprint(body.Detail)
+		}
+	}
+	msg := strings.TrimSpace(string(data))
+	if len(msg) > maxErrorBody {
+		msg = msg[:maxErrorBody] + "..."
+	}
+	if msg == "" {
+		msg = "empty body"
+	}
+	return msg
+}
+
+// backoffFor คือเวลารอก่อน retry ครั้งที่ n (เริ่มที่ 1) แบบ exponential พร้อม jitter
+func (c *Client) backoffFor(n int) time.Duration {
+	if c.backoff <= 0 {
+		return 0
+	}
+	d := c.backoff << (n - 1)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	// สุ่มในช่วง [d/2, d] เพื่อไม่ให้หลาย request retry พร้อมกัน
+	return d/2 + rand.N(d/2+1)
+}
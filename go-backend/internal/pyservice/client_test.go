@@ -0,0 +1,379 @@
+package pyservice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"globe/internal/config"
+)
+
+// stub คือ Python service จำลองที่ตอบตามลำดับ handler (ตัวสุดท้ายใช้ซ้ำ) และจดเวลาที่ถูกเรียก
+type stub struct {
+	mu       sync.Mutex
+	handlers []http.HandlerFunc
+	hits     []time.Time
+}
+
+func newStub(t *testing.T, handlers ...http.HandlerFunc) (*stub, *httptest.Server) {
+	s := &stub{handlers: handlers}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		n := len(s.hits)
+		s.hits = append(s.hits, time.Now())
+		h := s.handlers[min(n, len(s.handlers)-1)]
+		s.mu.Unlock()
+		h(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func (s *stub) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.hits)
+}
+
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+// hang ไม่ตอบจนกว่า client จะตัดการเชื่อมต่อ (อ่าน body ให้หมดก่อน server จึงเห็นการตัดการเชื่อมต่อ)
+func hang(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	select {
+	case <-r.Context().Done():
+	case <-time.After(5 * time.Second):
+	}
+}
+
+func testClient(url string, retries, threshold int) *Client {
+	return NewClient(config.PyService{
+		URL:              url,
+		Timeout:          100 * time.Millisecond,
+		ConnectTimeout:   100 * time.Millisecond,
+		Retries:          retries,
+		RetryBackoff:     20 * time.Millisecond,
+		BreakerThreshold: threshold,
+		BreakerCooldown:  time.Minute,
+	})
+}
+
+// fakeClock แทน breaker.now เพื่อเลื่อนเวลาข้าม cooldown
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestProcessRetriesGatewayErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			s, srv := newStub(t, respond(status, `{"message":"not ready"}`), respond(status, ``), respond(http.StatusOK, `{"ok":true}`))
+			c := testClient(srv.URL, 2, 0)
+
+			var out struct{ OK bool }
+			if err := c.Process(context.Background(), []int{1}, &out); err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+			if !out.OK {
+				t.Error("response was not decoded")
+			}
+			if s.count() != 3 {
+				t.Fatalf("attempts = %d, want 3", s.count())
+			}
+			// backoff 20ms สุ่มในช่วง [d/2, d] และเพิ่มเป็นสองเท่าทุกครั้ง
+			if gap := s.hits[1].Sub(s.hits[0]); gap < 10*time.Millisecond {
+				t.Errorf("first retry after %v, want at least 10ms", gap)
+			}
+			if gap := s.hits[2].Sub(s.hits[1]); gap < 20*time.Millisecond {
+				t.Errorf("second retry after %v, want at least 20ms", gap)
+			}
+		})
+	}
+}
+
+func TestProcessGivesUpAfterRetries(t *testing.T) {
+	s, srv := newStub(t, respond(http.StatusServiceUnavailable, `{"detail":"warming up"}`))
+	c := testClient(srv.URL, 2, 0)
+
+	err := c.Process(context.Background(), []int{1}, nil)
+	var pyErr *Error
+	if !errors.As(err, &pyErr) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if !errors.Is(err, ErrBadResponse) || pyErr.StatusCode != http.StatusServiceUnavailable || pyErr.Attempts != 3 {
+		t.Errorf("err = %v (status %d, attempts %d)", err, pyErr.StatusCode, pyErr.Attempts)
+	}
+	if s.count() != 3 {
+		t.Errorf("attempts = %d, want 3", s.count())
+	}
+	if HTTPStatus(err) != http.StatusBadGateway {
+		t.Errorf("HTTPStatus = %d, want 502", HTTPStatus(err))
+	}
+}
+
+func TestProcessDoesNotRetryClientAndServerErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			s, srv := newStub(t, respond(status, `{"message":"clustering failed"}`))
+			c := testClient(srv.URL, 2, 1)
+
+			err := c.Process(context.Background(), []int{1}, nil)
+			if !errors.Is(err, ErrBadResponse) {
+				t.Fatalf("err = %v, want ErrBadResponse", err)
+			}
+			var pyErr *Error
+			if errors.As(err, &pyErr) && (pyErr.StatusCode != status || pyErr.Attempts != 1) {
+				t.Errorf("status = %d, attempts = %d", pyErr.StatusCode, pyErr.Attempts)
+			}
+			if want := "POST /process: " + ErrBadResponse.Error() + ": status " + strconv.Itoa(status) + ": clustering failed"; err.Error() != want {
+				t.Errorf("err = %q, want %q", err, want)
+			}
+			if s.count() != 1 {
+				t.Errorf("attempts = %d, want 1", s.count())
+			}
+			// service ตอบได้ ความผิดพลาดจึงไม่นับใน breaker
+			if c.Circuit() != CircuitClosed {
+				t.Errorf("circuit = %s, want closed", c.Circuit())
+			}
+		})
+	}
+}
+
+func TestProcessRetriesTimeouts(t *testing.T) {
+	s, srv := newStub(t, hang, respond(http.StatusOK, `{}`))
+	c := testClient(srv.URL, 2, 0)
+
+	if err := c.Process(context.Background(), []int{1}, nil); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if s.count() != 2 {
+		t.Errorf("attempts = %d, want 2", s.count())
+	}
+}
+
+func TestProcessTimeout(t *testing.T) {
+	s, srv := newStub(t, hang)
+	c := testClient(srv.URL, 1, 0)
+
+	err := c.Process(context.Background(), []int{1}, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if s.count() != 2 {
+		t.Errorf("attempts = %d, want 2", s.count())
+	}
+	if HTTPStatus(err) != http.StatusGatewayTimeout {
+		t.Errorf("HTTPStatus = %d, want 504", HTTPStatus(err))
+	}
+}
+
+func TestProcessUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c := testClient(srv.URL, 1, 0)
+
+	err := c.Process(context.Background(), []int{1}, nil)
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("err = %v, want ErrUnreachable", err)
+	}
+	var pyErr *Error
+	if errors.As(err, &pyErr) && pyErr.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", pyErr.Attempts)
+	}
+	if HTTPStatus(err) != http.StatusServiceUnavailable {
+		t.Errorf("HTTPStatus = %d, want 503", HTTPStatus(err))
+	}
+}
+
+func TestProcessBadJSON(t *testing.T) {
+	s, srv := newStub(t, respond(http.StatusOK, `not json`))
+	c := testClient(srv.URL, 2, 0)
+
+	var out map[string]any
+	err := c.Process(context.Background(), []int{1}, &out)
+	if !errors.Is(err, ErrBadResponse) {
+		t.Fatalf("err = %v, want ErrBadResponse", err)
+	}
+	if s.count() != 1 {
+		t.Errorf("attempts = %d, want 1", s.count())
+	}
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := newBreaker(2, time.Minute)
+	b.now = clock.now
+
+	expect := func(want string) {
+		t.Helper()
+		if got := b.state(); got != want {
+			t.Fatalf("state = %s, want %s", got, want)
+		}
+	}
+
+	expect(CircuitClosed)
+	b.record(true)
+	expect(CircuitClosed)
+	b.record(false) // สำเร็จแล้วนับใหม่
+	b.record(true)
+	expect(CircuitClosed)
+	b.record(true)
+	expect(CircuitOpen)
+	if b.allow() {
+		t.Fatal("open circuit allowed a call")
+	}
+
+	clock.advance(time.Minute)
+	expect(CircuitHalfOpen)
+	if !b.allow() {
+		t.Fatal("half-open circuit refused the probe")
+	}
+	if b.allow() {
+		t.Fatal("half-open circuit allowed a second probe")
+	}
+	b.record(true) // probe ล้มเหลว เปิดต่ออีก cooldown
+	expect(CircuitOpen)
+
+	clock.advance(30 * time.Second)
+	expect(CircuitOpen)
+	clock.advance(30 * time.Second)
+	expect(CircuitHalfOpen)
+	if !b.allow() {
+		t.Fatal("half-open circuit refused the probe")
+	}
+	b.record(false)
+	expect(CircuitClosed)
+	if !b.allow() || !b.allow() {
+		t.Fatal("closed circuit refused a call")
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		b.record(true)
+	}
+	if b.state() != CircuitClosed || !b.allow() {
+		t.Fatal("disabled breaker opened")
+	}
+}
+
+func TestProcessCircuitOpen(t *testing.T) {
+	s, srv := newStub(t, respond(http.StatusServiceUnavailable, ``), respond(http.StatusOK, `{}`))
+	c := testClient(srv.URL, 0, 1)
+	clock := &fakeClock{t: time.Now()}
+	c.breaker.now = clock.now
+
+	if err := c.Process(context.Background(), []int{1}, nil); !errors.Is(err, ErrBadResponse) {
+		t.Fatalf("err = %v, want ErrBadResponse", err)
+	}
+	if c.Circuit() != CircuitOpen {
+		t.Fatalf("circuit = %s, want open", c.Circuit())
+	}
+
+	err := c.Process(context.Background(), []int{1}, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if HTTPStatus(err) != http.StatusServiceUnavailable {
+		t.Errorf("HTTPStatus = %d, want 503", HTTPStatus(err))
+	}
+	if s.count() != 1 {
+		t.Errorf("open circuit called the service (%d calls)", s.count())
+	}
+
+	clock.advance(time.Minute)
+	if c.Circuit() != CircuitHalfOpen {
+		t.Fatalf("circuit = %s, want half-open", c.Circuit())
+	}
+	if err := c.Process(context.Background(), []int{1}, nil); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if c.Circuit() != CircuitClosed {
+		t.Errorf("circuit = %s, want closed", c.Circuit())
+	}
+}
+
+func TestProcessCancelReleasesBreaker(t *testing.T) {
+	cases := []struct {
+		name     string
+		handler  http.HandlerFunc
+		backoff  time.Duration
+		attempts int
+	}{
+		// ยกเลิกระหว่างรอ response
+		{"during request", hang, 0, 1},
+		// ยกเลิกระหว่างรอ retry
+		{"during backoff", respond(http.StatusServiceUnavailable, ``), time.Hour, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hit := make(chan struct{}, 1)
+			s, srv := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+				hit <- struct{}{}
+				tc.handler(w, r)
+			})
+			c := testClient(srv.URL, 2, 1)
+			c.timeout = 5 * time.Second
+			c.backoff = tc.backoff
+			clock := &fakeClock{t: time.Now()}
+			c.breaker.now = clock.now
+
+			// ให้ circuit อยู่ในสถานะ half-open การเรียกครั้งนี้จึงเป็น probe
+			c.breaker.record(true)
+			clock.advance(time.Minute)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-hit
+				time.Sleep(20 * time.Millisecond)
+				cancel()
+			}()
+			err := c.Process(ctx, []int{1}, nil)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v, want context.Canceled", err)
+			}
+			var pyErr *Error
+			if errors.As(err, &pyErr) {
+				t.Errorf("cancel returned a service error: %v", err)
+			}
+			if s.count() != tc.attempts {
+				t.Errorf("attempts = %d, want %d", s.count(), tc.attempts)
+			}
+
+			// release คืน probe โดยไม่เปิด circuit ซ้ำ การเรียกถัดไปจึงเป็น probe ได้
+			if c.Circuit() != CircuitHalfOpen {
+				t.Errorf("circuit = %s, want half-open", c.Circuit())
+			}
+			if !c.breaker.allow() {
+				t.Error("probe slot was not released")
+			}
+		})
+	}
+}
+
+func TestBackoffFor(t *testing.T) {
+	c := &Client{backoff: 100 * time.Millisecond}
+	for n, upper := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: maxBackoff, 80: maxBackoff} {
+		for i := 0; i < 50; i++ {
+			if d := c.backoffFor(n); d < upper/2 || d > upper {
+				t.Fatalf("backoffFor(%d) = %v, want within [%v, %v]", n, d, upper/2, upper)
+			}
+		}
+	}
+	if d := (&Client{}).backoffFor(1); d != 0 {
+		t.Errorf("backoffFor without backoff = %v, want 0", d)
+	}
+}
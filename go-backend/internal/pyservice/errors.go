@@ -0,0 +1,55 @@
+package pyservice
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ประเภทของความผิดพลาดจาก Python service (ใช้กับ errors.Is)
+var (
+	ErrUnreachable = errors.New("python service unreachable")
+	ErrTimeout     = errors.New("python service timed out")
+	ErrBadResponse = errors.New("python service returned a bad response")
+	ErrCircuitOpen = errors.New("python service circuit open") // ล้มเหลวติดกันหลายครั้ง จึงงดเรียกชั่วคราว
+)
+
+// Error คือ error ของการเรียก Python service หนึ่งครั้ง (รวมทุก retry)
+type Error struct {
+	Endpoint   string // เช่น POST /process
+	Kind       error  // ErrUnreachable, ErrTimeout, ErrBadResponse หรือ ErrCircuitOpen
+	StatusCode int    // HTTP status ของ response สุดท้าย (0 ถ้าไม่ได้ response)
+	Attempts   int
+	Err        error // สาเหตุของความผิดพลาดครั้งสุดท้าย
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Endpoint, e.Kind)
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// HTTPStatus แปลง error ของ client เป็น status ที่ API ตอบกลับ
+// 503 เมื่อเรียกไม่ถึง, 504 เมื่อหมดเวลา, 502 เมื่อ response ใช้ไม่ได้
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnreachable), errors.Is(err, ErrCircuitOpen):
+		return fiber.StatusServiceUnavailable
+	case errors.Is(err, ErrTimeout):
+		return fiber.StatusGatewayTimeout
+	case errors.Is(err, ErrBadResponse):
+		return fiber.StatusBadGateway
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
@@ -1,10 +1,11 @@
 package pyservice
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
-	"globe/internal/db/repository"
+	"globe/internal/db/store"
+	"globe/internal/history/service"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,42 +13,45 @@ import (
 // Handler handles Python service related requests
 type Handler struct {
 	client *Client
+	events store.EventStore
 }
 
 // NewHandler creates a new Python service handler
-func NewHandler() *Handler {
+func NewHandler(events store.EventStore, client *Client) *Handler {
 	return &Handler{
-		client: NewClient(),
+		client: client,
+		events: events,
 	}
 }
 
 // ProcessEvent handles event processing through Python service
 func (h *Handler) ProcessEvent(c *fiber.Ctx) error {
 	startTime := time.Now()
-	log.Printf("[Python Service] Starting to process request from %s", c.IP())
+	ctx := c.UserContext()
+	slog.InfoContext(ctx, "Processing events through Python service", "ip", c.IP())
 
-	events, err := repository.GetEventLatLonDate()
+	events, _, err := service.GetClusteringEvents(ctx, h.events, c.QueryBool("include_flagged"))
 	if err != nil {
-		log.Printf("[Python Service] Error fetching events: %v", err)
+		slog.ErrorContext(ctx, "Fetching events failed", "err", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch events from DB",
 		})
 	}
-	log.Printf("[Python Service] Fetched %d events", len(events))
+	slog.DebugContext(ctx, "Fetched events", "events", len(events))
 
 	// ส่งข้อมูลไปยัง Python service
-	result, err := h.client.ProcessData(events)
-	if err != nil {
-		log.Printf("[Python Service] Error processing data: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+	var result map[string]interface{}
+	if err := h.client.Process(ctx, events, &result); err != nil {
+		slog.ErrorContext(ctx, "Python service processing failed", "err", err)
+		return c.Status(HTTPStatus(err)).JSON(fiber.Map{
 			"error":   "Failed to process data",
 			"details": err.Error(),
 		})
 	}
 
 	processingTime := time.Since(startTime)
-	log.Printf("[Python Service] Request processed successfully in %v", processingTime)
-	log.Printf("[Python Service] Result: %+v", result)
+	slog.InfoContext(ctx, "Python service processed events", "duration_ms", processingTime.Milliseconds())
+	slog.DebugContext(ctx, "Python service result", "result", result)
 
 	return c.JSON(result)
 }
\ No newline at end of file
@@ -0,0 +1,243 @@
+package quality
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"globe/internal/db/models"
+	"globe/internal/geo"
+)
+
+// Issue codes
+const (
+	CodeMissingCoordinates   = "missing_coordinates"
+	CodeInvalidCoordinates   = "invalid_coordinates"
+	CodeMissingDate          = "missing_date"
+	CodeDateOutOfRange       = "date_out_of_range"
+	CodeInvalidDateRange     = "invalid_date_range"
+	CodeInvalidDatePrecision = "invalid_date_precision"
+	CodeEmptyName            = "empty_name"
+	CodeBrokenMediaURL       = "broken_media_url"
+)
+
+// Options กำหนดเกณฑ์การตรวจ
+type Options struct {
+	MinDate    time.Time // วันที่เก่าสุดที่ยอมรับ
+	MaxDate    time.Time // วันที่ใหม่สุดที่ยอมรับ
+	CheckMedia bool      // ยิง HEAD ไปที่ media URL เพื่อตรวจว่ายังใช้ได้
+}
+
+// DefaultOptions ยอมรับวันที่ตั้งแต่ models.MinYear ถึงปัจจุบัน และตรวจ URL แค่รูปแบบ
+func DefaultOptions() Options {
+	return Options{
+		MinDate: time.Date(models.MinYear, 1, 1, 0, 0, 0, 0, time.UTC),
+		MaxDate: time.Now().UTC(),
+	}
+}
+
+// Check ตรวจ event หนึ่งรายการ คืน issues ทั้งหมดที่พบ (ไม่รวมการตรวจ media แบบ online)
+func Check(r models.EventRecord, opts Options) []models.QualityIssue {
+	issues := []models.QualityIssue{}
+
+	switch {
+	case r.Lat == nil || r.Lon == nil:
+		issues = append(issues, models.QualityIssue{
+			Code: CodeMissingCoordinates, Field: "lat/lon", Message: "lat or lon is null",
+		})
+	case !ValidCoordinates(*r.Lat, *r.Lon):
+		issues = append(issues, models.QualityIssue{
+			Code: CodeInvalidCoordinates, Field: "lat/lon", Message: "lat/lon is NaN or outside [-90,90]/[-180,180]",
+		})
+	}
+
+	switch {
+	case r.Date == nil:
+		issues = append(issues, models.QualityIssue{
+			Code: CodeMissingDate, Field: "date", Message: "date is null",
+		})
+	case r.Date.Before(opts.MinDate) || r.Date.After(opts.MaxDate):
+		issues = append(issues, models.QualityIssue{
+			Code:    CodeDateOutOfRange,
+			Field:   "date",
+			Message: "date " + r.Date.String() + " is outside " + models.Date{Time: opts.MinDate}.String() + " – " + models.Date{Time: opts.MaxDate}.String(),
+		})
+	}
+
+	if r.Precision != nil && !models.ValidDatePrecision(*r.Precision) {
+		issues = append(issues, models.QualityIssue{
+			Code: CodeInvalidDatePrecision, Field: "date_precision", Message: "unknown date precision: " + *r.Precision,
+		})
+	}
+	if r.Date != nil && r.EndDate != nil && r.EndDate.Before(r.Date.Time) {
+		issues = append(issues, models.QualityIssue{
+			Code: CodeInvalidDateRange, Field: "end_date", Message: "end_date is before date",
+		})
+	}
+
+	if r.EventName == nil || strings.TrimSpace(*r.EventName) == "" {
+		issues = append(issues, models.QualityIssue{
+			Code: CodeEmptyName, Field: "event_name", Message: "event name is empty",
+		})
+	}
+
+	for _, m := range mediaFields(r) {
+		field, u := m.field, m.url
+		if u == nil || strings.TrimSpace(*u) == "" {
+			continue // ไม่มี media ไม่ถือว่าผิด
+		}
+		if !wellFormedURL(*u) {
+			issues = append(issues, models.QualityIssue{
+				Code: CodeBrokenMediaURL, Field: field, Message: "malformed URL: " + *u,
+			})
+		}
+	}
+
+	return issues
+}
+
+// ValidCoordinates ตรวจว่า lat/lon เป็นตัวเลขจริงและอยู่ในช่วงที่ถูกต้อง
+func ValidCoordinates(lat, lon float64) bool {
+	return geo.ValidCoordinates(lat, lon)
+}
+
+// BuildReport ตรวจทุก record แล้วสรุปเป็นรายงาน โดยใส่เฉพาะ event ที่มีปัญหา
+func BuildReport(ctx context.Context, records []models.EventRecord, opts Options) models.QualityReport {
+	report := models.QualityReport{
+		TotalEvents: len(records),
+		IssueCounts: map[string]int{},
+		Events:      []models.EventQualityReport{},
+	}
+
+	issuesByIndex := make([][]models.QualityIssue, len(records))
+	for i, r := range records {
+		issuesByIndex[i] = Check(r, opts)
+	}
+	if opts.CheckMedia {
+		for i, issues := range checkMediaOnline(ctx, records) {
+			issuesByIndex[i] = append(issuesByIndex[i], issues...)
+		}
+	}
+
+	for i, r := range records {
+		issues := issuesByIndex[i]
+		if len(issues) == 0 {
+			continue
+		}
+		for _, is := range issues {
+			report.IssueCounts[is.Code]++
+		}
+		name := ""
+		if r.EventName != nil {
+			name = *r.EventName
+		}
+		report.Events = append(report.Events, models.EventQualityReport{
+			EventID:   r.EventID,
+			EventName: name,
+			Issues:    issues,
+		})
+	}
+	report.FlaggedEvents = len(report.Events)
+	return report
+}
+
+// FlaggedIDs คืน set ของ event_id ที่มีปัญหาอย่างน้อยหนึ่งข้อ
+func FlaggedIDs(records []models.EventRecord, opts Options) map[int]struct{} {
+	flagged := make(map[int]struct{})
+	for _, r := range records {
+		if len(Check(r, opts)) > 0 {
+			flagged[r.EventID] = struct{}{}
+		}
+	}
+	return flagged
+}
+
+type mediaField struct {
+	field string
+	url   *string
+}
+
+func mediaFields(r models.EventRecord) []mediaField {
+	return []mediaField{{"image", r.Image}, {"video", r.Video}}
+}
+
+func wellFormedURL(raw string) bool {
+	u, err := url.ParseRequestURI(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+const (
+	mediaCheckWorkers = 8
+	mediaCheckTimeout = 5 * time.Second
+)
+
+// checkMediaOnline ยิง HEAD ไปยัง media URL ที่รูปแบบถูกต้อง คืน issues แยกตาม index ของ record
+func checkMediaOnline(ctx context.Context, records []models.EventRecord) map[int][]models.QualityIssue {
+	type job struct {
+		index int
+		field string
+		url   string
+	}
+
+	jobs := make(chan job)
+	var mu sync.Mutex
+	out := make(map[int][]models.QualityIssue)
+	client := &http.Client{Timeout: mediaCheckTimeout}
+
+	var wg sync.WaitGroup
+	for w := 0; w < mediaCheckWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if msg := probeURL(ctx, client, j.url); msg != "" {
+					mu.Lock()
+					out[j.index] = append(out[j.index], models.QualityIssue{
+						Code: CodeBrokenMediaURL, Field: j.field, Message: msg,
+					})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i, r := range records {
+		for _, m := range mediaFields(r) {
+			field, u := m.field, m.url
+			if u == nil || !wellFormedURL(*u) {
+				continue
+			}
+			select {
+			case jobs <- job{index: i, field: field, url: strings.TrimSpace(*u)}:
+			case <-ctx.Done():
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return out
+}
+
+// probeURL คืนข้อความ error ถ้า URL ใช้ไม่ได้ หรือ "" ถ้าใช้ได้
+func probeURL(ctx context.Context, client *http.Client, rawURL string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return "unreachable URL: " + err.Error()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "unreachable URL: " + rawURL
+	}
+	resp.Body.Close()
+	// บาง host ไม่รองรับ HEAD จึงนับเฉพาะ 404/410 และ 5xx ว่าเสีย
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone || resp.StatusCode >= 500 {
+		return "URL returned " + resp.Status + ": " + rawURL
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+
+	"globe/internal/metrics"
+
+	"github.com/jackc/pgx/v5"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer สร้าง client span ของทุก query ที่ผ่าน pgx ชื่อ span คือฟังก์ชันที่สั่ง query
+// เช่น repository.GetFilteredEvents (ชื่อเดียวกับ label query ของ metrics)
+type QueryTracer struct{}
+
+type querySpanKey struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	// ไม่สร้าง span ลอยถ้า query ไม่ได้อยู่ใต้ request หรืองานที่ trace อยู่ (เช่น migration ตอน start)
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	name := metrics.QueryName()
+	ctx, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBQuerySummary(name),
+			semconv.DBQueryText(data.SQL),
+		))
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	// ใช้ span ที่เก็บไว้ตอน start เท่านั้น เพื่อไม่ไปปิด span ของ request แทน
+	span, ok := ctx.Value(querySpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(semconv.DBResponseReturnedRows(int(data.CommandTag.RowsAffected())))
+	End(span, data.Err)
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"errors"
+
+	"globe/internal/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware สร้าง server span ของทุก request (ต่อจาก traceparent ที่ client ส่งมาถ้ามี)
+// และใส่ span ลง UserContext เพื่อให้ query และการเรียก Python service เป็น span ลูก
+// ต้องอยู่หลัง logging.Middleware เพื่อให้มี request ID แล้ว
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		headers := propagation.HeaderCarrier{}
+		for k, v := range c.GetReqHeaders() {
+			headers[k] = v
+		}
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headers)
+
+		// ค่าจาก fiber.Ctx ใช้ buffer ซ้ำหลังจบ request แต่ span ถูกส่งออกเป็น batch ภายหลัง ต้อง copy ก่อนเก็บ
+		method := utils.CopyString(c.Method())
+		path := utils.CopyString(c.Path())
+		ctx, span := Tracer().Start(ctx, method+" "+path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				semconv.URLPath(path),
+				semconv.ClientAddress(utils.CopyString(c.IP())),
+				attribute.String("request.id", logging.RequestID(ctx)),
+			))
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+			span.RecordError(err)
+		}
+		// ชื่อ span ใช้ route pattern (เช่น GET /api/events/:id/nearby) ซึ่งรู้หลังจาก match route แล้ว
+		route := c.Route().Path
+		span.SetName(method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, fiber.ErrInternalServerError.Message)
+		}
+		return err
+	}
+}
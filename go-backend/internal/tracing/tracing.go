@@ -0,0 +1,100 @@
+// Package tracing ตั้งค่า OpenTelemetry tracing: span ของ HTTP request, query ของ Postgres
+// และการเรียก Python service (ส่ง W3C trace context ใน header traceparent)
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exporter ที่เลือกได้ใน TRACING_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout" // เขียน span เป็น JSON ลง stderr สำหรับดูในเครื่อง
+	ExporterOTLP   = "otlp"   // ส่งแบบ OTLP/HTTP ตาม OTEL_EXPORTER_OTLP_ENDPOINT (ค่าเริ่มต้น localhost:4318)
+)
+
+// ServiceName คือ service.name ของ span (OTEL_SERVICE_NAME ใช้แทนได้)
+const ServiceName = "globe-api"
+
+const instrumentation = "globe"
+
+// Tracer คือ tracer ของ backend (ใช้ provider ที่ตั้งใน Setup)
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Setup ตั้ง tracer provider และ propagator แบบ W3C trace context
+// exporter none จะไม่บันทึก span แต่ยังส่ง traceparent ที่รับมาต่อให้ Python service
+// คืนฟังก์ชันสำหรับ flush span ที่ค้างอยู่ตอนปิด server
+func Setup(ctx context.Context, exporter string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME และ OTEL_RESOURCE_ATTRIBUTES มาทีหลังจึงแทนค่าข้างบนได้
+	if env, err := resource.New(ctx, resource.WithFromEnv()); err == nil {
+		res, _ = resource.Merge(res, env)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start เริ่ม span ภายในชื่อ name เช่น clusters.insert
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ปิด span และบันทึก err (ถ้ามี) เป็นสถานะ error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject ใส่ trace context ของ ctx ลง header ของ request ขาออกผ่าน set
+func Inject(ctx context.Context, set func(key, value string)) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for k, v := range carrier {
+		set(k, v)
+	}
+}
@@ -1,68 +1,293 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
+	"globe/internal/clusterjobs"
+	"globe/internal/config"
 	"globe/internal/db/connection"
+	"globe/internal/db/migrate"
+	"globe/internal/db/repository"
+	"globe/internal/db/store"
+	"globe/internal/lifecycle"
+	"globe/internal/logging"
+	"globe/internal/metrics"
+	"globe/internal/pyservice"
+	"globe/internal/tracing"
 	"globe/routes"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Config logging
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Println("🚀 Starting Globe API Server...")
+	// flag อยู่ก่อน subcommand เช่น go run . -go-port 8080 หรือ go run . -env-file prod.env migrate up
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	logging.Setup(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+
+	// go run . migrate up|down [n]|status
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(cfg, args[1:]); err != nil {
+			fatal("Migrate failed", err)
+		}
+		return
+	}
+
+	// go run . snapshot <file.db>
+	if len(args) > 0 && args[0] == "snapshot" {
+		if err := runSnapshot(cfg, args[1:]); err != nil {
+			fatal("Snapshot failed", err)
+		}
+		return
+	}
+
+	slog.Info("Starting Globe API server")
+	slog.LogAttrs(context.Background(), slog.LevelInfo, "Configuration", cfg.Dump()...)
+
+	flushTraces, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.SampleRatio)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
 
 	app := fiber.New(fiber.Config{
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  5 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 		AppName:      "Globe API",
+		// banner ของ fiber ไม่ใช่ JSON จึงแสดงเฉพาะ log แบบ text
+		DisableStartupMessage: cfg.Log.Format == logging.FormatJSON,
 	})
 
-	// middleware
+	// middleware: log, trace และ metrics อยู่นอก recover เพื่อให้เห็น panic เป็น 500
+	app.Use(logging.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(metrics.Middleware())
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format:     "${time} | ${status} | ${latency} | ${method} | ${path} | ${ip} | ${headers}\n",
-		TimeFormat: "2006-01-02 15:04:05",
-		TimeZone:   "Asia/Bangkok",
-		Output:     os.Stdout,
-	}))
 
 	// ตั้งค่า CORS
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "http://localhost:5173", // รองรับทั้ง localhost, IP และ local IP
+		AllowOrigins:     cfg.CORSOrigins,
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Requested-With",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Requested-With, traceparent, tracestate, " + logging.RequestIDHeader,
+		ExposeHeaders:    logging.RequestIDHeader,
 		AllowCredentials: true,
 		MaxAge:           300, // ระยะเวลาที่ browser เก็บ cache preflight response (วินาที)
 	}))
 
-	log.Println("📦 Connecting to database...")
-	if err := connection.ConnectDB(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	slog.Info("Connecting to database")
+	db, err := openStore(cfg)
+	if err != nil {
+		fatal("Failed to connect to database", err)
+	}
+	slog.Info("Database connected")
+	metrics.RegisterDataCounts(db.GetDataCounts)
+
+	jobs := lifecycle.NewJobs()
+	// client ของ Python service ตัวเดียว เพื่อให้ทุก endpoint และ worker ใช้ circuit breaker ร่วมกัน
+	py := pyservice.NewClient(cfg.PyService)
+	clusterJobs := clusterjobs.NewRunner(db, py, jobs, cfg.ClusterJobs)
+	routes.RegisterRoutes(app, db, jobs, py, clusterJobs)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// worker หยุดหยิบงานใหม่เมื่อได้รับสัญญาณ งานที่ค้างในคิวจะทำต่อเมื่อเปิด server ครั้งหน้า
+	if err := clusterJobs.Start(ctx); err != nil {
+		closeStore(db)
+		fatal("Failed to start cluster job workers", err)
 	}
-	log.Println("✅ Database connected successfully")
 
-	routes.RegisterRoutes(app)
+	listenErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server is running", "url", fmt.Sprintf("http://localhost:%d", cfg.Port))
+		listenErr <- app.Listen(cfg.Addr())
+	}()
 
-	envPath := filepath.Join("..", ".env")
-	if err := godotenv.Load(envPath); err != nil {
-		log.Fatalf("❌ Failed to load .env file from %s: %v", envPath, err)
+	select {
+	case err := <-listenErr:
+		closeStore(db)
+		fatal("Failed to start server", err)
+	case <-ctx.Done():
 	}
+	// สัญญาณครั้งที่สองระหว่างรอจะปิดทันที
+	stop()
+	shutdown(app, db, jobs, cfg.Server.ShutdownTimeout)
 
-	goPort := os.Getenv("GO_PORT")
+	// ส่ง span ที่ยังค้างใน batch ก่อนจบโปรแกรม
+	ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+	if err := flushTraces(ctx); err != nil {
+		slog.Warn("Flushing traces failed", "err", err)
+	}
+}
+
+// abortGrace คือเวลาที่ให้งานที่ถูกยกเลิก rollback และคืนตัวก่อนปิดการเชื่อมต่อฐานข้อมูล
+const abortGrace = 5 * time.Second
+
+// traceFlushTimeout คือเวลาสูงสุดในการส่ง span ที่เหลือตอนปิด server
+const traceFlushTimeout = 5 * time.Second
+
+// shutdown หยุดรับ connection ใหม่ รอ request และงานที่ค้างอยู่ไม่เกิน timeout
+// งานที่ยังไม่เสร็จจะถูกยกเลิกและรายงานใน log จากนั้นจึงปิดฐานข้อมูล
+func shutdown(app *fiber.App, db store.Store, jobs *lifecycle.Jobs, timeout time.Duration) {
+	slog.Info("Shutting down, waiting for requests and jobs", "timeout", timeout.String())
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	jobs.Close()
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		slog.Warn("Requests still in flight after shutdown timeout", "timeout", timeout.String(), "err", err)
+	}
+	for _, job := range jobs.Wait(ctx, abortGrace) {
+		slog.Warn("Aborted job", "job_id", job.ID, "job", job.Name, "running_for", time.Since(job.Started).Round(time.Second).String())
+	}
+
+	closeStore(db)
+	slog.Info("Server stopped")
+}
+
+// fatal เขียน error แล้วจบโปรแกรม
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
+
+// closeStore ปิด pgx pool หรือไฟล์ SQLite
+func closeStore(db store.Store) {
+	if connection.DB != nil {
+		connection.DB.Close()
+	}
+	if c, ok := db.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			slog.Error("Failed to close database", "err", err)
+		}
+	}
+}
+
+// openStore เลือก backend ตาม scheme ของ DATABASE_URL: sqlite:// = ไฟล์ SQLite (offline), อื่น ๆ = Postgres
+func openStore(cfg *config.Config) (store.Store, error) {
+	if path, ok := store.SQLitePath(cfg.DatabaseURL); ok {
+		slog.Info("Using SQLite file (offline mode: event and cluster endpoints only)", "path", path)
+		return store.OpenSQLite(path)
+	}
+
+	if err := connection.ConnectDB(cfg.DatabaseURL); err != nil {
+		return nil, err
+	}
+	metrics.RegisterPool(connection.DB)
+
+	// ไม่ให้ server ทำงานกับ schema ที่ไม่ตรงกับ binary นี้
+	if err := migrate.Verify(context.Background(), connection.DB); err != nil {
+		return nil, fmt.Errorf("incompatible database schema: %w", err)
+	}
+
+	// โหมด PostGIS (ไม่บังคับ): filter เชิงพื้นที่ใน SQL และ hull ของ cluster
+	if cfg.PostGIS {
+		if err := repository.EnablePostGIS(context.Background(), cfg.ClusterHull); err != nil {
+			return nil, err
+		}
+		slog.Info("PostGIS mode enabled", "cluster_hull", cfg.ClusterHull.Mode)
+	}
+	return store.NewPostgres(), nil
+}
+
+// runSnapshot คัดลอกข้อมูลจาก Postgres (DATABASE_URL) ลงไฟล์ SQLite สำหรับโหมด offline
+func runSnapshot(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: snapshot <file.db>")
+	}
+	if err := connection.ConnectDB(cfg.DatabaseURL); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := migrate.Verify(ctx, connection.DB); err != nil {
+		return err
+	}
+
+	tables, err := store.Snapshot(ctx, connection.DB, args[0])
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		slog.Info("Copied table", "table", t.Table, "rows", t.Rows)
+	}
+	slog.Info("Snapshot written", "path", args[0])
+	return nil
+}
+
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [n] | status | postgis [down]")
+	}
+	if err := connection.ConnectDB(cfg.DatabaseURL); err != nil {
+		return err
+	}
+	ctx := context.Background()
 
-	log.Printf("🌐 Server is running on http://localhost:%s", goPort)
-	if err := app.Listen(":" + goPort); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	switch args[0] {
+	case "up":
+		applied, err := migrate.Up(ctx, connection.DB)
+		for _, m := range applied {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			slog.Info("Schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrate.Down(ctx, connection.DB, steps)
+		for _, m := range reverted {
+			slog.Info("Reverted migration", "version", m.Version, "name", m.Name)
+		}
+		return err
+	case "postgis":
+		down := len(args) > 1 && args[1] == "down"
+		if err := migrate.ApplyOptional(ctx, connection.DB, "postgis", down); err != nil {
+			return err
+		}
+		if down {
+			slog.Info("PostGIS columns removed")
+		} else {
+			slog.Info("PostGIS columns ready, set POSTGIS=true to use them")
+		}
+		return nil
+	case "status":
+		statuses, err := migrate.GetStatus(ctx, connection.DB)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Unknown:
+				state = "applied (unknown to this binary)"
+			case s.Applied:
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-24s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
 	}
 }
@@ -1,29 +1,102 @@
 package routes
 
 import (
+	"time"
+
+	"globe/internal/clusterjobs"
+	"globe/internal/db/store"
 	"globe/internal/history/handlers"
+	"globe/internal/lifecycle"
+	"globe/internal/metrics"
 	"globe/internal/pyservice"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // RegisterRoutes จะเชื่อม handler กับ path
-func RegisterRoutes(app *fiber.App) {
+// endpoint ที่ใช้ repository โดยตรงต้องมี Postgres (connection.DB) ในโหมด SQLite จะตอบ 501
+func RegisterRoutes(app *fiber.App, db store.Store, jobs *lifecycle.Jobs, py *pyservice.Client, clusterJobs *clusterjobs.Runner) {
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
 			"status":  "success",
-			"message": "🚀 Hello, Fiber + Globe API is running!",
-			"time":    "now",
+			"message": "Globe API is running, see /healthz and /readyz",
+			"time":    time.Now().UTC().Format(time.RFC3339),
 		})
 	})
 
+	h := handler.NewHandler(db, py, jobs, clusterJobs)
+
+	// Health (สำหรับ load balancer / orchestrator)
+	app.Get("/healthz", handler.HealthzHandler)
+	app.Get("/readyz", h.ReadyzHandler)
+	app.Get("/metrics", metrics.Handler())
+
 	api := app.Group("/api")
-	api.Post("/events-lat-lon-date", handler.GetEventLatLonDateHandler)
-	api.Post("/insert-clusters", handler.InsertClustersHandler)
-	api.Post("/events/filter", handler.GetFilteredEventsHandler)
-	api.Post("/clusters/hierarchical", handler.GetHierarchicalClustersHandler)
+	api.Post("/events-lat-lon-date", h.GetEventLatLonDateHandler)
+	api.Post("/insert-clusters", h.InsertClustersHandler)
+	api.Post("/events/filter", h.GetFilteredEventsHandler)
+	api.Post("/clusters/hierarchical", h.GetHierarchicalClustersHandler)
+	api.Get("/status", h.GetStatusHandler)
+
+	// Asynchronous clustering
+	api.Post("/cluster-jobs", h.CreateClusterJobHandler)
+	api.Get("/cluster-jobs", h.ListClusterJobsHandler)
+	api.Get("/cluster-jobs/:id", h.GetClusterJobHandler)
+	api.Post("/cluster-jobs/:id/cancel", h.CancelClusterJobHandler)
+
+	// Timeline playback, density heatmap, duplicate report and data quality (ทุก backend)
+	api.Post("/timeline/playback", h.GetPlaybackFramesHandler)
+	api.Post("/heatmap", h.GetHeatmapHandler)
+	api.Post("/events/duplicates", h.GetDuplicateReportHandler)
+	api.Get("/quality/report", h.GetQualityReportHandler)
 
 	// Python service routes
-	pythonHandler := pyservice.NewHandler()
+	pythonHandler := pyservice.NewHandler(db, py)
 	api.Post("/process", pythonHandler.ProcessEvent)
+
+	// endpoint ต่อไปนี้เรียก repository โดยตรง backend อื่นที่ไม่ใช่ Postgres จะได้ 501 (ดู handler.PostgresOnly)
+	pg := handler.PostgresOnly
+
+	// Cluster outlines (PostGIS mode)
+	api.Post("/clusters/hulls/refresh", pg, handler.RefreshClusterHullsHandler)
+
+	// Timeline
+	api.Post("/timeline/histogram", pg, handler.GetTimelineHistogramHandler)
+
+	// Hierarchical spatial cells
+	api.Get("/cells", pg, handler.GetCellsHandler)
+	api.Post("/cells/reindex", pg, handler.ReindexCellsHandler)
+
+	// Nearby events in space-time
+	api.Get("/events/near", pg, handler.GetEventsNearHandler)
+	api.Get("/events/:id/nearby", pg, handler.GetNearbyEventsHandler)
+	api.Post("/geohash/reindex", pg, handler.ReindexGeohashesHandler)
+
+	// Duplicate merge
+	api.Post("/events/merge", pg, handler.MergeEventsHandler)
+
+	// Event relationships
+	api.Post("/relations", pg, handler.CreateRelationHandler)
+	api.Get("/relations/:id", pg, handler.GetRelationHandler)
+	api.Put("/relations/:id", pg, handler.UpdateRelationHandler)
+	api.Delete("/relations/:id", pg, handler.DeleteRelationHandler)
+	api.Get("/events/:id/relations", pg, handler.GetEventRelationsHandler)
+	api.Get("/events/:id/graph", pg, handler.GetEventGraphHandler)
+
+	// People, units, organisations and countries
+	api.Post("/entities", pg, handler.CreateEntityHandler)
+	api.Get("/entities", pg, handler.ListEntitiesHandler)
+	api.Get("/entities/:id", pg, handler.GetEntityHandler)
+	api.Put("/entities/:id", pg, handler.UpdateEntityHandler)
+	api.Delete("/entities/:id", pg, handler.DeleteEntityHandler)
+	api.Get("/entities/:id/events", pg, handler.GetEntityEventsHandler)
+	api.Post("/entities/:id/events", pg, handler.LinkEntityEventHandler)
+	api.Delete("/entities/:id/events/:eventId", pg, handler.UnlinkEntityEventHandler)
+
+	// Narrative tours
+	api.Post("/tours", pg, handler.CreateTourHandler)
+	api.Get("/tours", pg, handler.ListToursHandler)
+	api.Get("/tours/:id", pg, handler.GetTourHandler)
+	api.Put("/tours/:id", pg, handler.UpdateTourHandler)
+	api.Delete("/tours/:id", pg, handler.DeleteTourHandler)
 }
@@ -6,4 +6,9 @@ def gaussian(x, mu, sigma):
 
 def find_weighted_dist(p, q):
     weights = [1, 1, 3]
-    return math.sqrt(sum(weights[i] * (p[i] - q[i]) ** 2 for i in range(len(p))))
\ No newline at end of file
+    # event ที่วันที่ไม่แน่นอน (เช่น precision เป็นปีหรือเป็นช่วงเวลา) ถือว่าอยู่ได้ทั้งช่วง
+    # ระยะทางด้านเวลาจึงลดลงตามความไม่แน่นอนของทั้งสอง event และเป็น 0 เมื่อช่วงซ้อนกัน
+    dt = abs(p[2] - q[2])
+    if len(p) > 3 and len(q) > 3:
+        dt = max(0.0, dt - (p[3] + q[3]))
+    return math.sqrt(weights[0] * (p[0] - q[0]) ** 2 + weights[1] * (p[1] - q[1]) ** 2 + weights[2] * dt ** 2)
\ No newline at end of file
@@ -1,4 +1,5 @@
 from .closest_pair import merge_sort, find_weighted_dist, closest_pair
+from .feature import to_day_number
 
 import numpy as np
 from datetime import datetime
@@ -148,18 +149,15 @@ def calculate_centroid(pairs):
     return centroid_lat, centroid_lon
 
 def calculate_centroid_days(pairs, min_date):
+    min_day = to_day_number(min_date)
     days = []
     for _, event in pairs:
         date_val = event.get('date', event.get('Date'))
         if date_val is not None:
-            if hasattr(date_val, 'timestamp'):
-                dt = date_val
-            else:
-                try:
-                    dt = datetime.strptime(str(date_val), "%Y-%m-%d")
-                except Exception:
-                    continue
-            days.append((dt - min_date).days)
+            try:
+                days.append(to_day_number(date_val) - min_day)
+            except Exception:
+                continue
     centroid_days = float(np.mean(days)) if days else 0.0
     return centroid_days
 
@@ -178,14 +176,11 @@ def cluster_tree_to_dict(node, parent_id=None, level=0, cluster_list=None, id_co
         for _, event in node.pairs:
             date_val = event.get('date', event.get('Date'))
             if date_val is not None:
-                if hasattr(date_val, 'timestamp'):
-                    min_date_candidates.append(date_val)
-                else:
-                    try:
-                        min_date_candidates.append(datetime.strptime(str(date_val), "%Y-%m-%d"))
-                    except Exception:
-                        continue
-        min_date = min(min_date_candidates) if min_date_candidates else datetime(1970,1,1)
+                try:
+                    min_date_candidates.append(to_day_number(date_val))
+                except Exception:
+                    continue
+        min_date = min(min_date_candidates) if min_date_candidates else 0.0
     cluster_id = id_counter["id"]
     id_counter["id"] += 1
 
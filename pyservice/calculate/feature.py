@@ -2,24 +2,34 @@ import numpy as np
 from datetime import datetime
 from .distance import gaussian
 
-def normalize_date_with_gaussian(date_str, min_date, war_years=[(1914, 1918), (1939, 1945)], sigma=183):
-    if isinstance(date_str, float) or isinstance(date_str, int):
-        date_str = str(int(date_str))
-    date_obj = datetime.strptime(date_str, "%Y-%m-%d")
+# แกนเวลาใช้จำนวนวันนับจาก 1970-01-01 (ติดลบได้) ตามที่ Go ส่งมาใน DayNumber
+# เพื่อให้รองรับปี BCE ซึ่ง datetime ของ Python รองรับไม่ได้ (ปี 1-9999 เท่านั้น)
+EPOCH = datetime(1970, 1, 1)
 
-    if isinstance(min_date, str):
-        min_date = datetime.strptime(min_date, "%Y-%m-%d")
+def to_day_number(value):
+    """แปลง day number (int/float) หรือ string "YYYY-MM-DD" เป็นจำนวนวันนับจาก EPOCH"""
+    if isinstance(value, (int, float, np.integer, np.floating)):
+        return float(value)
+    if isinstance(value, datetime):
+        return float((value - EPOCH).days)
+    date_str = str(value)
+    if 'T' in date_str:
+        date_str = date_str.split('T')[0]
+    return float((datetime.strptime(date_str, "%Y-%m-%d") - EPOCH).days)
 
-    days_since_min = (date_obj - min_date).days
+def normalize_date_with_gaussian(date_value, min_date, war_years=[(1914, 1918), (1939, 1945)], sigma=183):
+    min_day = to_day_number(min_date)
+    days_since_min = to_day_number(date_value) - min_day
 
     war_effect = 0
     for start_year, end_year in war_years:
-        war_center_date = datetime((start_year + end_year) // 2, 7, 1)
-        war_center_days = (war_center_date - min_date).days
+        war_center_days = to_day_number(datetime((start_year + end_year) // 2, 7, 1)) - min_day
         war_effect += gaussian(days_since_min, war_center_days, sigma)
 
     return days_since_min + war_effect
 
-def create_feature_vector(lat, lon, date_str, min_date, war_years=[(1914, 1918), (1939, 1945)], sigma=300):
-    time_value = normalize_date_with_gaussian(date_str, min_date, war_years, sigma)
-    return np.array([lat, lon, time_value])
\ No newline at end of file
+def create_feature_vector(lat, lon, date_value, min_date, war_years=[(1914, 1918), (1939, 1945)], sigma=300, uncertainty_days=0.0):
+    """vector [lat, lon, time, uncertainty] โดย uncertainty คือครึ่งความกว้างของช่วงวันที่ (วัน)
+    ใช้ขยายแกนเวลาของ event ใน find_weighted_dist ไม่ได้เป็นพิกัด"""
+    time_value = normalize_date_with_gaussian(date_value, min_date, war_years, sigma)
+    return np.array([lat, lon, time_value, max(float(uncertainty_days or 0.0), 0.0)])
\ No newline at end of file
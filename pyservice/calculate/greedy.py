@@ -21,9 +21,13 @@ def greedy_closest_pair_kdtree_grouping(events, k):
     max_date = max(date_str)
     logging.info("Datetime range: %s to %s", min_date, max_date)
     
-    points_3d = [create_feature_vector(event['Lat'], event['Lon'], event['Date'], min_date) for event in events]
+    points_3d = [
+        create_feature_vector(event['Lat'], event['Lon'], event['Date'], min_date, uncertainty_days=event.get('DateUncertaintyDays', 0.0))
+        for event in events
+    ]
     
-    tree = KDTree(points_3d)
+    # KDTree ใช้เฉพาะพิกัด lat, lon, time (ช่องที่ 4 คือความไม่แน่นอนของวันที่)
+    tree = KDTree([p[:3] for p in points_3d])
 
     n = len(events)
     visited = [False] * n
@@ -92,7 +96,7 @@ def greedy_closest_pair_kdtree_grouping(events, k):
         logging.info("Expanding group %d (seed event id: %d)", group_idx, event_id_map[seed_idx])
         while len(groups[group_idx]) < target_group_size and remaining_idxs:
             # ค้นหา point ที่ใกล้ที่สุดกับ seed ของกลุ่มนี้
-            dists, idxs = tree.query(points_3d[seed_idx], k=n)
+            dists, idxs = tree.query(points_3d[seed_idx][:3], k=n)
             for idx in idxs:
                 if not visited[idx]:
                     groups[group_idx].append(events[idx])
@@ -29,7 +29,7 @@ class CalculationService:
         for group_idx, group in enumerate(groups):
             min_date = min(event['Date'] for event in group)
             pairs = [
-                (create_feature_vector(event['Lat'], event['Lon'], event['Date'], min_date), event)
+                (create_feature_vector(event['Lat'], event['Lon'], event['Date'], min_date, uncertainty_days=event.get('DateUncertaintyDays', 0.0)), event)
                 for event in group
             ]
             root = divisive_custom_tree(pairs, self.min_cluster_size)
@@ -4,7 +4,7 @@ from fastapi.responses import JSONResponse
 from .calculation_service import CalculationService
 import json
 from typing import List, Dict
-from datetime import datetime
+from calculate.feature import to_day_number
 
 router = APIRouter()
 calculation_service = CalculationService()
@@ -53,13 +53,18 @@ async def process_data(request: Request):
                 float(event['Lat']) 
                 float(event['Lon']) 
                 
-                # Preprocess วันที่
-                date_str = event['Date']
-                if 'T' in date_str:
-                    date_str = date_str.split('T')[0] 
-                event['Date'] = date_str
-                
-                datetime.strptime(date_str, "%Y-%m-%d")
+                # Preprocess วันที่: ใช้ DayNumber (วันนับจาก 1970-01-01) เป็นแกนเวลา
+                # Go ส่ง DayNumber มาเสมอ ซึ่งรองรับปี BCE; ถ้าไม่มีจะ parse จาก Date แบบ %Y-%m-%d
+                if event.get('DayNumber') is not None:
+                    event['Date'] = float(event['DayNumber'])
+                else:
+                    event['Date'] = to_day_number(event['Date'])
+
+                # DateUncertaintyDays: ครึ่งความกว้างของช่วงวันที่ (วัน) ใช้ขยายแกนเวลาตอนวัดระยะ
+                uncertainty = float(event.get('DateUncertaintyDays') or 0.0)
+                if uncertainty < 0:
+                    raise ValueError(f"DateUncertaintyDays must not be negative: {uncertainty}")
+                event['DateUncertaintyDays'] = uncertainty
             except ValueError as e:
                 return JSONResponse(
                     content={